@@ -1,8 +1,12 @@
 package api_test
 
 import (
+	"context"
+	"encoding/json"
 	"github.com/go-chi/chi"
+	"github.com/pkg/errors"
 	. "github.com/rinosukmandityo/user-profile/api"
+	"github.com/rinosukmandityo/user-profile/helper"
 	m "github.com/rinosukmandityo/user-profile/models"
 	repo "github.com/rinosukmandityo/user-profile/repositories"
 	rh "github.com/rinosukmandityo/user-profile/repositories/helper"
@@ -12,20 +16,24 @@ import (
 )
 
 var (
-	sessionRepo repo.SessionRepository
-	sessionSvc  services.SessionService
-	userRepo    repo.UserRepository
-	userService services.UserService
-	r           *chi.Mux
-	ts          *httptest.Server
+	attemptRepo   repo.LoginAttemptRepository
+	sessionRepo   repo.SessionRepository
+	tokenRepo     repo.TokenRepository
+	twoFactorRepo repo.TwoFactorRepository
+	sessionSvc    services.SessionService
+	userRepo      repo.UserRepository
+	userService   services.UserService
+	r             *chi.Mux
+	ts            *httptest.Server
 )
 
 func init() {
-	userRepo, sessionRepo, _ = rh.ChooseRepo()
+	var transactor repo.Transactor
+	userRepo, sessionRepo, tokenRepo, attemptRepo, twoFactorRepo, transactor = rh.ChooseRepo()
 	r = RegisterHandler()
 
-	sessionSvc = logic.NewSessionService(sessionRepo)
-	userService = logic.NewUserService(userRepo)
+	sessionSvc = logic.NewSessionService(sessionRepo, userRepo, transactor, logic.DefaultSessionPolicy())
+	userService = logic.NewUserService(userRepo, transactor, logic.DefaultPasswordPolicy())
 }
 
 func getBytes(_data m.User) ([]byte, error) {
@@ -36,10 +44,21 @@ func getBytes(_data m.User) ([]byte, error) {
 	return dataBytes, nil
 }
 
+// getUpdateBytes encodes the updatable fields of _data as a PUT /user body
+func getUpdateBytes(_data m.User) ([]byte, error) {
+	return json.Marshal(&m.UserUpdate{
+		Name:      &_data.Name,
+		Email:     &_data.Email,
+		Password:  &_data.Password,
+		Telephone: &_data.Telephone,
+		Address:   &_data.Address,
+	})
+}
+
 func seedUserData(testdata []m.User) error {
 	var err error
 	for _, data := range testdata {
-		if err = userRepo.Store(&data); err != nil {
+		if err = userRepo.Store(context.Background(), &data); err != nil {
 			return err
 		}
 	}
@@ -47,9 +66,30 @@ func seedUserData(testdata []m.User) error {
 }
 
 func cleanupUserData() error {
-	return userRepo.DeleteAll()
+	return userRepo.DeleteAll(context.Background())
 }
 
 func cleanupSessionData() error {
-	return sessionRepo.DeleteAll()
+	return sessionRepo.DeleteAll(context.Background())
+}
+
+func cleanupTokenData() error {
+	if e := tokenRepo.DeleteAll(context.Background()); errors.Cause(e) != helper.ErrTokenNotFound {
+		return e
+	}
+	return nil
+}
+
+func cleanupAttemptData() error {
+	if e := attemptRepo.DeleteAll(context.Background()); errors.Cause(e) != helper.ErrAttemptNotFound {
+		return e
+	}
+	return nil
+}
+
+func cleanupTwoFactorData() error {
+	if e := twoFactorRepo.DeleteAll(context.Background()); errors.Cause(e) != helper.ErrTwoFactorNotFound {
+		return e
+	}
+	return nil
 }
@@ -49,7 +49,7 @@ func (u *changePassword) ResetPassword(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	isTokenValid, e := u.tokenSvc.IsTokenValid(q.Get("d"), q.Get("e"))
+	isTokenValid, e := u.tokenSvc.IsTokenValid(r.Context(), q.Get("d"), q.Get("e"))
 	if e != nil || !isTokenValid {
 		data["ErrorMessage"] = e.Error()
 		registerPage(w, path.Join(baseViewURL, "reset-error.html"), data)
@@ -95,11 +95,14 @@ func (u *changePassword) ChangePassword(w http.ResponseWriter, r *http.Request)
 		ResponseWithResult(w, contentType, result.SetError(e), http.StatusBadRequest)
 		return
 	}
-	passwd := data["Password"].(string)
+	passwd, _ := data["Password"].(string)
 
-	if e = u.tokenSvc.ChangePasswordToken(userID, passwd, tokenID); e != nil {
+	if e = u.tokenSvc.ChangePasswordToken(r.Context(), userID, passwd, tokenID); e != nil {
 		log.Println("Error on changing password with token:", e.Error())
-		ResponseWithResult(w, contentType, result.SetError(e), http.StatusBadRequest)
+		if !policyResult(result, e) {
+			result.SetError(e)
+		}
+		ResponseWithResult(w, contentType, result, http.StatusBadRequest)
 		return
 	}
 	ResponseWithResult(w, contentType, result.SetData(data), statusCode)
@@ -123,7 +126,7 @@ func (u *changePassword) ResetLink(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	email := data["Email"].(string)
-	user, tokenID, e := u.tokenSvc.ResetPasswordByMail(email, time.Minute*15)
+	user, tokenID, e := u.tokenSvc.ResetPasswordByMail(r.Context(), email, time.Minute*15)
 	if e != nil {
 		if errors.Cause(e) == helper.ErrUserNotFound {
 			log.Println("User not found")
@@ -134,7 +137,7 @@ func (u *changePassword) ResetLink(w http.ResponseWriter, r *http.Request) {
 		ResponseWithResult(w, contentType, result.SetError(e), http.StatusBadRequest)
 		return
 	}
-	hostURL := helper.ConstructEmailURL(r.Referer(), map[string]string{"suffix": "/resetpassword?e="})
+	hostURL := helper.AppURL("/resetpassword?e=")
 	userMap := map[string]string{"id": user.ID, "fullname": user.Name, "email": user.Email}
 	if e = helper.ResetPasswordMailContent(userMap, tokenID, hostURL); e != nil {
 		log.Println("Error on sending reset password email:", e.Error())
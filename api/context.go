@@ -0,0 +1,46 @@
+package api
+
+import (
+	"context"
+
+	m "github.com/rinosukmandityo/user-profile/models"
+)
+
+// contextKey is the type of the request context keys of the handlers, a key of
+// its own type can't collide with the keys set by other packages.
+type contextKey int
+
+const (
+	sessionKey contextKey = iota
+	userKey
+	sessionCheckedKey
+)
+
+func withSession(ctx context.Context, sess m.Session) context.Context {
+	return context.WithValue(ctx, sessionKey, sess)
+}
+
+// sessionFrom returns the active session CheckSession or UserCtx has found.
+func sessionFrom(ctx context.Context) (m.Session, bool) {
+	sess, ok := ctx.Value(sessionKey).(m.Session)
+	return sess, ok
+}
+
+func withUser(ctx context.Context, user *m.User) context.Context {
+	return context.WithValue(ctx, userKey, user)
+}
+
+// userFrom returns the user of the active session, it is set by UserCtx.
+func userFrom(ctx context.Context) (*m.User, bool) {
+	user, ok := ctx.Value(userKey).(*m.User)
+	return user, ok
+}
+
+func withSessionChecked(ctx context.Context, checked bool) context.Context {
+	return context.WithValue(ctx, sessionCheckedKey, checked)
+}
+
+func isSessionChecked(ctx context.Context) bool {
+	checked, _ := ctx.Value(sessionCheckedKey).(bool)
+	return checked
+}
@@ -1,9 +1,19 @@
 package api
 
 import (
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/go-chi/chi"
 	"github.com/go-chi/chi/middleware"
 
+	"github.com/rinosukmandityo/user-profile/api/ratelimit"
+	"github.com/rinosukmandityo/user-profile/helper"
 	rh "github.com/rinosukmandityo/user-profile/repositories/helper"
 	"github.com/rinosukmandityo/user-profile/services/logic"
 )
@@ -13,48 +23,249 @@ var (
 		"/updateprofile": true,
 		"/mainprofile":   true,
 		"/logout":        true,
+		"/devices":       true,
 	}
 	pathPageMap = map[string]string{
-		"/":               "index.html",
-		"/updateprofile":  "update-profile.html",
-		"/mainprofile":    "main-profile.html",
-		"/forgotpassword": "forgot-password.html",
-		"/notregistered":  "user-not-registered.html",
-		"/emailduplicate": "email-duplicate.html",
-		"/resetsuccess":   "reset-success.html",
-		"/forgotsuccess":  "forgot-success.html",
+		"/":                 "index.html",
+		"/updateprofile":    "update-profile.html",
+		"/mainprofile":      "main-profile.html",
+		"/forgotpassword":   "forgot-password.html",
+		"/notregistered":    "user-not-registered.html",
+		"/emailduplicate":   "email-duplicate.html",
+		"/resetsuccess":     "reset-success.html",
+		"/forgotsuccess":    "forgot-success.html",
+		"/twofactor":        "two-factor.html",
+		"/verificationsent": "verification-sent.html",
+		"/emaillogin":       "magic-link.html",
+		"/devices":          "devices.html",
 	}
 	baseViewURL = "ui/views"
+	// defaultRateLimits are per route, ratelimits overrides them
+	defaultRateLimits = map[string]string{
+		"auth":           "20/1m",
+		"signup":         "5/1h",
+		"resetlink":      "5/1h",
+		"changepassword": "10/1h",
+		"updateuser":     "20/1m",
+		"twofactor":      "10/1m",
+		"resendverify":   "5/1h",
+		"magiclink":      "5/1h",
+		"restore":        "5/1h",
+	}
 )
 
 func RegisterHandler() *chi.Mux {
 	r := chi.NewRouter()
 	r.Use(middleware.RequestID)
-	r.Use(middleware.RealIP)
+	r.Use(RealIP(trustedProxies()))
 	r.Use(middleware.Recoverer)
+	helper.SetCookieOptions(cookieOptions())
 
-	userRepo, sessionRepo, tokenRepo := rh.ChooseRepo()
-	pageHandler := NewPageHandler(logic.NewSessionService(sessionRepo))
-	userHandler := NewUserHandler(logic.NewUserService(userRepo), logic.NewSessionService(sessionRepo))
-	loginHandler := NewLoginHandler(logic.NewSessionService(sessionRepo), logic.NewUserService(userRepo))
-	signupHandler := NewSignUpHandler(logic.NewSessionService(sessionRepo), logic.NewUserService(userRepo))
-	changePasswordHandler := NewChangePassword(logic.NewTokenService(tokenRepo, userRepo))
+	userRepo, sessionRepo, tokenRepo, attemptRepo, twoFactorRepo, transactor := rh.ChooseRepo()
+	sessionService := logic.NewSessionService(sessionRepo, userRepo, transactor, sessionPolicy())
+	pageHandler := NewPageHandler(sessionService)
+	policy := passwordPolicy()
+	userService := logic.NewUserService(userRepo, transactor, policy)
+	accountService := logic.NewAccountService(userRepo, sessionRepo, tokenRepo, twoFactorRepo, attemptRepo, transactor, helper.GracePeriod())
+	lockoutService := logic.NewLockoutService(attemptRepo, LockoutPolicy())
+	userHandler := NewUserHandler(userService, sessionService, accountService, lockoutService)
+	twoFactorService := logic.NewTwoFactorService(twoFactorRepo, transactor, totpIssuer())
+	twoFactorHandler := NewTwoFactorHandler(twoFactorService, sessionService)
+	tokenService := logic.NewTokenService(tokenRepo, userRepo, sessionRepo, attemptRepo, transactor, policy)
+	loginHandler := NewLoginHandler(sessionService, userService, lockoutService, twoFactorService, tokenService)
+	signupHandler := NewSignUpHandler(sessionService, userService, tokenService, transactor)
+	changePasswordHandler := NewChangePassword(tokenService)
+	verifyEmailHandler := NewVerifyEmailHandler(tokenService)
+	sessionHandler := NewSessionHandler(sessionService)
+	limiter := rateLimiter{store: ratelimit.NewMemoryStore(), limits: rateLimits()}
 	r.Use(pageHandler.CheckSession)
 
-	registerUserHandler(r, userHandler)
-	registerLoginHandler(r, loginHandler)
-	registerSignupHandler(r, signupHandler)
+	registerUserHandler(r, userHandler, twoFactorHandler, sessionHandler, limiter)
+	registerLoginHandler(r, loginHandler, limiter)
+	registerSignupHandler(r, signupHandler, limiter)
 	registerPageHandler(r, pageHandler)
-	registerChangePasswordHandler(r, changePasswordHandler)
+	registerChangePasswordHandler(r, changePasswordHandler, limiter)
+	registerVerifyEmailHandler(r, verifyEmailHandler, limiter)
 
 	return r
 }
 
-func registerUserHandler(r *chi.Mux, handler UserHandler) {
+// passwordPolicy reads the password policy, passwordrequire is a comma separated list
+// of lower, upper, digit and symbol, set it to none to require no character class.
+// breachedpasswords points to the HIBP range file or directory loaded at startup.
+func passwordPolicy() logic.PasswordPolicy {
+	policy := logic.DefaultPasswordPolicy()
+	if v, e := strconv.Atoi(os.Getenv("passwordminlength")); e == nil {
+		policy.MinLength = v
+	}
+	if v, e := strconv.Atoi(os.Getenv("passwordmaxlength")); e == nil {
+		policy.MaxLength = v
+	}
+	if v := os.Getenv("passwordrequire"); v != "" {
+		classes := map[string]bool{}
+		for _, class := range strings.Split(v, ",") {
+			classes[strings.TrimSpace(class)] = true
+		}
+		policy.RequireLower = classes["lower"]
+		policy.RequireUpper = classes["upper"]
+		policy.RequireDigit = classes["digit"]
+		policy.RequireSymbol = classes["symbol"]
+	}
+	if path := os.Getenv("breachedpasswords"); path != "" {
+		breached, e := logic.LoadBreachedPasswords(path)
+		if e != nil {
+			log.Fatal(e)
+		}
+		log.Printf("%d breached password hashes loaded\n", breached.Len())
+		policy.Breached = breached
+	}
+	return policy
+}
+
+// LockoutPolicy reads how many failed logins an account (lockoutmaxfailures) and an
+// IP address (lockoutipmaxfailures) may have before they are locked for
+// lockoutduration minutes, the purge command keeps the counters by it as well.
+func LockoutPolicy() logic.LockoutPolicy {
+	policy := logic.DefaultLockoutPolicy()
+	if v, e := strconv.Atoi(os.Getenv("lockoutmaxfailures")); e == nil && v > 0 {
+		policy.MaxFailures = v
+	}
+	if v, e := strconv.Atoi(os.Getenv("lockoutipmaxfailures")); e == nil && v > 0 {
+		policy.MaxIPFailures = v
+	}
+	if v, e := strconv.Atoi(os.Getenv("lockoutduration")); e == nil && v > 0 {
+		policy.Duration = time.Duration(v) * time.Minute
+	}
+	return policy
+}
+
+// sessionPolicy reads after how many minutes without requests a session ends
+// (sessionidletimeout), after how many hours it ends however active it is
+// (sessionlifetime) and for how many days a remembered session lasts (sessionrememberdays).
+func sessionPolicy() logic.SessionPolicy {
+	policy := logic.DefaultSessionPolicy()
+	if v, e := strconv.Atoi(os.Getenv("sessionidletimeout")); e == nil && v > 0 {
+		policy.IdleTimeout = time.Duration(v) * time.Minute
+	}
+	if v, e := strconv.Atoi(os.Getenv("sessionlifetime")); e == nil && v > 0 {
+		policy.Lifetime = time.Duration(v) * time.Hour
+	}
+	if v, e := strconv.Atoi(os.Getenv("sessionrememberdays")); e == nil && v > 0 {
+		policy.RememberLifetime = time.Duration(v) * 24 * time.Hour
+	}
+	return policy
+}
+
+// rateLimits reads the per route limits from ratelimits, a comma separated list of
+// route=<requests>/<period>, e.g. auth=20/1m,resetlink=5/1h.
+func rateLimits() map[string]ratelimit.Limit {
+	rules := map[string]string{}
+	for name, rule := range defaultRateLimits {
+		rules[name] = rule
+	}
+	if v := os.Getenv("ratelimits"); v != "" {
+		for _, pair := range strings.Split(v, ",") {
+			kv := strings.SplitN(pair, "=", 2)
+			if len(kv) != 2 {
+				log.Fatalf("invalid rate limit %q, use route=<requests>/<period>", pair)
+			}
+			name := strings.TrimSpace(kv[0])
+			if _, ok := defaultRateLimits[name]; !ok {
+				log.Fatalf("unknown rate limited route %q", name)
+			}
+			rules[name] = kv[1]
+		}
+	}
+	limits := map[string]ratelimit.Limit{}
+	for name, rule := range rules {
+		limit, e := ratelimit.ParseLimit(rule)
+		if e != nil {
+			log.Fatal(e)
+		}
+		limits[name] = limit
+	}
+	return limits
+}
+
+// trustedProxies reads trustedproxies, a comma separated list of the addresses or
+// CIDR ranges of the proxies in front of the server. Only they are trusted to
+// forward the client address, without any the peer address is the client.
+func trustedProxies() []*net.IPNet {
+	var networks []*net.IPNet
+	for _, v := range strings.Split(os.Getenv("trustedproxies"), ",") {
+		if v = strings.TrimSpace(v); v == "" {
+			continue
+		}
+		if !strings.Contains(v, "/") {
+			if ip := net.ParseIP(v); ip != nil && ip.To4() != nil {
+				v += "/32"
+			} else {
+				v += "/128"
+			}
+		}
+		_, network, e := net.ParseCIDR(v)
+		if e != nil {
+			log.Fatalf("invalid trusted proxy %q, use an address or a CIDR range", v)
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
+
+// cookieOptions reads how cookies are hardened. cookiekeys is a comma separated list
+// of secret keys signing the cookies, the first signs new cookies and the others
+// are still accepted while they are rotated out. cookiesamesite is lax (default),
+// strict or none, cookiesecure=true sends cookies over HTTPS only and
+// cookiehostprefix=true names them with the __Host- prefix.
+func cookieOptions() helper.CookieOptions {
+	opts := helper.CookieOptions{SameSite: http.SameSiteLaxMode}
+	for _, key := range strings.Split(os.Getenv("cookiekeys"), ",") {
+		if key = strings.TrimSpace(key); key == "" {
+			continue
+		}
+		if len(key) < 32 {
+			log.Println("Cookie key is shorter than 32 characters")
+		}
+		opts.Keys = append(opts.Keys, []byte(key))
+	}
+	switch strings.ToLower(os.Getenv("cookiesamesite")) {
+	case "strict":
+		opts.SameSite = http.SameSiteStrictMode
+	case "none":
+		opts.SameSite = http.SameSiteNoneMode
+	}
+	opts.Secure, _ = strconv.ParseBool(os.Getenv("cookiesecure"))
+	opts.HostPrefix, _ = strconv.ParseBool(os.Getenv("cookiehostprefix"))
+	return opts
+}
+
+// totpIssuer names the accounts in authenticator apps.
+func totpIssuer() string {
+	if v := os.Getenv("totpissuer"); v != "" {
+		return v
+	}
+	return "User Profile"
+}
+
+func registerUserHandler(r *chi.Mux, handler UserHandler, twoFactorHandler TwoFactorHandler, sessionHandler SessionHandler, limiter rateLimiter) {
 	r.Route("/user", func(r chi.Router) {
 		r.Use(handler.UserCtx)
-		r.Get("/", handler.Get)    // GET /user/
-		r.Put("/", handler.Update) // PUT /user/
+		r.Get("/", handler.Get)                                                    // GET /user/
+		r.With(limiter.limit("updateuser", KeyBySession)).Put("/", handler.Update) // PUT /user/
+		r.Delete("/", handler.Delete)                                              // DELETE /user/
+		r.Get("/twofactor", twoFactorHandler.Status)                               // GET /user/twofactor
+		r.Post("/twofactor", twoFactorHandler.Enroll)                              // POST /user/twofactor
+		r.Post("/twofactor/confirm", twoFactorHandler.Confirm)                     // POST /user/twofactor/confirm
+		r.Get("/sessions", sessionHandler.List)                                    // GET /user/sessions
+		r.Delete("/sessions", sessionHandler.RevokeAll)                            // DELETE /user/sessions
+		r.Delete("/sessions/{id}", sessionHandler.Revoke)                          // DELETE /user/sessions/{id}
+	})
+	r.With(limiter.limit("restore", KeyByIP, KeyByEmail)).Post("/restore", handler.Restore)
+	r.Route("/users", func(r chi.Router) {
+		r.Use(handler.UserCtx)
+		r.Use(handler.AdminCtx)
+		r.Use(twoFactorHandler.RequireTwoFactor)
+		r.Get("/", handler.List) // GET /users/
 	})
 }
 
@@ -64,22 +275,31 @@ func registerPageHandler(r *chi.Mux, handler PageHandler) {
 	}
 }
 
-func registerLoginHandler(r *chi.Mux, handler LoginHandler) {
-	r.Post("/auth", handler.Auth)
+func registerLoginHandler(r *chi.Mux, handler LoginHandler, limiter rateLimiter) {
+	r.With(limiter.limit("auth", KeyByIP)).Post("/auth", handler.Auth)
+	r.With(limiter.limit("twofactor", KeyByIP)).Post("/auth/twofactor", handler.AuthTwoFactor)
+	r.With(limiter.limit("magiclink", KeyByIP, KeyByEmail)).Post("/magiclink", handler.MagicLink)
+	r.Get("/magiclogin", handler.MagicLinkConfirm)
+	r.Post("/magiclogin", handler.MagicLinkLogin)
 	r.HandleFunc("/googlelogin", handler.GoogleLogin)
 	r.HandleFunc("/googlecallback", handler.GoogleCallback)
 	r.HandleFunc("/logout", handler.Logout)
 }
 
-func registerSignupHandler(r *chi.Mux, handler SignupHandler) {
-	r.Post("/dosignup", handler.DoSignUp)
+func registerSignupHandler(r *chi.Mux, handler SignupHandler, limiter rateLimiter) {
+	r.With(limiter.limit("signup", KeyByIP)).Post("/dosignup", handler.DoSignUp)
 	r.HandleFunc("/signup", handler.SignUp)
 	r.HandleFunc("/googlesignup", handler.GoogleSignUp)
 	r.HandleFunc("/googlesignupcallback", handler.GoogleSignUpCallback)
 }
 
-func registerChangePasswordHandler(r *chi.Mux, handler ChangePassword) {
-	r.HandleFunc("/resetlink", handler.ResetLink)
+func registerChangePasswordHandler(r *chi.Mux, handler ChangePassword, limiter rateLimiter) {
+	r.With(limiter.limit("resetlink", KeyByIP, KeyByEmail)).HandleFunc("/resetlink", handler.ResetLink)
 	r.HandleFunc("/resetpassword", handler.ResetPassword)
-	r.HandleFunc("/changepassword", handler.ChangePassword)
+	r.With(limiter.limit("changepassword", KeyByIP)).HandleFunc("/changepassword", handler.ChangePassword)
+}
+
+func registerVerifyEmailHandler(r *chi.Mux, handler VerifyEmailHandler, limiter rateLimiter) {
+	r.Get("/verifyemail", handler.VerifyEmail)
+	r.With(limiter.limit("resendverify", KeyByIP, KeyByEmail)).Post("/resendverification", handler.ResendVerification)
 }
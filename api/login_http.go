@@ -1,17 +1,16 @@
 package api
 
 import (
-	"context"
-	"crypto/sha256"
-	"encoding/hex"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"net/http"
 	"os"
+	"path"
 	"time"
 
 	"github.com/rinosukmandityo/user-profile/helper"
+	"github.com/rinosukmandityo/user-profile/helper/ids"
 	m "github.com/rinosukmandityo/user-profile/models"
 	svc "github.com/rinosukmandityo/user-profile/services"
 
@@ -22,14 +21,21 @@ import (
 
 type LoginHandler interface {
 	Auth(http.ResponseWriter, *http.Request)
+	AuthTwoFactor(http.ResponseWriter, *http.Request)
+	MagicLink(http.ResponseWriter, *http.Request)
+	MagicLinkConfirm(http.ResponseWriter, *http.Request)
+	MagicLinkLogin(http.ResponseWriter, *http.Request)
 	GoogleLogin(http.ResponseWriter, *http.Request)
 	GoogleCallback(w http.ResponseWriter, r *http.Request)
 	Logout(w http.ResponseWriter, r *http.Request)
 }
 
 type loginHandler struct {
-	sessionService svc.SessionService
-	userService    svc.UserService
+	sessionService   svc.SessionService
+	userService      svc.UserService
+	lockoutService   svc.LockoutService
+	twoFactorService svc.TwoFactorService
+	tokenService     svc.TokenService
 }
 
 const (
@@ -51,14 +57,76 @@ var (
 
 func init() {
 	if oauthStateString == "" {
-		timestamp := time.Now()
-		id := sha256.Sum256([]byte(fmt.Sprintf("%s", timestamp.String())))
-		oauthStateString = hex.EncodeToString(id[:])
+		state, e := ids.NewSecret()
+		if e != nil {
+			log.Fatal(e)
+		}
+		oauthStateString = state
+	}
+}
+
+// magicLinkValidity is how long a login link can be used.
+const magicLinkValidity = 15 * time.Minute
+
+func NewLoginHandler(sessionService svc.SessionService, userService svc.UserService, lockoutService svc.LockoutService, twoFactorService svc.TwoFactorService, tokenService svc.TokenService) LoginHandler {
+	return &loginHandler{sessionService, userService, lockoutService, twoFactorService, tokenService}
+}
+
+// setSessionCookies keeps the cookie of a session which isn't remembered until the
+// browser is closed, the server ends the session when it has been idle too long.
+// A remembered session keeps its cookie until the session ends.
+func setSessionCookies(w http.ResponseWriter, r *http.Request, tSession m.Session) {
+	expiresAfter := time.Duration(0)
+	if tSession.IsRemembered {
+		expiresAfter = time.Until(tSession.Expired)
 	}
+	helper.SetCookie(w, r, helper.SESSION_COOKIE_KEY, tSession.ID, expiresAfter)
 }
 
-func NewLoginHandler(sessionService svc.SessionService, userService svc.UserService) LoginHandler {
-	return &loginHandler{sessionService, userService}
+// endPreviousSessions logs out the sessions the request already carries. A login
+// always starts from a new session ID, so an ID planted in the browser before
+// the login is never the one that gets authenticated.
+func endPreviousSessions(r *http.Request, sessionService svc.SessionService) {
+	for _, name := range []string{helper.SESSION_COOKIE_KEY, helper.PENDING_SESSION_COOKIE_KEY} {
+		id, e := helper.GetCookie(r, name)
+		if e != nil || id == "" {
+			continue
+		}
+		if e := sessionService.Logout(r.Context(), m.Session{ID: id}); e != nil && errors.Cause(e) != helper.ErrUserNotFound {
+			log.Printf("Error on ending previous session: %s\n", e.Error())
+		}
+	}
+}
+
+// login creates the session of user, when two-factor authentication is enabled it
+// creates a pending session instead which AuthTwoFactor upgrades after a valid code.
+// A user who hasn't verified the email gets helper.ErrEmailNotVerified. A remembered
+// session lasts longer and keeps its cookie after the browser is closed.
+func (u *loginHandler) login(w http.ResponseWriter, r *http.Request, user m.User, remember bool) (pending bool, e error) {
+	if !user.IsActive {
+		return false, helper.ErrEmailNotVerified
+	}
+	endPreviousSessions(r, u.sessionService)
+	enabled, e := u.twoFactorService.IsEnabled(r.Context(), user.ID)
+	if e != nil {
+		return false, e
+	}
+	client := clientOf(r)
+	client.Remember = remember
+	if enabled {
+		tSession, e := u.sessionService.CreatePendingSession(r.Context(), user, client)
+		if e != nil {
+			return false, e
+		}
+		helper.SetCookie(w, r, helper.PENDING_SESSION_COOKIE_KEY, tSession.ID, time.Until(tSession.Expired))
+		return true, nil
+	}
+	tSession, e := u.sessionService.CreateNewSession(r.Context(), user, client)
+	if e != nil {
+		return false, e
+	}
+	setSessionCookies(w, r, tSession)
+	return false, nil
 }
 
 func (u *loginHandler) GoogleLogin(w http.ResponseWriter, r *http.Request) {
@@ -72,7 +140,7 @@ func (u *loginHandler) GoogleCallback(w http.ResponseWriter, r *http.Request) {
 		http.Redirect(w, r, "/", http.StatusTemporaryRedirect)
 		return
 	}
-	token, e := googleOauthConfig.Exchange(context.Background(), r.FormValue("code"))
+	token, e := googleOauthConfig.Exchange(r.Context(), r.FormValue("code"))
 	if e != nil {
 		log.Printf("Could not get token: %s\n", e.Error())
 		http.Redirect(w, r, "/", http.StatusTemporaryRedirect)
@@ -108,21 +176,32 @@ func (u *loginHandler) GoogleCallback(w http.ResponseWriter, r *http.Request) {
 		Name:  name,
 		Email: email,
 	}
-	if _, _, e = u.userService.GetByEmail(user.Email); e != nil {
+	_, registered, e := u.userService.GetByEmail(r.Context(), user.Email)
+	if e != nil {
 		if errors.Cause(e) == helper.ErrUserNotFound {
 			log.Println(helper.ErrAuthEmailMsg)
 			http.Redirect(w, r, "/notregistered", http.StatusTemporaryRedirect)
 			return
 		}
+		log.Printf("Could not get user: %s\n", e.Error())
+		http.Redirect(w, r, "/", http.StatusTemporaryRedirect)
+		return
 	}
 
-	tSession, e := u.sessionService.CreateNewSession(user)
+	pending, e := u.login(w, r, registered, false)
+	if e == helper.ErrEmailNotVerified {
+		http.Redirect(w, r, "/verificationsent", http.StatusTemporaryRedirect)
+		return
+	}
 	if e != nil {
 		log.Printf("Could not create new session: %s\n", e.Error())
 		http.Redirect(w, r, "/", http.StatusTemporaryRedirect)
+		return
+	}
+	if pending {
+		http.Redirect(w, r, "/twofactor", http.StatusTemporaryRedirect)
+		return
 	}
-	helper.SetCookie(w, r, helper.SESSION_COOKIE_KEY, tSession.ID, time.Until(tSession.Expired))
-	helper.SetCookie(w, r, helper.EMAIL_COOKIE_KEY, tSession.Email, time.Until(tSession.Expired))
 
 	http.Redirect(w, r, "/updateprofile", http.StatusTemporaryRedirect)
 }
@@ -140,39 +219,217 @@ func (u *loginHandler) Auth(w http.ResponseWriter, r *http.Request) {
 		ResponseWithResult(w, contentType, result.SetError(e), http.StatusBadRequest)
 		return
 	}
-	if _, _, e = u.sessionService.Authenticate(user.Email, user.Password); e != nil {
-		if errors.Cause(e) == helper.ErrUserNotFound {
-			ResponseWithResult(w, contentType, result.SetErrMsg(helper.ErrAuthEmailMsg), http.StatusNotFound)
-		} else if errors.Cause(e) == helper.ErrPasswordDoesNotMatch {
-			ResponseWithResult(w, contentType, result.SetErrMsg(helper.ErrAuthPasswordMsg), http.StatusNotFound)
+	data, e := GetSerializer(contentType).DecodeMap(requestBody)
+	if e != nil {
+		ResponseWithResult(w, contentType, result.SetError(e), http.StatusBadRequest)
+		return
+	}
+	remember, _ := data["Remember"].(bool)
+	ip := clientIP(r)
+	if lockedUntil, e := u.lockoutService.Check(r.Context(), user.Email, ip); e != nil {
+		if errors.Cause(e) == helper.ErrTooManyAttempts {
+			retryAfter(w, lockedUntil)
+			ResponseWithResult(w, contentType, result.SetError(e), http.StatusTooManyRequests)
+		} else {
+			ResponseWithResult(w, contentType, result.SetError(e), http.StatusInternalServerError)
+		}
+		return
+	}
+	_, authUser, e := u.sessionService.Authenticate(r.Context(), user.Email, user.Password)
+	if e != nil {
+		// an unknown email and a wrong password look the same so that accounts can't be enumerated
+		if errors.Cause(e) == helper.ErrUserNotFound || errors.Cause(e) == helper.ErrPasswordDoesNotMatch {
+			if _, e := u.lockoutService.Failure(r.Context(), user.Email, ip); e != nil {
+				log.Println("Error on counting failed login:", e.Error())
+			}
+			ResponseWithResult(w, contentType, result.SetErrMsg(helper.ErrAuthFailedMsg), http.StatusUnauthorized)
 		} else {
 			ResponseWithResult(w, contentType, result.SetError(e), http.StatusBadRequest)
 		}
 		return
 	}
-	tSession, e := u.sessionService.CreateNewSession(*user)
+	if e := u.lockoutService.Success(r.Context(), user.Email); e != nil {
+		log.Println("Error on resetting failed logins:", e.Error())
+	}
+	pending, e := u.login(w, r, authUser, remember)
+	if e == helper.ErrEmailNotVerified {
+		ResponseWithResult(w, contentType, result.SetError(e), http.StatusForbidden)
+		return
+	}
 	if e != nil {
 		ResponseWithResult(w, contentType, result.SetError(e), http.StatusInternalServerError)
 		return
 	}
-	helper.SetCookie(w, r, helper.SESSION_COOKIE_KEY, tSession.ID, time.Until(tSession.Expired))
-	helper.SetCookie(w, r, helper.EMAIL_COOKIE_KEY, tSession.Email, time.Until(tSession.Expired))
+	if pending {
+		ResponseWithResult(w, contentType, result.SetMessage(helper.TwoFactorCode), http.StatusAccepted)
+		return
+	}
+
+	ResponseWithResult(w, contentType, result.SetMessage(helper.SuccessLogin), http.StatusOK)
+}
+
+// AuthTwoFactor upgrades the pending session of a login to a full session with a
+// code of the authenticator app or a recovery code. Wrong codes count as failed logins.
+func (u *loginHandler) AuthTwoFactor(w http.ResponseWriter, r *http.Request) {
+	contentType := r.Header.Get("Content-Type")
+	result := helper.NewResult(nil)
+	pendingID, e := helper.GetCookie(r, helper.PENDING_SESSION_COOKIE_KEY)
+	if e != nil {
+		ResponseWithResult(w, contentType, result.SetError(helper.ErrSessionNotFound), http.StatusUnauthorized)
+		return
+	}
+	pending, e := u.sessionService.GetPendingSession(r.Context(), pendingID)
+	if e != nil {
+		if errors.Cause(e) == helper.ErrSessionNotFound {
+			ResponseWithResult(w, contentType, result.SetError(helper.ErrSessionNotFound), http.StatusUnauthorized)
+		} else {
+			ResponseWithResult(w, contentType, result.SetError(e), http.StatusInternalServerError)
+		}
+		return
+	}
+	requestBody, e := ioutil.ReadAll(r.Body)
+	if e != nil {
+		ResponseWithResult(w, contentType, result.SetError(e), http.StatusBadRequest)
+		return
+	}
+	data, e := GetSerializer(contentType).DecodeMap(requestBody)
+	if e != nil {
+		ResponseWithResult(w, contentType, result.SetError(e), http.StatusBadRequest)
+		return
+	}
+	code, _ := data["Code"].(string)
+
+	ip := clientIP(r)
+	if lockedUntil, e := u.lockoutService.Check(r.Context(), pending.Email, ip); e != nil {
+		if errors.Cause(e) == helper.ErrTooManyAttempts {
+			retryAfter(w, lockedUntil)
+			ResponseWithResult(w, contentType, result.SetError(e), http.StatusTooManyRequests)
+		} else {
+			ResponseWithResult(w, contentType, result.SetError(e), http.StatusInternalServerError)
+		}
+		return
+	}
+	if e = u.twoFactorService.Verify(r.Context(), pending.UserID, code); e != nil {
+		if errors.Cause(e) == helper.ErrInvalidCode {
+			if _, e := u.lockoutService.Failure(r.Context(), pending.Email, ip); e != nil {
+				log.Println("Error on counting failed login:", e.Error())
+			}
+			ResponseWithResult(w, contentType, result.SetError(helper.ErrInvalidCode), http.StatusUnauthorized)
+		} else {
+			ResponseWithResult(w, contentType, result.SetError(e), http.StatusInternalServerError)
+		}
+		return
+	}
+	if e := u.lockoutService.Success(r.Context(), pending.Email); e != nil {
+		log.Println("Error on resetting failed logins:", e.Error())
+	}
+	tSession, e := u.sessionService.CompletePendingSession(r.Context(), pending)
+	if e != nil {
+		if errors.Cause(e) == helper.ErrSessionNotFound {
+			// another request has completed the login with a code meanwhile
+			ResponseWithResult(w, contentType, result.SetError(helper.ErrSessionNotFound), http.StatusUnauthorized)
+		} else {
+			ResponseWithResult(w, contentType, result.SetError(e), http.StatusInternalServerError)
+		}
+		return
+	}
+	helper.SetCookie(w, r, helper.PENDING_SESSION_COOKIE_KEY, pending.ID, time.Minute*-30)
+	setSessionCookies(w, r, tSession)
 
 	ResponseWithResult(w, contentType, result.SetMessage(helper.SuccessLogin), http.StatusOK)
 }
 
 func (u *loginHandler) Logout(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	sess, ok := ctx.Value("session").(m.Session)
+	sess, ok := sessionFrom(ctx)
 	if !ok {
 		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
 		return
 	}
-	if e := u.sessionService.Logout(sess); e != nil {
+	if e := u.sessionService.Logout(r.Context(), sess); e != nil {
 		log.Printf("Error on session logout %s\n", e.Error())
 	}
 	helper.SetCookie(w, r, helper.SESSION_COOKIE_KEY, sess.ID, time.Minute*-30)
-	helper.SetCookie(w, r, helper.EMAIL_COOKIE_KEY, sess.Email, time.Minute*-30)
 
 	http.Redirect(w, r, "/", http.StatusTemporaryRedirect)
 }
+
+// MagicLink emails a one-time login link, it answers the same way whether or
+// not the email belongs to an account so that accounts can't be enumerated.
+func (u *loginHandler) MagicLink(w http.ResponseWriter, r *http.Request) {
+	contentType := r.Header.Get("Content-Type")
+	result := helper.NewResult(nil)
+	requestBody, e := ioutil.ReadAll(r.Body)
+	if e != nil {
+		ResponseWithResult(w, contentType, result.SetError(e), http.StatusBadRequest)
+		return
+	}
+	data, e := GetSerializer(contentType).DecodeMap(requestBody)
+	if e != nil {
+		ResponseWithResult(w, contentType, result.SetError(e), http.StatusBadRequest)
+		return
+	}
+	email, _ := data["Email"].(string)
+	user, tokenID, e := u.tokenService.MagicLinkByMail(r.Context(), email, magicLinkValidity)
+	if e != nil {
+		if cause := errors.Cause(e); cause != helper.ErrUserNotFound && cause != helper.ErrEmailNotVerified {
+			log.Println("Error on getting login token:", e.Error())
+		}
+	} else {
+		hostURL := helper.AppURL("/magiclogin?e=")
+		userMap := map[string]string{"id": user.ID, "fullname": user.Name, "email": user.Email}
+		if e = helper.MagicLinkMailContent(userMap, tokenID, hostURL); e != nil {
+			log.Println("Error on sending login link email:", e.Error())
+		}
+	}
+
+	ResponseWithResult(w, contentType, result.SetMessage(helper.MagicLinkSent), http.StatusOK)
+}
+
+// MagicLinkConfirm opens the link sent by MagicLink. It only asks to log in, mail
+// scanners and link previews fetch links too and would use up the token otherwise.
+func (u *loginHandler) MagicLinkConfirm(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	data := map[string]interface{}{}
+	if q.Get("e") == "" || q.Get("d") == "" {
+		data["ErrorMessage"] = helper.ErrInvalidToken
+		registerPage(w, path.Join(baseViewURL, "magic-link-error.html"), data)
+		return
+	}
+	data["TokenID"] = q.Get("e")
+	data["UserID"] = q.Get("d")
+	registerPage(w, path.Join(baseViewURL, "magic-link-confirm.html"), data)
+}
+
+// MagicLinkLogin logs in with the token posted from the MagicLinkConfirm page, it
+// stands in for the password only so two-factor authentication still asks for its code.
+func (u *loginHandler) MagicLinkLogin(w http.ResponseWriter, r *http.Request) {
+	tokenID, userID := r.PostFormValue("e"), r.PostFormValue("d")
+	data := map[string]interface{}{}
+	if tokenID == "" || userID == "" {
+		data["ErrorMessage"] = helper.ErrInvalidToken
+		registerPage(w, path.Join(baseViewURL, "magic-link-error.html"), data)
+		return
+	}
+
+	user, e := u.tokenService.ClaimMagicLink(r.Context(), userID, tokenID)
+	if e != nil {
+		log.Println("Error on claiming login link:", e.Error())
+		data["ErrorMessage"] = errors.Cause(e).Error()
+		registerPage(w, path.Join(baseViewURL, "magic-link-error.html"), data)
+		return
+	}
+	pending, e := u.login(w, r, user, false)
+	if e != nil {
+		log.Printf("Could not create new session: %s\n", e.Error())
+		data["ErrorMessage"] = e.Error()
+		registerPage(w, path.Join(baseViewURL, "magic-link-error.html"), data)
+		return
+	}
+	if pending {
+		http.Redirect(w, r, "/twofactor", http.StatusSeeOther)
+		return
+	}
+
+	http.Redirect(w, r, "/mainprofile", http.StatusSeeOther)
+}
@@ -2,10 +2,15 @@ package api_test
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
 	"github.com/rinosukmandityo/user-profile/api/caller"
 	"github.com/rinosukmandityo/user-profile/helper"
 	"net/http"
+	"strings"
 	"testing"
+	"time"
 
 	. "github.com/rinosukmandityo/user-profile/api"
 	m "github.com/rinosukmandityo/user-profile/models"
@@ -18,7 +23,7 @@ func TestAuthenticateUserSuccess(t *testing.T) {
 		ID:       "userid01",
 		Email:    "usermail01@gmail.com",
 		Address:  "User Address 01",
-		IsActive: false,
+		IsActive: true,
 	}}
 
 	defer func() {
@@ -31,7 +36,7 @@ func TestAuthenticateUserSuccess(t *testing.T) {
 	}()
 	_data := testdata[0]
 
-	if err := userService.Store(&_data); err != nil {
+	if err := userService.Store(context.Background(), &_data); err != nil {
 		t.Fatal(err)
 	}
 
@@ -76,6 +81,9 @@ func TestAuthenticateUserFailed(t *testing.T) {
 		if err := cleanupUserData(); err != nil {
 			t.Fatal(err)
 		}
+		if err := cleanupAttemptData(); err != nil {
+			t.Fatal(err)
+		}
 	}()
 
 	if err := seedUserData(testdata); err != nil {
@@ -98,15 +106,15 @@ func TestAuthenticateUserFailed(t *testing.T) {
 		{
 			name:               "email_does_not_exists",
 			data:               wrongEmail,
-			expectedMsg:        helper.ErrAuthEmailMsg,
-			expectedStatusCode: http.StatusNotFound,
+			expectedMsg:        helper.ErrAuthFailedMsg,
+			expectedStatusCode: http.StatusUnauthorized,
 			expectedResp:       &helper.ResultInfo{},
 		},
 		{
 			name:               "password_does_not_match",
 			data:               wrongPassword,
-			expectedMsg:        helper.ErrAuthPasswordMsg,
-			expectedStatusCode: http.StatusNotFound,
+			expectedMsg:        helper.ErrAuthFailedMsg,
+			expectedStatusCode: http.StatusUnauthorized,
 			expectedResp:       &helper.ResultInfo{},
 		},
 	}
@@ -143,3 +151,313 @@ func TestAuthenticateUserFailed(t *testing.T) {
 		})
 	}
 }
+
+func TestAuthenticateLockout(t *testing.T) {
+	testdata := m.User{
+		Name:     "User 01",
+		Password: "Password.1",
+		ID:       "userid01",
+		Email:    "usermail01@gmail.com",
+	}
+
+	defer func() {
+		if err := cleanupUserData(); err != nil {
+			t.Fatal(err)
+		}
+		if err := cleanupAttemptData(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	if err := seedUserData([]m.User{testdata}); err != nil {
+		t.Fatal(err)
+	}
+
+	wrongPassword := testdata
+	wrongPassword.Password = "Wrong.Password.1"
+
+	testTable := []struct {
+		name               string
+		data               m.User
+		expectedStatusCode int
+	}{
+		{name: "first_failure", data: wrongPassword, expectedStatusCode: http.StatusUnauthorized},
+		{name: "second_failure", data: wrongPassword, expectedStatusCode: http.StatusUnauthorized},
+		{name: "third_failure_backs_off", data: wrongPassword, expectedStatusCode: http.StatusUnauthorized},
+		{name: "correct_password_during_backoff", data: testdata, expectedStatusCode: http.StatusTooManyRequests},
+	}
+
+	for _, tt := range testTable {
+		dataBytes, err := getBytes(tt.data)
+		if err != nil {
+			t.Fatalf("cannot get data byte: %s", err.Error())
+		}
+		req, e := http.NewRequest("POST", "/auth", bytes.NewReader(dataBytes))
+		if e != nil {
+			t.Fatalf("failed to create a mew request: %s ", e.Error())
+		}
+		req.RemoteAddr = "10.0.0.1:1234"
+
+		resp, _, e := caller.New(r).SetRequest(req).SetResponse(&helper.ResultInfo{}).
+			SetHeader("Content-Type", ContentTypeJson).Exec()
+		if e != nil {
+			t.Fatalf("failed to call an API: %s ", e.Error())
+		}
+		if resp.Code != tt.expectedStatusCode {
+			t.Errorf("%s: status response is not correct, want %d, got %d", tt.name, tt.expectedStatusCode, resp.Code)
+		}
+		if tt.expectedStatusCode == http.StatusTooManyRequests && resp.Header().Get("Retry-After") == "" {
+			t.Errorf("%s: Retry-After header is missing", tt.name)
+		}
+	}
+}
+
+func TestMagicLinkLogin(t *testing.T) {
+	testData := []m.User{{
+		Name:     "User 01",
+		Password: "Password.1",
+		ID:       "userid01",
+		Email:    "usermail01@gmail.com",
+		IsActive: true,
+	}, {
+		Name:     "User 02",
+		Password: "Password.2",
+		ID:       "userid02",
+		Email:    "usermail02@gmail.com",
+		IsActive: true,
+	}}
+
+	defer func() {
+		if e := cleanupUserData(); e != nil {
+			t.Fatal(e)
+		}
+		if e := cleanupSessionData(); e != nil {
+			t.Fatal(e)
+		}
+		if e := cleanupTokenData(); e != nil {
+			t.Fatal(e)
+		}
+		if e := cleanupTwoFactorData(); e != nil {
+			t.Fatal(e)
+		}
+	}()
+
+	if e := seedUserData(testData); e != nil {
+		t.Fatal(e)
+	}
+	if e := twoFactorRepo.Save(context.Background(), &m.TwoFactor{ID: testData[1].ID, Secret: "JBSWY3DPEHPK3PXP", IsEnabled: true, Created: time.Now().UTC()}); e != nil {
+		t.Fatal(e)
+	}
+	for i, user := range testData {
+		token := m.Token{
+			ID:      fmt.Sprintf("token0%d", i+1),
+			UserID:  user.ID,
+			Created: time.Now().UTC(),
+			Expired: time.Now().UTC().Add(time.Minute * 15),
+			Purpose: m.TokenMagicLink,
+		}
+		if e := tokenRepo.Store(context.Background(), &token); e != nil {
+			t.Fatal(e)
+		}
+	}
+
+	testTable := []struct {
+		name             string
+		method           string
+		form             string
+		expectedLocation string
+		expectedCookie   string
+	}{
+		{name: "open_link", method: "GET"},
+		{name: "wrong_token", method: "POST", form: "e=token02&d=userid01"},
+		{name: "valid_token", method: "POST", form: "e=token01&d=userid01", expectedLocation: "/mainprofile", expectedCookie: helper.SESSION_COOKIE_KEY},
+		{name: "used_token", method: "POST", form: "e=token01&d=userid01"},
+		{name: "two_factor", method: "POST", form: "e=token02&d=userid02", expectedLocation: "/twofactor", expectedCookie: helper.PENDING_SESSION_COOKIE_KEY},
+	}
+
+	for _, tt := range testTable {
+		target := "/magiclogin"
+		if tt.method == "GET" {
+			// opening the link only asks to log in, the token is still there for valid_token
+			target += "?e=token01&d=userid01"
+		}
+		req, e := http.NewRequest(tt.method, target, strings.NewReader(tt.form))
+		if e != nil {
+			t.Fatal(e)
+		}
+		resp, _, e := caller.New(r).SetRequest(req).SetHeader("Content-Type", "application/x-www-form-urlencoded").Exec()
+		if e != nil {
+			t.Fatalf("failed to call an API: %s ", e.Error())
+		}
+		if location := resp.Header().Get("Location"); location != tt.expectedLocation {
+			t.Errorf("%s: redirect is not correct, want %q, got %q", tt.name, tt.expectedLocation, location)
+		}
+		for _, name := range []string{helper.SESSION_COOKIE_KEY, helper.PENDING_SESSION_COOKIE_KEY} {
+			if hasCookie := getCookie(resp.Result().Cookies(), name) != ""; hasCookie != (name == tt.expectedCookie) {
+				t.Errorf("%s: cookie %s is not correct, want %t, got %t", tt.name, name, name == tt.expectedCookie, hasCookie)
+			}
+		}
+	}
+}
+
+func TestMagicLink(t *testing.T) {
+	req, e := http.NewRequest("POST", "/magiclink", bytes.NewReader([]byte(`{"Email":"usermail01@gmail.com"}`)))
+	if e != nil {
+		t.Fatal(e)
+	}
+	resp, respBody, e := caller.New(r).SetRequest(req).SetResponse(&helper.ResultInfo{}).
+		SetHeader("Content-Type", ContentTypeJson).Exec()
+	if e != nil {
+		t.Fatalf("failed to call an API: %s ", e.Error())
+	}
+	if resp.Code != http.StatusOK {
+		t.Errorf("status response is not correct, want %d, got %d", http.StatusOK, resp.Code)
+	}
+	if msg := respBody.(*helper.ResultInfo).Message; msg != helper.MagicLinkSent {
+		t.Errorf("unknown email should get the same message, want %s, got %s", helper.MagicLinkSent, msg)
+	}
+}
+
+func TestLoginEndsPreviousSession(t *testing.T) {
+	testData := m.User{
+		Name:     "User 01",
+		Password: "Password.1",
+		ID:       "userid01",
+		Email:    "usermail01@gmail.com",
+		IsActive: true,
+	}
+
+	defer func() {
+		if e := cleanupUserData(); e != nil {
+			t.Fatal(e)
+		}
+		if e := cleanupSessionData(); e != nil {
+			t.Fatal(e)
+		}
+	}()
+
+	user := testData
+	if e := userService.Store(context.Background(), &user); e != nil {
+		t.Fatal(e)
+	}
+	// a session ID planted in the browser before the login
+	planted, e := sessionSvc.CreateNewSession(context.Background(), m.User{ID: "userid02", Email: "usermail02@gmail.com"}, m.Client{})
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	dataBytes, e := getBytes(testData)
+	if e != nil {
+		t.Fatal(e)
+	}
+	req, e := http.NewRequest("POST", "/auth", bytes.NewReader(dataBytes))
+	if e != nil {
+		t.Fatal(e)
+	}
+	resp, _, e := caller.New(r).SetRequest(req).SetResponse(&helper.ResultInfo{}).
+		SetHeader("Cookie", sessionCookie(planted.ID)).
+		SetHeader("Content-Type", ContentTypeJson).Exec()
+	if e != nil {
+		t.Fatalf("failed to call an API: %s ", e.Error())
+	}
+	if resp.Code != http.StatusOK {
+		t.Fatalf("status response is not correct, want %d, got %d", http.StatusOK, resp.Code)
+	}
+	if sessionID := getCookie(resp.Result().Cookies(), helper.SESSION_COOKIE_KEY); sessionID == "" || sessionID == planted.ID {
+		t.Errorf("login should start a new session, got %q", sessionID)
+	}
+	if active, _, _ := sessionSvc.IsSessionActive(context.Background(), planted.ID); active {
+		t.Error("previous session should be ended")
+	}
+}
+
+func TestAuthenticateRememberMe(t *testing.T) {
+	testData := m.User{
+		Name:     "User 01",
+		Password: "Password.1",
+		ID:       "userid01",
+		Email:    "usermail01@gmail.com",
+		IsActive: true,
+	}
+
+	defer func() {
+		if e := cleanupUserData(); e != nil {
+			t.Fatal(e)
+		}
+		if e := cleanupSessionData(); e != nil {
+			t.Fatal(e)
+		}
+	}()
+
+	user := testData
+	if e := userService.Store(context.Background(), &user); e != nil {
+		t.Fatal(e)
+	}
+
+	testTable := []struct {
+		name     string
+		remember bool
+	}{
+		{
+			name:     "browser_session",
+			remember: false,
+		},
+		{
+			name:     "remember_me",
+			remember: true,
+		},
+	}
+
+	for _, _tt := range testTable {
+		tt := _tt
+		t.Run(tt.name, func(t *testing.T) {
+			dataBytes, e := json.Marshal(map[string]interface{}{
+				"Email":    testData.Email,
+				"Password": testData.Password,
+				"Remember": tt.remember,
+			})
+			if e != nil {
+				t.Fatal(e)
+			}
+			req, e := http.NewRequest("POST", "/auth", bytes.NewReader(dataBytes))
+			if e != nil {
+				t.Fatal(e)
+			}
+			resp, _, e := caller.New(r).SetRequest(req).SetResponse(&helper.ResultInfo{}).
+				SetHeader("Content-Type", ContentTypeJson).Exec()
+			if e != nil {
+				t.Fatalf("failed to call an API: %s ", e.Error())
+			}
+			if resp.Code != http.StatusOK {
+				t.Fatalf("status response is not correct, want %d, got %d", http.StatusOK, resp.Code)
+			}
+			var cookie *http.Cookie
+			for _, c := range resp.Result().Cookies() {
+				if c.Name == helper.SESSION_COOKIE_KEY {
+					cookie = c
+				}
+			}
+			if cookie == nil {
+				t.Fatal("session cookie is not set")
+			}
+			// a browser session cookie has no expiry, a remembered one outlives the browser
+			if tt.remember != !cookie.Expires.IsZero() {
+				t.Errorf("cookie expiry is not correct, got %v", cookie.Expires)
+			}
+			if tt.remember && cookie.Expires.Before(time.Now().Add(24*time.Hour)) {
+				t.Errorf("remembered session should last for days, got %v", cookie.Expires)
+			}
+			sessionID, e := helper.VerifyCookie(helper.SESSION_COOKIE_KEY, cookie.Value)
+			if e != nil {
+				t.Fatal(e)
+			}
+			tSession, e := sessionSvc.GetBy(context.Background(), map[string]interface{}{"ID": sessionID})
+			if e != nil {
+				t.Fatal(e)
+			}
+			if tSession.IsRemembered != tt.remember {
+				t.Errorf("session should be remembered: %v, got %v", tt.remember, tSession.IsRemembered)
+			}
+		})
+	}
+}
@@ -1,9 +1,7 @@
 package api
 
 import (
-	"context"
 	"github.com/rinosukmandityo/user-profile/helper"
-	m "github.com/rinosukmandityo/user-profile/models"
 	svc "github.com/rinosukmandityo/user-profile/services"
 	"html/template"
 	"log"
@@ -35,13 +33,13 @@ func (u *pageHandler) CheckSession(next http.Handler) http.Handler {
 		var isSessionChecked bool
 
 		if sessionCheckPath[urlPath] {
-			sessCookie, e := r.Cookie(helper.SESSION_COOKIE_KEY)
-			if e != nil || sessCookie == nil {
+			sessionID, e := helper.GetCookie(r, helper.SESSION_COOKIE_KEY)
+			if e != nil {
 				log.Printf("Error on get session ID on cookies: %s\n", e.Error())
 				http.Redirect(w, r, "/", http.StatusTemporaryRedirect)
 				return
 			}
-			isActive, sess, e := u.sessionSvc.IsSessionActive(sessCookie.Value)
+			isActive, sess, e := u.sessionSvc.IsSessionActive(r.Context(), sessionID)
 			if e != nil {
 				log.Printf("Error on get session: %s\n", e.Error())
 				http.Redirect(w, r, "/", http.StatusTemporaryRedirect)
@@ -52,11 +50,11 @@ func (u *pageHandler) CheckSession(next http.Handler) http.Handler {
 				http.Redirect(w, r, "/", http.StatusTemporaryRedirect)
 				return
 			}
-			ctx = context.WithValue(ctx, "session", sess)
+			ctx = withSession(ctx, sess)
 			isSessionChecked = true
 		}
 
-		ctx = context.WithValue(ctx, "sessionChecked", isSessionChecked)
+		ctx = withSessionChecked(ctx, isSessionChecked)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
@@ -64,9 +62,8 @@ func (u *pageHandler) CheckSession(next http.Handler) http.Handler {
 func (u *pageHandler) CommonPageHandler(w http.ResponseWriter, r *http.Request) {
 	urlPath := r.URL.Path
 	ctx := r.Context()
-	isSessionChecked, _ := ctx.Value("sessionChecked").(bool)
-	if isSessionChecked {
-		_, ok := ctx.Value("session").(m.Session)
+	if isSessionChecked(ctx) {
+		_, ok := sessionFrom(ctx)
 		if !ok {
 			http.Redirect(w, r, "/", http.StatusTemporaryRedirect)
 			return
@@ -0,0 +1,112 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Limit is a token bucket which holds up to Burst tokens and gets one back every Interval.
+type Limit struct {
+	Burst    int
+	Interval time.Duration
+}
+
+// ParseLimit reads a limit written as <requests>/<period>, e.g. 5/1m allows
+// 5 requests at once and one more every 12 seconds.
+func ParseLimit(s string) (Limit, error) {
+	parts := strings.SplitN(s, "/", 2)
+	if len(parts) != 2 {
+		return Limit{}, fmt.Errorf("invalid rate limit %q, use <requests>/<period>", s)
+	}
+	burst, e := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if e != nil || burst <= 0 {
+		return Limit{}, fmt.Errorf("invalid rate limit %q, requests must be a positive number", s)
+	}
+	period, e := time.ParseDuration(strings.TrimSpace(parts[1]))
+	if e != nil || period <= 0 {
+		return Limit{}, fmt.Errorf("invalid rate limit %q, period must be a positive duration", s)
+	}
+	return Limit{Burst: burst, Interval: period / time.Duration(burst)}, nil
+}
+
+// Store keeps the token buckets. MemoryStore limits a single instance, a store
+// shared by every instance, e.g. on Redis, has to take the token atomically.
+type Store interface {
+	// Take removes a token from the bucket of key, when the bucket is empty it
+	// returns false and how long until the next token is available.
+	Take(ctx context.Context, key string, limit Limit) (bool, time.Duration, error)
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	limit  Limit
+}
+
+// full reports whether the bucket has refilled completely at now, such a
+// bucket is the same as a missing one.
+func (b *bucket) full(now time.Time) bool {
+	return now.Sub(b.last) >= time.Duration((float64(b.limit.Burst)-b.tokens)*float64(b.limit.Interval))
+}
+
+// MemoryStore keeps the buckets in process memory, full buckets are dropped
+// every sweepInterval so that idle keys don't pile up.
+type MemoryStore struct {
+	mu            sync.Mutex
+	buckets       map[string]*bucket
+	lastSweep     time.Time
+	sweepInterval time.Duration
+	now           func() time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets:       map[string]*bucket{},
+		sweepInterval: time.Minute,
+		now:           time.Now,
+	}
+}
+
+func (s *MemoryStore) Take(ctx context.Context, key string, limit Limit) (bool, time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+
+	b, ok := s.buckets[key]
+	if !ok || b.limit != limit {
+		b = &bucket{tokens: float64(limit.Burst), last: now, limit: limit}
+		s.buckets[key] = b
+	}
+	b.tokens += float64(now.Sub(b.last)) / float64(limit.Interval)
+	if b.tokens > float64(limit.Burst) {
+		b.tokens = float64(limit.Burst)
+	}
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0, nil
+	}
+	return false, time.Duration((1 - b.tokens) * float64(limit.Interval)), nil
+}
+
+func (s *MemoryStore) sweep(now time.Time) {
+	if s.lastSweep.IsZero() {
+		s.lastSweep = now
+	}
+	if now.Sub(s.lastSweep) < s.sweepInterval {
+		return
+	}
+	for key, b := range s.buckets {
+		if b.full(now) {
+			delete(s.buckets, key)
+		}
+	}
+	s.lastSweep = now
+}
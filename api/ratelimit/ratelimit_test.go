@@ -0,0 +1,88 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestParseLimit(t *testing.T) {
+	testTable := []struct {
+		name          string
+		limit         string
+		expectedLimit Limit
+		expectedError bool
+	}{
+		{name: "per_minute", limit: "5/1m", expectedLimit: Limit{Burst: 5, Interval: 12 * time.Second}},
+		{name: "per_hour", limit: "3/1h", expectedLimit: Limit{Burst: 3, Interval: 20 * time.Minute}},
+		{name: "no_period", limit: "5", expectedError: true},
+		{name: "zero_requests", limit: "0/1m", expectedError: true},
+		{name: "invalid_period", limit: "5/minute", expectedError: true},
+	}
+
+	for _, _tt := range testTable {
+		tt := _tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			limit, e := ParseLimit(tt.limit)
+			if (e != nil) != tt.expectedError {
+				t.Fatalf("error is not correct, want error %v, got %v", tt.expectedError, e)
+			}
+			if limit != tt.expectedLimit {
+				t.Errorf("limit is not correct, want %+v, got %+v", tt.expectedLimit, limit)
+			}
+		})
+	}
+}
+
+func TestMemoryStoreTake(t *testing.T) {
+	now := time.Now()
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+	limit := Limit{Burst: 2, Interval: time.Minute}
+	ctx := context.Background()
+
+	for i := 0; i < limit.Burst; i++ {
+		if ok, _, _ := store.Take(ctx, "ip:10.0.0.1", limit); !ok {
+			t.Fatalf("request %d should be allowed", i+1)
+		}
+	}
+	ok, retryAfter, _ := store.Take(ctx, "ip:10.0.0.1", limit)
+	if ok {
+		t.Fatal("request over the burst should be refused")
+	}
+	if retryAfter != time.Minute {
+		t.Errorf("retry after is not correct, want %s, got %s", time.Minute, retryAfter)
+	}
+	if ok, _, _ := store.Take(ctx, "ip:10.0.0.2", limit); !ok {
+		t.Error("another key should have its own bucket")
+	}
+
+	now = now.Add(30 * time.Second)
+	if _, retryAfter, _ := store.Take(ctx, "ip:10.0.0.1", limit); retryAfter != 30*time.Second {
+		t.Errorf("retry after is not correct, want %s, got %s", 30*time.Second, retryAfter)
+	}
+	now = now.Add(30 * time.Second)
+	if ok, _, _ := store.Take(ctx, "ip:10.0.0.1", limit); !ok {
+		t.Error("a token should be refilled after one interval")
+	}
+}
+
+func TestMemoryStoreSweep(t *testing.T) {
+	now := time.Now()
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+	limit := Limit{Burst: 2, Interval: time.Second}
+	ctx := context.Background()
+
+	store.Take(ctx, "ip:10.0.0.1", limit)
+	now = now.Add(store.sweepInterval)
+	store.Take(ctx, "ip:10.0.0.2", limit)
+
+	if _, ok := store.buckets["ip:10.0.0.1"]; ok {
+		t.Error("full bucket should be swept")
+	}
+	if _, ok := store.buckets["ip:10.0.0.2"]; !ok {
+		t.Error("bucket in use should be kept")
+	}
+}
@@ -0,0 +1,94 @@
+package api
+
+import (
+	"bytes"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rinosukmandityo/user-profile/api/ratelimit"
+	"github.com/rinosukmandityo/user-profile/helper"
+)
+
+// KeyFunc picks the value a request is limited by, an empty value means the
+// request is not limited by it.
+type KeyFunc func(r *http.Request) string
+
+// KeyByIP limits every client address.
+func KeyByIP(r *http.Request) string {
+	if ip := clientIP(r); ip != "" {
+		return "ip:" + ip
+	}
+	return ""
+}
+
+// KeyByEmail limits every Email in the request body, the body is left for the handler to read.
+func KeyByEmail(r *http.Request) string {
+	requestBody, e := ioutil.ReadAll(r.Body)
+	r.Body = ioutil.NopCloser(bytes.NewReader(requestBody))
+	if e != nil {
+		return ""
+	}
+	data, e := GetSerializer(r.Header.Get("Content-Type")).DecodeMap(requestBody)
+	if e != nil {
+		return ""
+	}
+	email, _ := data["Email"].(string)
+	if email = strings.ToLower(strings.TrimSpace(email)); email != "" {
+		return "email:" + email
+	}
+	return ""
+}
+
+// KeyBySession limits every logged in session.
+func KeyBySession(r *http.Request) string {
+	if id, e := helper.GetCookie(r, helper.SESSION_COOKIE_KEY); e == nil && id != "" {
+		return "session:" + id
+	}
+	return ""
+}
+
+// RateLimit lets every key of a request through at most at limit, name separates
+// the buckets of different routes in the same store. A request is refused with
+// 429 when any of its keys is over the limit. When the store fails the request
+// is let through, so an outage of a shared store doesn't take the routes down.
+func RateLimit(store ratelimit.Store, name string, limit ratelimit.Limit, keys ...KeyFunc) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var wait time.Duration
+			for _, keyFunc := range keys {
+				key := keyFunc(r)
+				if key == "" {
+					continue
+				}
+				ok, retry, e := store.Take(r.Context(), name+":"+key, limit)
+				if e != nil {
+					log.Println("Error on taking rate limit token:", e.Error())
+					continue
+				}
+				if !ok && retry > wait {
+					wait = retry
+				}
+			}
+			if wait > 0 {
+				retryAfter(w, time.Now().Add(wait))
+				result := helper.NewResult(nil).SetError(helper.ErrTooManyRequests)
+				ResponseWithResult(w, r.Header.Get("Content-Type"), result, http.StatusTooManyRequests)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+type rateLimiter struct {
+	store  ratelimit.Store
+	limits map[string]ratelimit.Limit
+}
+
+// limit rate limits a route with its configured limit.
+func (rl rateLimiter) limit(name string, keys ...KeyFunc) func(http.Handler) http.Handler {
+	return RateLimit(rl.store, name, rl.limits[name], keys...)
+}
@@ -0,0 +1,119 @@
+package api_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi"
+
+	. "github.com/rinosukmandityo/user-profile/api"
+	"github.com/rinosukmandityo/user-profile/api/ratelimit"
+	"github.com/rinosukmandityo/user-profile/helper"
+)
+
+func TestRateLimit(t *testing.T) {
+	limit := ratelimit.Limit{Burst: 2, Interval: time.Minute}
+	router := chi.NewRouter()
+	router.With(RateLimit(ratelimit.NewMemoryStore(), "resetlink", limit, KeyByIP, KeyByEmail)).
+		Post("/resetlink", func(w http.ResponseWriter, r *http.Request) {
+			// the handler still gets the body after the email key has read it
+			body, _ := ioutil.ReadAll(r.Body)
+			w.Write(body)
+		})
+
+	testTable := []struct {
+		name               string
+		ip                 string
+		email              string
+		expectedStatusCode int
+	}{
+		{name: "first_request", ip: "10.0.0.1:1234", email: "usermail01@gmail.com", expectedStatusCode: http.StatusOK},
+		{name: "second_request", ip: "10.0.0.1:1234", email: "usermail02@gmail.com", expectedStatusCode: http.StatusOK},
+		{name: "ip_over_limit", ip: "10.0.0.1:1234", email: "usermail03@gmail.com", expectedStatusCode: http.StatusTooManyRequests},
+		{name: "another_ip", ip: "10.0.0.2:1234", email: "usermail02@gmail.com", expectedStatusCode: http.StatusOK},
+		{name: "email_over_limit", ip: "10.0.0.3:1234", email: "UserMail02@gmail.com", expectedStatusCode: http.StatusTooManyRequests},
+	}
+
+	for _, tt := range testTable {
+		body := []byte(`{"Email":"` + tt.email + `"}`)
+		req, e := http.NewRequest(http.MethodPost, "/resetlink", bytes.NewReader(body))
+		if e != nil {
+			t.Fatal(e)
+		}
+		req.Header.Set("Content-Type", ContentTypeJson)
+		req.RemoteAddr = tt.ip
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+
+		if resp.Code != tt.expectedStatusCode {
+			t.Errorf("%s: status code is not correct, want %d, got %d", tt.name, tt.expectedStatusCode, resp.Code)
+			continue
+		}
+		if tt.expectedStatusCode == http.StatusOK && !bytes.Equal(resp.Body.Bytes(), body) {
+			t.Errorf("%s: handler got the wrong body %q", tt.name, resp.Body.String())
+		}
+		if tt.expectedStatusCode == http.StatusTooManyRequests {
+			if resp.Header().Get("Retry-After") != "60" {
+				t.Errorf("%s: Retry-After is not correct, want 60, got %q", tt.name, resp.Header().Get("Retry-After"))
+			}
+			result := helper.ResultInfo{}
+			if e := json.Unmarshal(resp.Body.Bytes(), &result); e != nil {
+				t.Fatal(e)
+			}
+			if result.Message != helper.ErrTooManyRequests.Error() {
+				t.Errorf("%s: message is not correct, want %s, got %s", tt.name, helper.ErrTooManyRequests.Error(), result.Message)
+			}
+		}
+	}
+}
+
+func TestRateLimitForwardedIP(t *testing.T) {
+	_, proxy, _ := net.ParseCIDR("10.0.0.0/8")
+	limit := ratelimit.Limit{Burst: 1, Interval: time.Minute}
+	router := chi.NewRouter()
+	router.Use(RealIP([]*net.IPNet{proxy}))
+	router.With(RateLimit(ratelimit.NewMemoryStore(), "auth", limit, KeyByIP)).
+		Post("/auth", func(w http.ResponseWriter, r *http.Request) {})
+
+	testTable := []struct {
+		name               string
+		ip                 string
+		forwardedFor       string
+		realIP             string
+		expectedStatusCode int
+	}{
+		{name: "client_first_request", ip: "192.0.2.1:1234", forwardedFor: "198.51.100.1", expectedStatusCode: http.StatusOK},
+		{name: "client_rotates_forwarded_for", ip: "192.0.2.1:1234", forwardedFor: "198.51.100.2", expectedStatusCode: http.StatusTooManyRequests},
+		{name: "client_rotates_real_ip", ip: "192.0.2.1:1234", realIP: "198.51.100.3", expectedStatusCode: http.StatusTooManyRequests},
+		{name: "proxy_forwards_client", ip: "10.0.0.1:1234", forwardedFor: "198.51.100.1", expectedStatusCode: http.StatusOK},
+		{name: "proxy_forwards_same_client", ip: "10.0.0.2:1234", forwardedFor: "198.51.100.1", expectedStatusCode: http.StatusTooManyRequests},
+		{name: "proxy_forwards_spoofed_chain", ip: "10.0.0.1:1234", forwardedFor: "203.0.113.9, 198.51.100.1, 10.0.0.3", expectedStatusCode: http.StatusTooManyRequests},
+		{name: "proxy_forwards_real_ip", ip: "10.0.0.1:1234", realIP: "198.51.100.4", expectedStatusCode: http.StatusOK},
+	}
+
+	for _, tt := range testTable {
+		req, e := http.NewRequest(http.MethodPost, "/auth", nil)
+		if e != nil {
+			t.Fatal(e)
+		}
+		req.RemoteAddr = tt.ip
+		if tt.forwardedFor != "" {
+			req.Header.Set("X-Forwarded-For", tt.forwardedFor)
+		}
+		if tt.realIP != "" {
+			req.Header.Set("X-Real-IP", tt.realIP)
+		}
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+
+		if resp.Code != tt.expectedStatusCode {
+			t.Errorf("%s: status code is not correct, want %d, got %d", tt.name, tt.expectedStatusCode, resp.Code)
+		}
+	}
+}
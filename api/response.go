@@ -2,9 +2,17 @@ package api
 
 import (
 	"log"
+	"math"
+	"net"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/rinosukmandityo/user-profile/helper"
+	m "github.com/rinosukmandityo/user-profile/models"
+
+	"github.com/pkg/errors"
 )
 
 func SetupResponse(w http.ResponseWriter, contentType string, body []byte, statusCode int) {
@@ -22,3 +30,98 @@ func ResponseWithResult(w http.ResponseWriter, contentType string, result *helpe
 	}
 	SetupResponse(w, contentType, respBody, statusCode)
 }
+
+// policyResult fills result with one message per broken password rule, or with
+// the breach message, and reports whether e was a password policy error.
+func policyResult(result *helper.ResultInfo, e error) bool {
+	if errors.Cause(e) == helper.ErrPasswordBreached {
+		result.SetErrMsg(helper.ErrPasswordBreached.Error())
+		return true
+	}
+	policyErr, ok := errors.Cause(e).(*helper.PasswordPolicyError)
+	if ok {
+		result.SetErrMsg(helper.ErrPasswordPolicyMsg).SetArrMsg(policyErr.Violations)
+	}
+	return ok
+}
+
+// retryAfter tells the client in whole seconds when it may try again.
+func retryAfter(w http.ResponseWriter, until time.Time) {
+	seconds := int(math.Ceil(time.Until(until).Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+}
+
+// clientIP returns the address of the client, RemoteAddr already holds the
+// forwarded address when the request came through a trusted proxy.
+func clientIP(r *http.Request) string {
+	if host, _, e := net.SplitHostPort(r.RemoteAddr); e == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+// RealIP sets RemoteAddr to the address a trusted proxy forwarded the request for.
+// X-Forwarded-For and X-Real-IP are only read when the peer is one of trusted,
+// anyone else could send them to pose as another client, and the first address
+// of X-Forwarded-For from the right which isn't a trusted proxy is the client.
+func RealIP(trusted []*net.IPNet) func(http.Handler) http.Handler {
+	isTrusted := func(addr string) bool {
+		ip := net.ParseIP(strings.TrimSpace(addr))
+		if ip == nil {
+			return false
+		}
+		for _, network := range trusted {
+			if network.Contains(ip) {
+				return true
+			}
+		}
+		return false
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if isTrusted(clientIP(r)) {
+				if ip := forwardedFor(r, isTrusted); ip != "" {
+					r.RemoteAddr = ip
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// forwardedFor returns the client address the proxies forwarded, or empty when
+// they didn't forward a valid one.
+func forwardedFor(r *http.Request, isTrusted func(string) bool) string {
+	if xff := r.Header.Values("X-Forwarded-For"); len(xff) > 0 {
+		addrs := strings.Split(strings.Join(xff, ","), ",")
+		for i := len(addrs) - 1; i >= 0; i-- {
+			addr := strings.TrimSpace(addrs[i])
+			if net.ParseIP(addr) == nil {
+				return ""
+			}
+			if i == 0 || !isTrusted(addr) {
+				return addr
+			}
+		}
+	}
+	if addr := strings.TrimSpace(r.Header.Get("X-Real-IP")); net.ParseIP(addr) != nil {
+		return addr
+	}
+	return ""
+}
+
+// maxUserAgentLength is the size of the UserAgent column of sessions.
+const maxUserAgentLength = 255
+
+// clientOf returns where the request came from, it is kept on the sessions the
+// request starts.
+func clientOf(r *http.Request) m.Client {
+	userAgent := r.UserAgent()
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+	}
+	return m.Client{IP: clientIP(r), UserAgent: userAgent}
+}
@@ -1,6 +1,8 @@
 package json
 
 import (
+	"bytes"
+
 	"github.com/rinosukmandityo/user-profile/helper"
 	m "github.com/rinosukmandityo/user-profile/models"
 
@@ -26,6 +28,17 @@ func (u *User) Encode(input *m.User) ([]byte, error) {
 	return rawMsg, nil
 }
 
+// DecodeUserUpdate rejects any field which is not part of m.UserUpdate.
+func (u *User) DecodeUserUpdate(input []byte) (*m.UserUpdate, error) {
+	data := new(m.UserUpdate)
+	decoder := json.NewDecoder(bytes.NewReader(input))
+	decoder.DisallowUnknownFields()
+	if e := decoder.Decode(data); e != nil {
+		return nil, errors.Wrap(e, "serializer.Logic.DecodeUserUpdate")
+	}
+	return data, nil
+}
+
 func (u *User) DecodeMap(input []byte) (map[string]interface{}, error) {
 	user := map[string]interface{}{}
 	if e := json.Unmarshal(input, &user); e != nil {
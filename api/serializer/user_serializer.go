@@ -8,6 +8,7 @@ import (
 type UserSerializer interface {
 	Decode(input []byte) (*m.User, error)
 	Encode(input *m.User) ([]byte, error)
+	DecodeUserUpdate(input []byte) (*m.UserUpdate, error)
 	DecodeMap(input []byte) (map[string]interface{}, error)
 	EncodeMap(input map[string]interface{}) ([]byte, error)
 	DecodeResult(input []byte) (*helper.ResultInfo, error)
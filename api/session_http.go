@@ -0,0 +1,134 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi"
+
+	"github.com/rinosukmandityo/user-profile/helper"
+	m "github.com/rinosukmandityo/user-profile/models"
+	svc "github.com/rinosukmandityo/user-profile/services"
+)
+
+// SessionHandler lists the devices a user is logged in on and signs them out.
+type SessionHandler interface {
+	List(w http.ResponseWriter, r *http.Request)
+	Revoke(w http.ResponseWriter, r *http.Request)
+	RevokeAll(w http.ResponseWriter, r *http.Request)
+}
+
+type sessionHandler struct {
+	sessionService svc.SessionService
+}
+
+func NewSessionHandler(sessionService svc.SessionService) SessionHandler {
+	return &sessionHandler{sessionService}
+}
+
+// device is a session as its user sees it. The session ID is the credential of
+// the session cookie, so a session is only referred to by a hash of its ID.
+type device struct {
+	ID        string    `json:"ID" msgpack:"ID"`
+	IP        string    `json:"IP" msgpack:"IP"`
+	UserAgent string    `json:"UserAgent" msgpack:"UserAgent"`
+	Created   time.Time `json:"Created" msgpack:"Created"`
+	LastSeen  time.Time `json:"LastSeen" msgpack:"LastSeen"`
+	Current   bool      `json:"Current" msgpack:"Current"`
+}
+
+func deviceID(sessionID string) string {
+	sum := sha256.Sum256([]byte(sessionID))
+	return base64.RawURLEncoding.EncodeToString(sum[:16])
+}
+
+func (u *sessionHandler) List(w http.ResponseWriter, r *http.Request) {
+	result := helper.NewResult(nil)
+	contentType := r.Header.Get("Content-Type")
+	ctx := r.Context()
+	user, ok := userFrom(ctx)
+	if !ok {
+		ResponseWithResult(w, contentType, result.SetErrMsg(helper.ErrUserNotFound.Error()), http.StatusBadRequest)
+		return
+	}
+	current, _ := sessionFrom(ctx)
+	sessions, e := u.sessionService.ListSessions(ctx, user.ID)
+	if e != nil {
+		ResponseWithResult(w, contentType, result.SetError(e), http.StatusInternalServerError)
+		return
+	}
+	devices := make([]device, len(sessions))
+	for i, sess := range sessions {
+		devices[i] = device{
+			ID:        deviceID(sess.ID),
+			IP:        sess.IP,
+			UserAgent: sess.UserAgent,
+			Created:   sess.Created,
+			LastSeen:  sess.LastSeen,
+			Current:   sess.ID == current.ID,
+		}
+	}
+	ResponseWithResult(w, contentType, result.SetData(devices).SetTotal(len(devices)), http.StatusOK)
+}
+
+// Revoke signs out the session DELETE /user/sessions/{id} refers to, only the
+// sessions of the logged in user can be found this way.
+func (u *sessionHandler) Revoke(w http.ResponseWriter, r *http.Request) {
+	result := helper.NewResult(nil)
+	contentType := r.Header.Get("Content-Type")
+	ctx := r.Context()
+	user, ok := userFrom(ctx)
+	if !ok {
+		ResponseWithResult(w, contentType, result.SetErrMsg(helper.ErrUserNotFound.Error()), http.StatusBadRequest)
+		return
+	}
+	sessions, e := u.sessionService.ListSessions(ctx, user.ID)
+	if e != nil {
+		ResponseWithResult(w, contentType, result.SetError(e), http.StatusInternalServerError)
+		return
+	}
+	id := chi.URLParam(r, "id")
+	var target *m.Session
+	for i := range sessions {
+		if deviceID(sessions[i].ID) == id {
+			target = &sessions[i]
+			break
+		}
+	}
+	if target == nil {
+		ResponseWithResult(w, contentType, result.SetErrMsg(helper.ErrSessionNotFound.Error()), http.StatusNotFound)
+		return
+	}
+	if e := u.sessionService.Logout(ctx, *target); e != nil {
+		ResponseWithResult(w, contentType, result.SetError(e), http.StatusInternalServerError)
+		return
+	}
+	if current, _ := sessionFrom(ctx); current.ID == target.ID {
+		helper.SetCookie(w, r, helper.SESSION_COOKIE_KEY, target.ID, time.Minute*-30)
+	}
+	ResponseWithResult(w, contentType, result.SetMessage(helper.SuccessRevoke), http.StatusOK)
+}
+
+// RevokeAll signs out every device of the logged in user, the current one included.
+func (u *sessionHandler) RevokeAll(w http.ResponseWriter, r *http.Request) {
+	result := helper.NewResult(nil)
+	contentType := r.Header.Get("Content-Type")
+	ctx := r.Context()
+	user, ok := userFrom(ctx)
+	if !ok {
+		ResponseWithResult(w, contentType, result.SetErrMsg(helper.ErrUserNotFound.Error()), http.StatusBadRequest)
+		return
+	}
+	if e := u.sessionService.LogoutAll(ctx, user.ID); e != nil {
+		log.Printf("Error on logging out all sessions: %s\n", e.Error())
+		ResponseWithResult(w, contentType, result.SetError(e), http.StatusInternalServerError)
+		return
+	}
+	if current, ok := sessionFrom(ctx); ok {
+		helper.SetCookie(w, r, helper.SESSION_COOKIE_KEY, current.ID, time.Minute*-30)
+	}
+	ResponseWithResult(w, contentType, result.SetMessage(helper.SuccessRevokeAll), http.StatusOK)
+}
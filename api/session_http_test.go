@@ -0,0 +1,242 @@
+package api_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"testing"
+
+	. "github.com/rinosukmandityo/user-profile/api"
+	"github.com/rinosukmandityo/user-profile/api/caller"
+	"github.com/rinosukmandityo/user-profile/helper"
+	m "github.com/rinosukmandityo/user-profile/models"
+)
+
+func TestDeviceSessions(t *testing.T) {
+	testData := m.User{
+		Name:     "User 01",
+		Password: "Password.1",
+		ID:       "userid01",
+		Email:    "usermail01@gmail.com",
+		IsActive: true,
+	}
+
+	defer func() {
+		if e := cleanupUserData(); e != nil {
+			t.Fatal(e)
+		}
+		if e := cleanupSessionData(); e != nil {
+			t.Fatal(e)
+		}
+	}()
+
+	user := testData
+	if e := userService.Store(context.Background(), &user); e != nil {
+		t.Fatal(e)
+	}
+	dataBytes, e := getBytes(testData)
+	if e != nil {
+		t.Fatal(e)
+	}
+	login := func(ip, userAgent string) string {
+		req, e := http.NewRequest("POST", "/auth", bytes.NewReader(dataBytes))
+		if e != nil {
+			t.Fatal(e)
+		}
+		req.RemoteAddr = ip + ":1234"
+		resp, _, e := caller.New(r).SetRequest(req).SetResponse(&helper.ResultInfo{}).
+			SetHeader("User-Agent", userAgent).
+			SetHeader("Content-Type", ContentTypeJson).Exec()
+		if e != nil {
+			t.Fatalf("failed to call an API: %s ", e.Error())
+		}
+		if resp.Code != http.StatusOK {
+			t.Fatalf("failed to log in, want %d, got %d", http.StatusOK, resp.Code)
+		}
+		return getCookie(resp.Result().Cookies(), helper.SESSION_COOKIE_KEY)
+	}
+	call := func(method, path, sessionID string) (int, *helper.ResultInfo) {
+		req, e := http.NewRequest(method, path, nil)
+		if e != nil {
+			t.Fatal(e)
+		}
+		resp, respBody, e := caller.New(r).SetRequest(req).SetResponse(&helper.ResultInfo{}).
+			SetHeader("Cookie", sessionCookie(sessionID)).
+			SetHeader("Content-Type", ContentTypeJson).Exec()
+		// a logged out session is redirected with an HTML body
+		if e != nil && resp.Code != http.StatusTemporaryRedirect {
+			t.Fatalf("failed to call an API: %s ", e.Error())
+		}
+		result, _ := respBody.(*helper.ResultInfo)
+		return resp.Code, result
+	}
+
+	laptop := login("10.0.0.1", "Laptop Browser")
+	phone := login("10.0.0.2", "Phone Browser")
+	if laptop == "" || laptop == phone {
+		t.Fatalf("every login should get its own session, got %q and %q", laptop, phone)
+	}
+
+	code, result := call("GET", "/user/sessions", laptop)
+	if code != http.StatusOK {
+		t.Fatalf("status response is not correct, want %d, got %d", http.StatusOK, code)
+	}
+	devices, _ := result.Data.([]interface{})
+	if len(devices) != 2 {
+		t.Fatalf("want 2 devices, got %d", len(devices))
+	}
+	var phoneID string
+	for _, item := range devices {
+		d := item.(map[string]interface{})
+		if d["ID"] == laptop || d["ID"] == phone {
+			t.Error("session ID must not be shown")
+		}
+		switch d["UserAgent"] {
+		case "Laptop Browser":
+			if d["IP"] != "10.0.0.1" || d["Current"] != true {
+				t.Errorf("laptop is not correct, got %v", d)
+			}
+		case "Phone Browser":
+			if d["IP"] != "10.0.0.2" || d["Current"] != false {
+				t.Errorf("phone is not correct, got %v", d)
+			}
+			phoneID, _ = d["ID"].(string)
+		default:
+			t.Errorf("unknown device %v", d)
+		}
+	}
+
+	if code, _ := call("DELETE", "/user/sessions/unknown", laptop); code != http.StatusNotFound {
+		t.Errorf("unknown device, want %d, got %d", http.StatusNotFound, code)
+	}
+	if code, _ := call("DELETE", "/user/sessions/"+phoneID, laptop); code != http.StatusOK {
+		t.Fatalf("status response is not correct, want %d, got %d", http.StatusOK, code)
+	}
+	if code, _ := call("GET", "/user/sessions", phone); code != http.StatusTemporaryRedirect {
+		t.Errorf("signed out device should be logged out, want %d, got %d", http.StatusTemporaryRedirect, code)
+	}
+	if code, _ := call("GET", "/user/sessions", laptop); code != http.StatusOK {
+		t.Errorf("the other device should stay logged in, want %d, got %d", http.StatusOK, code)
+	}
+
+	tablet := login("10.0.0.3", "Tablet Browser")
+	if code, _ := call("DELETE", "/user/sessions", laptop); code != http.StatusOK {
+		t.Fatalf("status response is not correct, want %d, got %d", http.StatusOK, code)
+	}
+	for _, sessionID := range []string{laptop, tablet} {
+		if code, _ := call("GET", "/user/sessions", sessionID); code != http.StatusTemporaryRedirect {
+			t.Errorf("every device should be logged out, want %d, got %d", http.StatusTemporaryRedirect, code)
+		}
+	}
+}
+
+func TestSignedCookies(t *testing.T) {
+	testData := m.User{
+		Name:     "User 01",
+		Password: "Password.1",
+		ID:       "userid01",
+		Email:    "usermail01@gmail.com",
+		IsActive: true,
+	}
+	oldKey := []byte("old cookie key of at least 32 characters")
+	newKey := []byte("new cookie key of at least 32 characters")
+
+	defer func() {
+		helper.SetCookieOptions(helper.CookieOptions{Keys: [][]byte{newKey}, SameSite: http.SameSiteLaxMode})
+		if e := cleanupUserData(); e != nil {
+			t.Fatal(e)
+		}
+		if e := cleanupSessionData(); e != nil {
+			t.Fatal(e)
+		}
+	}()
+
+	user := testData
+	if e := userService.Store(context.Background(), &user); e != nil {
+		t.Fatal(e)
+	}
+	tSession, e := sessionSvc.CreateNewSession(context.Background(), user, m.Client{})
+	if e != nil {
+		t.Fatal(e)
+	}
+	call := func(cookie string) int {
+		req, e := http.NewRequest("GET", "/user/sessions", nil)
+		if e != nil {
+			t.Fatal(e)
+		}
+		resp, _, e := caller.New(r).SetRequest(req).SetResponse(&helper.ResultInfo{}).
+			SetHeader("Cookie", cookie).
+			SetHeader("Content-Type", ContentTypeJson).Exec()
+		if e != nil && resp.Code != http.StatusTemporaryRedirect {
+			t.Fatalf("failed to call an API: %s ", e.Error())
+		}
+		return resp.Code
+	}
+
+	helper.SetCookieOptions(helper.CookieOptions{Keys: [][]byte{oldKey}})
+	signed := helper.SignCookie(helper.SESSION_COOKIE_KEY, tSession.ID)
+	tampered := []byte(signed)
+	tampered[0] ^= 1
+
+	testTable := []struct {
+		name               string
+		cookie             string
+		expectedStatusCode int
+	}{
+		{name: "signed", cookie: helper.SESSION_COOKIE_KEY + "=" + signed, expectedStatusCode: http.StatusOK},
+		{name: "unsigned", cookie: helper.SESSION_COOKIE_KEY + "=" + tSession.ID, expectedStatusCode: http.StatusTemporaryRedirect},
+		{name: "tampered", cookie: helper.SESSION_COOKIE_KEY + "=" + string(tampered), expectedStatusCode: http.StatusTemporaryRedirect},
+		{
+			name:               "signed_as_another_cookie",
+			cookie:             helper.SESSION_COOKIE_KEY + "=" + helper.SignCookie(helper.PENDING_SESSION_COOKIE_KEY, tSession.ID),
+			expectedStatusCode: http.StatusTemporaryRedirect,
+		},
+	}
+	for _, tt := range testTable {
+		if code := call(tt.cookie); code != tt.expectedStatusCode {
+			t.Errorf("%s: status response is not correct, want %d, got %d", tt.name, tt.expectedStatusCode, code)
+		}
+	}
+
+	// a rotated key still accepts the cookies signed by the old one until it is dropped
+	helper.SetCookieOptions(helper.CookieOptions{Keys: [][]byte{newKey, oldKey}})
+	if code := call(helper.SESSION_COOKIE_KEY + "=" + signed); code != http.StatusOK {
+		t.Errorf("cookie of the old key should be accepted, want %d, got %d", http.StatusOK, code)
+	}
+	if helper.SignCookie(helper.SESSION_COOKIE_KEY, tSession.ID) == signed {
+		t.Error("new cookies should be signed with the new key")
+	}
+	helper.SetCookieOptions(helper.CookieOptions{Keys: [][]byte{newKey}})
+	if code := call(helper.SESSION_COOKIE_KEY + "=" + signed); code != http.StatusTemporaryRedirect {
+		t.Errorf("cookie of a dropped key should be refused, want %d, got %d", http.StatusTemporaryRedirect, code)
+	}
+
+	helper.SetCookieOptions(helper.CookieOptions{Keys: [][]byte{newKey}, SameSite: http.SameSiteStrictMode, HostPrefix: true})
+	dataBytes, e := getBytes(testData)
+	if e != nil {
+		t.Fatal(e)
+	}
+	req, e := http.NewRequest("POST", "/auth", bytes.NewReader(dataBytes))
+	if e != nil {
+		t.Fatal(e)
+	}
+	resp, _, e := caller.New(r).SetRequest(req).SetResponse(&helper.ResultInfo{}).
+		SetHeader("Content-Type", ContentTypeJson).Exec()
+	if e != nil {
+		t.Fatalf("failed to call an API: %s ", e.Error())
+	}
+	cookies := resp.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("want a session cookie, got %v", cookies)
+	}
+	c := cookies[0]
+	if c.Name != "__Host-"+helper.SESSION_COOKIE_KEY || !c.Secure || !c.HttpOnly || c.SameSite != http.SameSiteStrictMode || c.Domain != "" || c.Path != "/" {
+		t.Errorf("cookie is not hardened, got %+v", c)
+	}
+	if code := call(c.Name + "=" + c.Value); code != http.StatusOK {
+		t.Errorf("prefixed cookie should be accepted, want %d, got %d", http.StatusOK, code)
+	}
+	if code := call(helper.SESSION_COOKIE_KEY + "=" + c.Value); code != http.StatusTemporaryRedirect {
+		t.Errorf("cookie without the prefix should be refused, want %d, got %d", http.StatusTemporaryRedirect, code)
+	}
+}
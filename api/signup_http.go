@@ -2,18 +2,17 @@ package api
 
 import (
 	"context"
-	"crypto/sha256"
-	"encoding/hex"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"net/http"
 	"os"
 	"path"
-	"time"
 
 	"github.com/rinosukmandityo/user-profile/helper"
+	"github.com/rinosukmandityo/user-profile/helper/ids"
 	m "github.com/rinosukmandityo/user-profile/models"
+	repo "github.com/rinosukmandityo/user-profile/repositories"
 	svc "github.com/rinosukmandityo/user-profile/services"
 
 	"github.com/pkg/errors"
@@ -31,6 +30,8 @@ type SignupHandler interface {
 type signupHandler struct {
 	sessionService svc.SessionService
 	userService    svc.UserService
+	tokenService   svc.TokenService
+	transactor     repo.Transactor
 }
 
 var (
@@ -48,14 +49,42 @@ var (
 
 func init() {
 	if oauthStateString == "" {
-		timestamp := time.Now()
-		id := sha256.Sum256([]byte(fmt.Sprintf("%s", timestamp.String())))
-		oauthStateString = hex.EncodeToString(id[:])
+		state, e := ids.NewSecret()
+		if e != nil {
+			log.Fatal(e)
+		}
+		oauthStateString = state
 	}
 }
 
-func NewSignUpHandler(sessionService svc.SessionService, userService svc.UserService) SignupHandler {
-	return &signupHandler{sessionService, userService}
+func NewSignUpHandler(sessionService svc.SessionService, userService svc.UserService, tokenService svc.TokenService, transactor repo.Transactor) SignupHandler {
+	return &signupHandler{sessionService, userService, tokenService, transactor}
+}
+
+// registerUnverified stores the new user and its email verification token as one
+// unit of work, the user can't log in until the email has been verified.
+func (u *signupHandler) registerUnverified(ctx context.Context, user *m.User) (tokenID string, e error) {
+	e = u.transactor.WithinTransaction(ctx, func(ctx context.Context) (e error) {
+		if e = u.userService.Store(ctx, user); e != nil {
+			return
+		}
+		_, tokenID, e = u.tokenService.VerificationByMail(ctx, user.Email, verificationValidity)
+		return
+	})
+	return
+}
+
+// register stores the new user and opens its first session as one unit of work,
+// so a failed session doesn't leave an account behind that can't sign up again.
+func (u *signupHandler) register(ctx context.Context, user *m.User, client m.Client) (tSession m.Session, e error) {
+	e = u.transactor.WithinTransaction(ctx, func(ctx context.Context) (e error) {
+		if e = u.userService.Store(ctx, user); e != nil {
+			return
+		}
+		tSession, e = u.sessionService.CreateNewSession(ctx, *user, client)
+		return
+	})
+	return
 }
 
 func (u *signupHandler) SignUp(w http.ResponseWriter, r *http.Request) {
@@ -81,22 +110,21 @@ func (u *signupHandler) DoSignUp(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, e.Error(), http.StatusBadRequest)
 		return
 	}
-	if e := u.userService.Store(user); e != nil {
+	// only the Google sign up may create an account without a password or a verified email
+	user.IsGoogleAuth = false
+	user.IsActive = false
+	if tokenID, e := u.registerUnverified(r.Context(), user); e != nil {
 		if errors.Cause(e) == helper.ErrEmailDuplicate {
 			result.SetErrMsg(helper.ErrDuplicateEmail)
-		} else {
+		} else if !policyResult(result, e) {
 			result.SetErrMsg(e.Error())
 		}
 		statusCode = http.StatusBadRequest
-	}
-	if result.Success {
-		tSession, e := u.sessionService.CreateNewSession(*user)
-		if e != nil {
-			result.SetError(e)
+	} else {
+		// the account is kept when the email can't be sent, the link can be sent again
+		if e := sendVerificationMail(r, *user, tokenID); e != nil {
+			log.Println("Error on sending verification email:", e.Error())
 		}
-		helper.SetCookie(w, r, helper.SESSION_COOKIE_KEY, tSession.ID, time.Until(tSession.Expired))
-		helper.SetCookie(w, r, helper.EMAIL_COOKIE_KEY, tSession.Email, time.Until(tSession.Expired))
-
 		result.SetMessage(helper.SuccessSignup)
 	}
 	respBody, e := GetSerializer(contentType).EncodeResult(result)
@@ -112,7 +140,7 @@ func (u *signupHandler) GoogleSignUpCallback(w http.ResponseWriter, r *http.Requ
 		http.Redirect(w, r, "/", http.StatusTemporaryRedirect)
 		return
 	}
-	token, e := googleSignupOauthConfig.Exchange(context.Background(), r.FormValue("code"))
+	token, e := googleSignupOauthConfig.Exchange(r.Context(), r.FormValue("code"))
 	if e != nil {
 		log.Printf("Could not get token: %s\n", e.Error())
 		http.Redirect(w, r, "/", http.StatusTemporaryRedirect)
@@ -144,25 +172,26 @@ func (u *signupHandler) GoogleSignUpCallback(w http.ResponseWriter, r *http.Requ
 	if nameInt, hasData := respMap["name"]; hasData {
 		name = nameInt.(string)
 	}
+	// Google has verified the email already
 	user := &m.User{
-		Name:  name,
-		Email: email,
+		Name:         name,
+		Email:        email,
+		IsActive:     true,
+		IsGoogleAuth: true,
 	}
-	if e := u.userService.Store(user); e != nil {
+	endPreviousSessions(r, u.sessionService)
+	tSession, e := u.register(r.Context(), user, clientOf(r))
+	if e != nil {
 		if errors.Cause(e) == helper.ErrEmailDuplicate {
 			log.Println(helper.ErrEmailDuplicate)
 			http.Redirect(w, r, "/emailduplicate", http.StatusTemporaryRedirect)
 			return
 		}
-	}
-
-	tSession, e := u.sessionService.CreateNewSession(*user)
-	if e != nil {
-		log.Printf("Could not create new session: %s\n", e.Error())
+		log.Printf("Could not sign up: %s\n", e.Error())
 		http.Redirect(w, r, "/", http.StatusTemporaryRedirect)
+		return
 	}
-	helper.SetCookie(w, r, helper.SESSION_COOKIE_KEY, tSession.ID, time.Until(tSession.Expired))
-	helper.SetCookie(w, r, helper.EMAIL_COOKIE_KEY, tSession.Email, time.Until(tSession.Expired))
+	setSessionCookies(w, r, tSession)
 
 	http.Redirect(w, r, "/updateprofile", http.StatusTemporaryRedirect)
 }
@@ -2,6 +2,7 @@ package api_test
 
 import (
 	"bytes"
+	"context"
 	"net/http"
 	"reflect"
 	"strings"
@@ -28,7 +29,7 @@ func TestSignUpSuccess(t *testing.T) {
 		if err := cleanupUserData(); err != nil {
 			t.Fatal(err)
 		}
-		if err := cleanupSessionData(); err != nil {
+		if err := cleanupTokenData(); err != nil {
 			t.Fatal(err)
 		}
 	}()
@@ -53,7 +54,7 @@ func TestSignUpSuccess(t *testing.T) {
 	actualMsg := result.Message
 	expectedMsg := helper.SuccessSignup
 
-	actualData, e := userService.GetById("userid01")
+	actualData, e := userService.GetById(context.Background(), "userid01")
 	if e != nil {
 		t.Errorf("unable to get data by ID")
 	}
@@ -65,8 +66,17 @@ func TestSignUpSuccess(t *testing.T) {
 	if actualMsg != expectedMsg {
 		t.Errorf("error message is not correct, want %s, got %s", expectedMsg, actualMsg)
 	}
+	if getCookie(resp.Result().Cookies(), helper.SESSION_COOKIE_KEY) != "" {
+		t.Error("no session should be created before the email is verified")
+	}
+	if _, e := tokenRepo.GetLatestToken(context.Background(), "userid01", m.TokenVerifyEmail); e != nil {
+		t.Errorf("email verification token should be created: %v", e)
+	}
 
-	expectedData.Password = repositories.EncryptPassword(expectedData.Password)
+	if !repositories.IsPasswordMatch(expectedData.Password, actualData.Password) {
+		t.Errorf("password is not stored as a hash of the signed up password")
+	}
+	expectedData.Password, actualData.Password = "", ""
 	if !reflect.DeepEqual(expectedData, actualData) {
 		t.Errorf("data is not correct, \nwant: \n%+v, \ngot: \n%+v", expectedData, actualData)
 	}
@@ -98,12 +108,18 @@ func TestSignUpFailed(t *testing.T) {
 	duplicateEntry := testdata[0]
 	duplicateEntry.Email = "random email"
 
+	weakPassword := testdata[0]
+	weakPassword.ID = "userid02"
+	weakPassword.Email = "usermail02@gmail.com"
+	weakPassword.Password = "usermail02"
+
 	testTable := []struct {
 		name               string
 		data               m.User
 		expectedMsg        string
 		expectedStatusCode int
 		expectedResp       interface{}
+		expectedArrMsg     []string
 	}{
 		{
 			name:               "duplicate_email",
@@ -119,6 +135,14 @@ func TestSignUpFailed(t *testing.T) {
 			expectedStatusCode: http.StatusBadRequest,
 			expectedResp:       &helper.ResultInfo{},
 		},
+		{
+			name:               "weak_password",
+			data:               weakPassword,
+			expectedMsg:        helper.ErrPasswordPolicyMsg,
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResp:       &helper.ResultInfo{},
+			expectedArrMsg:     []string{helper.ErrPasswordNoUpper, helper.ErrPasswordHasEmail, helper.ErrPasswordHasName},
+		},
 	}
 
 	for _, _tt := range testTable {
@@ -150,6 +174,10 @@ func TestSignUpFailed(t *testing.T) {
 			if !strings.Contains(actualMsg, tt.expectedMsg) {
 				t.Errorf("error messsage is not correct, want %s, got %s", tt.expectedMsg, actualMsg)
 			}
+
+			if tt.expectedArrMsg != nil && !reflect.DeepEqual(tt.expectedArrMsg, result.ArrMsg) {
+				t.Errorf("error messages are not correct, want %v, got %v", tt.expectedArrMsg, result.ArrMsg)
+			}
 		})
 	}
 }
@@ -0,0 +1,141 @@
+package api
+
+import (
+	"io/ioutil"
+	"log"
+	"net/http"
+
+	"github.com/rinosukmandityo/user-profile/helper"
+	svc "github.com/rinosukmandityo/user-profile/services"
+
+	"github.com/pkg/errors"
+)
+
+// TwoFactorHandler enrols the logged in user into TOTP two-factor authentication,
+// the code itself is checked on login by LoginHandler.AuthTwoFactor.
+type TwoFactorHandler interface {
+	Status(http.ResponseWriter, *http.Request)
+	Enroll(http.ResponseWriter, *http.Request)
+	Confirm(http.ResponseWriter, *http.Request)
+	RequireTwoFactor(http.Handler) http.Handler
+}
+
+type twoFactorHandler struct {
+	twoFactorService svc.TwoFactorService
+	sessionService   svc.SessionService
+}
+
+func NewTwoFactorHandler(twoFactorService svc.TwoFactorService, sessionService svc.SessionService) TwoFactorHandler {
+	return &twoFactorHandler{twoFactorService, sessionService}
+}
+
+func (u *twoFactorHandler) Status(w http.ResponseWriter, r *http.Request) {
+	contentType := r.Header.Get("Content-Type")
+	result := helper.NewResult(nil)
+	user, ok := userFrom(r.Context())
+	if !ok {
+		http.Error(w, http.StatusText(http.StatusUnprocessableEntity), http.StatusUnprocessableEntity)
+		return
+	}
+	enabled, e := u.twoFactorService.IsEnabled(r.Context(), user.ID)
+	if e != nil {
+		ResponseWithResult(w, contentType, result.SetError(e), http.StatusInternalServerError)
+		return
+	}
+	ResponseWithResult(w, contentType, result.SetData(map[string]interface{}{"IsEnabled": enabled}), http.StatusOK)
+}
+
+// Enroll returns a new secret with its otpauth URI, two-factor authentication is
+// only enabled once Confirm gets a code made from it.
+func (u *twoFactorHandler) Enroll(w http.ResponseWriter, r *http.Request) {
+	contentType := r.Header.Get("Content-Type")
+	result := helper.NewResult(nil)
+	user, ok := userFrom(r.Context())
+	if !ok {
+		http.Error(w, http.StatusText(http.StatusUnprocessableEntity), http.StatusUnprocessableEntity)
+		return
+	}
+	enrollment, e := u.twoFactorService.Enroll(r.Context(), *user)
+	if e != nil {
+		if errors.Cause(e) == helper.ErrTwoFactorEnabled {
+			ResponseWithResult(w, contentType, result.SetError(helper.ErrTwoFactorEnabled), http.StatusConflict)
+			return
+		}
+		log.Println("Error on enrolling two-factor authentication:", e.Error())
+		ResponseWithResult(w, contentType, result.SetError(e), http.StatusInternalServerError)
+		return
+	}
+	ResponseWithResult(w, contentType, result.SetData(enrollment), http.StatusOK)
+}
+
+// Confirm enables two-factor authentication and returns the recovery codes, they can't be shown again.
+// The confirmed code is a second factor, the session moves to a new ID which has passed it.
+func (u *twoFactorHandler) Confirm(w http.ResponseWriter, r *http.Request) {
+	contentType := r.Header.Get("Content-Type")
+	result := helper.NewResult(nil)
+	user, ok := userFrom(r.Context())
+	if !ok {
+		http.Error(w, http.StatusText(http.StatusUnprocessableEntity), http.StatusUnprocessableEntity)
+		return
+	}
+	requestBody, e := ioutil.ReadAll(r.Body)
+	if e != nil {
+		ResponseWithResult(w, contentType, result.SetError(e), http.StatusBadRequest)
+		return
+	}
+	data, e := GetSerializer(contentType).DecodeMap(requestBody)
+	if e != nil {
+		ResponseWithResult(w, contentType, result.SetError(e), http.StatusBadRequest)
+		return
+	}
+	code, _ := data["Code"].(string)
+	codes, e := u.twoFactorService.Confirm(r.Context(), user.ID, code)
+	if e != nil {
+		switch errors.Cause(e) {
+		case helper.ErrInvalidCode, helper.ErrTwoFactorNotFound:
+			ResponseWithResult(w, contentType, result.SetError(errors.Cause(e)), http.StatusBadRequest)
+		case helper.ErrTwoFactorEnabled:
+			ResponseWithResult(w, contentType, result.SetError(helper.ErrTwoFactorEnabled), http.StatusConflict)
+		default:
+			log.Println("Error on confirming two-factor authentication:", e.Error())
+			ResponseWithResult(w, contentType, result.SetError(e), http.StatusInternalServerError)
+		}
+		return
+	}
+	sess, _ := sessionFrom(r.Context())
+	sess.IsTwoFactor = true
+	tSession, e := u.sessionService.Rotate(r.Context(), sess, *user)
+	if e != nil {
+		log.Println("Error on rotating session:", e.Error())
+		ResponseWithResult(w, contentType, result.SetError(e), http.StatusInternalServerError)
+		return
+	}
+	setSessionCookies(w, r, tSession)
+	ResponseWithResult(w, contentType, result.SetData(map[string]interface{}{"RecoveryCodes": codes}), http.StatusOK)
+}
+
+// RequireTwoFactor refuses users without two-factor authentication and sessions
+// which haven't passed the second factor, it guards every route which shows the
+// data of other customers. A session from before the enrolment or from the password
+// alone is refused even when the user has enabled two-factor authentication.
+func (u *twoFactorHandler) RequireTwoFactor(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentType := r.Header.Get("Content-Type")
+		user, ok := userFrom(r.Context())
+		sess, _ := sessionFrom(r.Context())
+		if !ok || !sess.IsTwoFactor {
+			ResponseWithResult(w, contentType, helper.NewResult(nil).SetErrMsg(helper.ErrTwoFactorNeeded), http.StatusForbidden)
+			return
+		}
+		enabled, e := u.twoFactorService.IsEnabled(r.Context(), user.ID)
+		if e != nil {
+			ResponseWithResult(w, contentType, helper.NewResult(nil).SetError(e), http.StatusInternalServerError)
+			return
+		}
+		if !enabled {
+			ResponseWithResult(w, contentType, helper.NewResult(nil).SetErrMsg(helper.ErrTwoFactorNeeded), http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
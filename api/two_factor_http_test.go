@@ -0,0 +1,209 @@
+package api_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	. "github.com/rinosukmandityo/user-profile/api"
+	"github.com/rinosukmandityo/user-profile/api/caller"
+	"github.com/rinosukmandityo/user-profile/helper"
+	m "github.com/rinosukmandityo/user-profile/models"
+	"github.com/rinosukmandityo/user-profile/services/logic"
+)
+
+// getCookie returns the verified value of cookie name, empty when it isn't set or deleted.
+func getCookie(cookies []*http.Cookie, name string) string {
+	for _, c := range cookies {
+		if c.Name == name {
+			value, _ := helper.VerifyCookie(name, c.Value)
+			return value
+		}
+	}
+	return ""
+}
+
+// sessionCookie is the Cookie header of a logged in session.
+func sessionCookie(sessionID string) string {
+	return fmt.Sprintf("%s=%s", helper.SESSION_COOKIE_KEY, helper.SignCookie(helper.SESSION_COOKIE_KEY, sessionID))
+}
+
+func TestEnrollTwoFactor(t *testing.T) {
+	testData := m.User{
+		Name:     "User 01",
+		Password: "Password.1",
+		ID:       "userid01",
+		Email:    "usermail01@gmail.com",
+	}
+
+	defer func() {
+		if e := cleanupUserData(); e != nil {
+			t.Fatal(e)
+		}
+		if e := cleanupSessionData(); e != nil {
+			t.Fatal(e)
+		}
+		if e := cleanupTwoFactorData(); e != nil {
+			t.Fatal(e)
+		}
+	}()
+
+	if e := seedUserData([]m.User{testData}); e != nil {
+		t.Fatal(e)
+	}
+	tSession, e := sessionSvc.CreateNewSession(context.Background(), testData, m.Client{})
+	if e != nil {
+		t.Fatal(e)
+	}
+	cookieSession := sessionCookie(tSession.ID)
+	call := func(method, path, body string) (int, *helper.ResultInfo) {
+		req, e := http.NewRequest(method, path, bytes.NewReader([]byte(body)))
+		if e != nil {
+			t.Fatal(e)
+		}
+		resp, respBody, e := caller.New(r).SetRequest(req).SetResponse(&helper.ResultInfo{}).
+			SetHeader("Cookie", cookieSession).
+			SetHeader("Content-Type", ContentTypeJson).Exec()
+		if e != nil {
+			t.Fatalf("failed to call an API: %s ", e.Error())
+		}
+		if id := getCookie(resp.Result().Cookies(), helper.SESSION_COOKIE_KEY); id != "" {
+			cookieSession = sessionCookie(id)
+		}
+		return resp.Code, respBody.(*helper.ResultInfo)
+	}
+
+	code, result := call("POST", "/user/twofactor", "")
+	if code != http.StatusOK {
+		t.Fatalf("status response is not correct, want %d, got %d", http.StatusOK, code)
+	}
+	secret, _ := result.Data.(map[string]interface{})["Secret"].(string)
+	if secret == "" {
+		t.Fatal("secret should be returned")
+	}
+
+	if code, _ := call("POST", "/user/twofactor/confirm", `{"Code":"abcdef"}`); code != http.StatusBadRequest {
+		t.Errorf("wrong code should be rejected, want %d, got %d", http.StatusBadRequest, code)
+	}
+	totp, _ := logic.GenerateTOTP(secret, time.Now())
+	code, result = call("POST", "/user/twofactor/confirm", `{"Code":"`+totp+`"}`)
+	if code != http.StatusOK {
+		t.Fatalf("status response is not correct, want %d, got %d", http.StatusOK, code)
+	}
+	if codes, _ := result.Data.(map[string]interface{})["RecoveryCodes"].([]interface{}); len(codes) != 10 {
+		t.Errorf("want 10 recovery codes, got %d", len(codes))
+	}
+	// confirming moves the session to a new ID which has passed the second factor
+	if active, _, _ := sessionSvc.IsSessionActive(context.Background(), tSession.ID); active {
+		t.Error("session from before the confirmation should be logged out")
+	}
+	if cookieSession == sessionCookie(tSession.ID) {
+		t.Fatal("session should be rotated")
+	}
+
+	if _, result := call("GET", "/user/twofactor", ""); result.Data.(map[string]interface{})["IsEnabled"] != true {
+		t.Error("two-factor authentication should be enabled")
+	}
+	if code, _ := call("POST", "/user/twofactor", ""); code != http.StatusConflict {
+		t.Errorf("enabled two-factor authentication should not be replaced, want %d, got %d", http.StatusConflict, code)
+	}
+}
+
+func TestAuthTwoFactor(t *testing.T) {
+	testData := m.User{
+		Name:     "User 01",
+		Password: "Password.1",
+		ID:       "userid01",
+		Email:    "usermail01@gmail.com",
+		IsActive: true,
+	}
+	secret := "JBSWY3DPEHPK3PXP"
+
+	defer func() {
+		if e := cleanupUserData(); e != nil {
+			t.Fatal(e)
+		}
+		if e := cleanupSessionData(); e != nil {
+			t.Fatal(e)
+		}
+		if e := cleanupTwoFactorData(); e != nil {
+			t.Fatal(e)
+		}
+		if e := cleanupAttemptData(); e != nil {
+			t.Fatal(e)
+		}
+	}()
+
+	// the password is hashed to log in with it
+	user := testData
+	if e := userService.Store(context.Background(), &user); e != nil {
+		t.Fatal(e)
+	}
+	if e := twoFactorRepo.Save(context.Background(), &m.TwoFactor{ID: testData.ID, Secret: secret, IsEnabled: true, Created: time.Now().UTC()}); e != nil {
+		t.Fatal(e)
+	}
+
+	dataBytes, e := getBytes(testData)
+	if e != nil {
+		t.Fatal(e)
+	}
+	req, e := http.NewRequest("POST", "/auth", bytes.NewReader(dataBytes))
+	if e != nil {
+		t.Fatal(e)
+	}
+	resp, _, e := caller.New(r).SetRequest(req).SetResponse(&helper.ResultInfo{}).
+		SetHeader("Content-Type", ContentTypeJson).Exec()
+	if e != nil {
+		t.Fatalf("failed to call an API: %s ", e.Error())
+	}
+	if resp.Code != http.StatusAccepted {
+		t.Fatalf("status response is not correct, want %d, got %d", http.StatusAccepted, resp.Code)
+	}
+	if getCookie(resp.Result().Cookies(), helper.SESSION_COOKIE_KEY) != "" {
+		t.Error("no session should be created before the second factor")
+	}
+	pendingID := getCookie(resp.Result().Cookies(), helper.PENDING_SESSION_COOKIE_KEY)
+	if pendingID == "" {
+		t.Fatal("pending session cookie is missing")
+	}
+
+	totp, _ := logic.GenerateTOTP(secret, time.Now())
+	testTable := []struct {
+		name               string
+		pendingID          string
+		code               string
+		expectedStatusCode int
+	}{
+		{name: "no_pending_session", code: totp, expectedStatusCode: http.StatusUnauthorized},
+		{name: "wrong_code", pendingID: pendingID, code: "abcdef", expectedStatusCode: http.StatusUnauthorized},
+		{name: "valid_code", pendingID: pendingID, code: totp, expectedStatusCode: http.StatusOK},
+		{name: "pending_session_used", pendingID: pendingID, code: totp, expectedStatusCode: http.StatusUnauthorized},
+	}
+
+	for _, tt := range testTable {
+		req, e := http.NewRequest("POST", "/auth/twofactor", bytes.NewReader([]byte(`{"Code":"`+tt.code+`"}`)))
+		if e != nil {
+			t.Fatal(e)
+		}
+		if tt.pendingID != "" {
+			req.AddCookie(&http.Cookie{Name: helper.PENDING_SESSION_COOKIE_KEY, Value: helper.SignCookie(helper.PENDING_SESSION_COOKIE_KEY, tt.pendingID)})
+		}
+		resp, _, e := caller.New(r).SetRequest(req).SetResponse(&helper.ResultInfo{}).
+			SetHeader("Content-Type", ContentTypeJson).Exec()
+		if e != nil {
+			t.Fatalf("failed to call an API: %s ", e.Error())
+		}
+		if resp.Code != tt.expectedStatusCode {
+			t.Errorf("%s: status response is not correct, want %d, got %d", tt.name, tt.expectedStatusCode, resp.Code)
+		}
+		if tt.expectedStatusCode == http.StatusOK {
+			sessionID := getCookie(resp.Result().Cookies(), helper.SESSION_COOKIE_KEY)
+			if active, sess, _ := sessionSvc.IsSessionActive(context.Background(), sessionID); !active || !sess.IsTwoFactor {
+				t.Errorf("%s: session should be active and have passed the second factor, got %v %+v", tt.name, active, sess)
+			}
+		}
+	}
+}
@@ -1,10 +1,10 @@
 package api
 
 import (
-	"context"
 	"io/ioutil"
 	"log"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/rinosukmandityo/user-profile/helper"
@@ -18,15 +18,21 @@ type UserHandler interface {
 	UserCtx(http.Handler) http.Handler
 	Get(http.ResponseWriter, *http.Request)
 	Update(http.ResponseWriter, *http.Request)
+	Delete(http.ResponseWriter, *http.Request)
+	Restore(http.ResponseWriter, *http.Request)
+	AdminCtx(http.Handler) http.Handler
+	List(http.ResponseWriter, *http.Request)
 }
 
 type userHandler struct {
 	userService    svc.UserService
 	sessionService svc.SessionService
+	accountService svc.AccountService
+	lockoutService svc.LockoutService
 }
 
-func NewUserHandler(userService svc.UserService, sessionService svc.SessionService) UserHandler {
-	return &userHandler{userService, sessionService}
+func NewUserHandler(userService svc.UserService, sessionService svc.SessionService, accountService svc.AccountService, lockoutService svc.LockoutService) UserHandler {
+	return &userHandler{userService, sessionService, accountService, lockoutService}
 }
 
 func (u *userHandler) UserCtx(next http.Handler) http.Handler {
@@ -35,13 +41,14 @@ func (u *userHandler) UserCtx(next http.Handler) http.Handler {
 		w.Header().Set("pragma", "no-cache")
 		w.Header().Set("expires", "Sat, 01 Jan 1990 00:00:00 GMT")
 
-		sessCookie, e := r.Cookie(helper.SESSION_COOKIE_KEY)
-		if e != nil || sessCookie == nil {
+		// a tampered or unsigned cookie is refused before the session is looked up
+		sessionID, e := helper.GetCookie(r, helper.SESSION_COOKIE_KEY)
+		if e != nil {
 			log.Printf("Error on get session ID on cookies: %s\n", e.Error())
 			http.Redirect(w, r, "/", http.StatusTemporaryRedirect)
 			return
 		}
-		isActive, _, e := u.sessionService.IsSessionActive(sessCookie.Value)
+		isActive, sess, e := u.sessionService.IsSessionActive(r.Context(), sessionID)
 		if e != nil {
 			log.Printf("Error on get session: %s\n", e.Error())
 			http.Redirect(w, r, "/", http.StatusTemporaryRedirect)
@@ -52,15 +59,10 @@ func (u *userHandler) UserCtx(next http.Handler) http.Handler {
 			http.Redirect(w, r, "/", http.StatusTemporaryRedirect)
 			return
 		}
-		emailCookie, e := r.Cookie(helper.EMAIL_COOKIE_KEY)
-		if e != nil || emailCookie == nil {
-			log.Printf("Error on get email on cookies: %s\n", e.Error())
-			http.Redirect(w, r, "/", http.StatusTemporaryRedirect)
-			return
-		}
-		found, user, e := u.userService.GetByEmail(emailCookie.Value)
+		// the user comes from the session only, nothing the client sends can change whose it is
+		user, e := u.userService.GetById(r.Context(), sess.UserID)
 		if e != nil {
-			if errors.Cause(e) == helper.ErrUserNotFound || !found {
+			if errors.Cause(e) == helper.ErrUserNotFound {
 				log.Printf("User not found\n")
 				http.Redirect(w, r, "/", http.StatusTemporaryRedirect)
 				return
@@ -68,7 +70,7 @@ func (u *userHandler) UserCtx(next http.Handler) http.Handler {
 			http.Error(w, e.Error(), http.StatusBadRequest)
 			return
 		}
-		ctx := context.WithValue(r.Context(), "user", &user)
+		ctx := withUser(withSession(r.Context(), sess), &user)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
@@ -77,7 +79,7 @@ func (u *userHandler) Get(w http.ResponseWriter, r *http.Request) {
 	result := helper.NewResult(nil)
 	contentType := r.Header.Get("Content-Type")
 	ctx := r.Context()
-	data, ok := ctx.Value("user").(*m.User)
+	data, ok := userFrom(ctx)
 	if !ok {
 		ResponseWithResult(w, contentType, result.SetErrMsg(helper.ErrUserNotFound.Error()), http.StatusBadRequest)
 		return
@@ -90,7 +92,7 @@ func (u *userHandler) Update(w http.ResponseWriter, r *http.Request) {
 	result := helper.NewResult(nil)
 	contentType := r.Header.Get("Content-Type")
 	ctx := r.Context()
-	existingData, ok := ctx.Value("user").(*m.User)
+	existingData, ok := userFrom(ctx)
 	if !ok {
 		ResponseWithResult(w, contentType, result.SetErrMsg(helper.ErrUserNotFound.Error()), http.StatusBadRequest)
 		return
@@ -101,18 +103,175 @@ func (u *userHandler) Update(w http.ResponseWriter, r *http.Request) {
 		ResponseWithResult(w, contentType, result.SetError(e), http.StatusBadRequest)
 		return
 	}
-	data, e := GetSerializer(contentType).DecodeMap(requestBody)
+	data, e := GetSerializer(contentType).DecodeUserUpdate(requestBody)
 	if e != nil {
 		ResponseWithResult(w, contentType, result.SetError(e), http.StatusBadRequest)
 		return
 	}
-	user, e := u.userService.Update(data, id)
-	if e != nil && errors.Cause(e) != helper.ErrUserNotFound {
+	user, e := u.userService.Update(r.Context(), data, id)
+	if e != nil {
+		switch errors.Cause(e) {
+		case helper.ErrUserNotFound:
+			// nothing changed
+			user = *existingData
+		case helper.ErrEmailDuplicate, helper.ErrUserInvalid:
+			ResponseWithResult(w, contentType, result.SetError(e), http.StatusBadRequest)
+			return
+		default:
+			if policyResult(result, e) {
+				ResponseWithResult(w, contentType, result, http.StatusBadRequest)
+				return
+			}
+			ResponseWithResult(w, contentType, result.SetError(e), http.StatusInternalServerError)
+			return
+		}
+	}
+	if e == nil && (data.Password != nil || data.Email != nil) {
+		// the credentials changed, the session moves to a new ID
+		sess, _ := sessionFrom(ctx)
+		tSession, e := u.sessionService.Rotate(ctx, sess, user)
+		if e != nil {
+			ResponseWithResult(w, contentType, result.SetError(e), http.StatusInternalServerError)
+			return
+		}
+		setSessionCookies(w, r, tSession)
+	}
+	user.Password = ""
+	ResponseWithResult(w, contentType, result.SetData(user), http.StatusOK)
+
+}
+
+func (u *userHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	result := helper.NewResult(nil)
+	contentType := r.Header.Get("Content-Type")
+	ctx := r.Context()
+	data, ok := userFrom(ctx)
+	if !ok {
+		ResponseWithResult(w, contentType, result.SetErrMsg(helper.ErrUserNotFound.Error()), http.StatusBadRequest)
+		return
+	}
+	if e := u.accountService.Delete(r.Context(), data.ID); e != nil {
 		ResponseWithResult(w, contentType, result.SetError(e), http.StatusInternalServerError)
 		return
 	}
-	helper.SetCookie(w, r, helper.EMAIL_COOKIE_KEY, user.Email, time.Duration(time.Minute*30))
-	user.Password = ""
-	ResponseWithResult(w, contentType, result.SetData(data), http.StatusOK)
+	helper.SetCookie(w, r, helper.SESSION_COOKIE_KEY, "", time.Minute*-30)
+
+	ResponseWithResult(w, contentType, result.SetMessage(helper.SuccessDelete), http.StatusOK)
+}
 
+func (u *userHandler) Restore(w http.ResponseWriter, r *http.Request) {
+	result := helper.NewResult(nil)
+	contentType := r.Header.Get("Content-Type")
+	requestBody, e := ioutil.ReadAll(r.Body)
+	if e != nil {
+		ResponseWithResult(w, contentType, result.SetError(e), http.StatusBadRequest)
+		return
+	}
+	user, e := GetSerializer(contentType).Decode(requestBody)
+	if e != nil {
+		ResponseWithResult(w, contentType, result.SetError(e), http.StatusBadRequest)
+		return
+	}
+	// restoring checks the password, so it is locked out the same way a login is
+	ip := clientIP(r)
+	if lockedUntil, e := u.lockoutService.Check(r.Context(), user.Email, ip); e != nil {
+		if errors.Cause(e) == helper.ErrTooManyAttempts {
+			retryAfter(w, lockedUntil)
+			ResponseWithResult(w, contentType, result.SetError(e), http.StatusTooManyRequests)
+		} else {
+			ResponseWithResult(w, contentType, result.SetError(e), http.StatusInternalServerError)
+		}
+		return
+	}
+	if e := u.accountService.Restore(r.Context(), user.Email, user.Password); e != nil {
+		switch errors.Cause(e) {
+		case helper.ErrUserNotFound, helper.ErrPasswordDoesNotMatch:
+			// an unknown email and a wrong password look the same so that accounts can't be enumerated
+			if _, e := u.lockoutService.Failure(r.Context(), user.Email, ip); e != nil {
+				log.Println("Error on counting failed restore:", e.Error())
+			}
+			ResponseWithResult(w, contentType, result.SetErrMsg(helper.ErrAuthFailedMsg), http.StatusUnauthorized)
+		case helper.ErrRestoreExpired:
+			ResponseWithResult(w, contentType, result.SetError(e), http.StatusGone)
+		default:
+			ResponseWithResult(w, contentType, result.SetError(e), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if e := u.lockoutService.Success(r.Context(), user.Email); e != nil {
+		log.Println("Error on resetting failed logins:", e.Error())
+	}
+
+	ResponseWithResult(w, contentType, result.SetMessage(helper.SuccessRestore), http.StatusOK)
+}
+
+// AdminCtx must run after UserCtx, it only lets admins through.
+func (u *userHandler) AdminCtx(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, ok := userFrom(r.Context())
+		if !ok || !helper.IsAdmin(data.ID) {
+			ResponseWithResult(w, r.Header.Get("Content-Type"), helper.NewResult(nil).SetErrMsg(helper.ErrNotAdmin), http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (u *userHandler) List(w http.ResponseWriter, r *http.Request) {
+	result := helper.NewResult(nil)
+	contentType := r.Header.Get("Content-Type")
+	query, e := getListQuery(r)
+	if e != nil {
+		ResponseWithResult(w, contentType, result.SetError(e), http.StatusBadRequest)
+		return
+	}
+	page, e := u.userService.List(r.Context(), query)
+	if e != nil {
+		switch errors.Cause(e) {
+		case helper.ErrInvalidCursor, helper.ErrInvalidSort:
+			ResponseWithResult(w, contentType, result.SetError(e), http.StatusBadRequest)
+		default:
+			ResponseWithResult(w, contentType, result.SetError(e), http.StatusInternalServerError)
+		}
+		return
+	}
+	for i := range page.Users {
+		page.Users[i].Password = ""
+	}
+	ResponseWithResult(w, contentType, result.SetData(page).SetTotal(page.Total), http.StatusOK)
+}
+
+// getListQuery reads GET /users?active=&googleauth=&domain=&name=&sort=&order=&cursor=&limit=
+func getListQuery(r *http.Request) (m.UserListQuery, error) {
+	params := r.URL.Query()
+	query := m.UserListQuery{
+		EmailDomain: params.Get("domain"),
+		NamePrefix:  params.Get("name"),
+		SortBy:      params.Get("sort"),
+		Desc:        params.Get("order") == "desc",
+		Cursor:      params.Get("cursor"),
+	}
+	if v := params.Get("active"); v != "" {
+		isActive, e := strconv.ParseBool(v)
+		if e != nil {
+			return query, errors.Wrap(e, "active")
+		}
+		query.IsActive = &isActive
+	}
+	if v := params.Get("googleauth"); v != "" {
+		isGoogleAuth, e := strconv.ParseBool(v)
+		if e != nil {
+			return query, errors.Wrap(e, "googleauth")
+		}
+		query.IsGoogleAuth = &isGoogleAuth
+	}
+	if v := params.Get("limit"); v != "" {
+		limit, e := strconv.Atoi(v)
+		if e != nil {
+			return query, errors.Wrap(e, "limit")
+		}
+		query.Limit = limit
+	}
+	return query, nil
 }
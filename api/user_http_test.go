@@ -2,10 +2,13 @@ package api_test
 
 import (
 	"bytes"
-	"fmt"
+	"context"
 	"net/http"
+	"net/http/httptest"
+	"os"
 	"reflect"
 	"testing"
+	"time"
 
 	. "github.com/rinosukmandityo/user-profile/api"
 	"github.com/rinosukmandityo/user-profile/api/caller"
@@ -64,12 +67,12 @@ func TestUpdateUserSuccess(t *testing.T) {
 		tt := _tt
 		t.Run(tt.name, func(t *testing.T) {
 			_data := tt.expectedData
-			dataBytes, e := getBytes(_data)
+			dataBytes, e := getUpdateBytes(_data)
 			if e != nil {
 				t.Errorf("cannot get data byte: %s", e.Error())
 			}
 
-			tSession, e := sessionSvc.CreateNewSession(_data)
+			tSession, e := sessionSvc.CreateNewSession(context.Background(), _data, m.Client{})
 			if e != nil {
 				t.Errorf("cannot create a new session: %s", e.Error())
 			}
@@ -77,7 +80,7 @@ func TestUpdateUserSuccess(t *testing.T) {
 			if tt.validSessionCookie {
 				sessionID = tSession.ID
 			}
-			cookieSession := fmt.Sprintf("%s=%s; %s=%s", helper.SESSION_COOKIE_KEY, sessionID, helper.EMAIL_COOKIE_KEY, _data.Email)
+			cookieSession := sessionCookie(sessionID)
 
 			req, e := http.NewRequest("PUT", "/user", bytes.NewReader(dataBytes))
 			if e != nil {
@@ -101,7 +104,7 @@ func TestUpdateUserSuccess(t *testing.T) {
 			if reflect.DeepEqual(tt.expectedData, actualData) {
 				t.Errorf("data is not correct, \nwant: \n%+v, \ngot: \n%+v", tt.expectedData, actualData)
 			}
-			if e := sessionRepo.DeleteAll(); e != nil {
+			if e := sessionRepo.DeleteAll(context.Background()); e != nil {
 				t.Errorf("unable to delete session data")
 			}
 		})
@@ -162,12 +165,12 @@ func TestUpdateUserFailed(t *testing.T) {
 		tt := _tt
 		t.Run(tt.name, func(t *testing.T) {
 			_data := tt.expectedData
-			dataBytes, e := getBytes(_data)
+			dataBytes, e := getUpdateBytes(_data)
 			if e != nil {
 				t.Errorf("cannot get data byte: %s", e.Error())
 			}
 
-			tSession, e := sessionSvc.CreateNewSession(_data)
+			tSession, e := sessionSvc.CreateNewSession(context.Background(), _data, m.Client{})
 			if e != nil {
 				t.Errorf("cannot create a new session: %s", e.Error())
 			}
@@ -175,7 +178,7 @@ func TestUpdateUserFailed(t *testing.T) {
 			if tt.validSessionCookie {
 				sessionID = tSession.ID
 			}
-			cookieSession := fmt.Sprintf("%s=%s; %s=%s", helper.SESSION_COOKIE_KEY, sessionID, helper.EMAIL_COOKIE_KEY, _data.Email)
+			cookieSession := sessionCookie(sessionID)
 
 			req, e := http.NewRequest("PUT", "/user", bytes.NewReader(dataBytes))
 			if e != nil {
@@ -195,7 +198,87 @@ func TestUpdateUserFailed(t *testing.T) {
 			if respBody != tt.expectedResp {
 				t.Errorf("response body should be nil")
 			}
-			if e := sessionRepo.DeleteAll(); e != nil {
+			if e := sessionRepo.DeleteAll(context.Background()); e != nil {
+				t.Errorf("unable to delete session data")
+			}
+		})
+	}
+}
+
+func TestUpdateUserNotAllowedField(t *testing.T) {
+	testData := []m.User{{
+		Name:     "User 01",
+		Password: "Password.1",
+		ID:       "userid01",
+		Email:    "usermail01@gmail.com",
+		Address:  "User Address 01",
+		IsActive: false,
+	}}
+
+	defer func() {
+		if e := cleanupUserData(); e != nil {
+			t.Fatal(e)
+		}
+	}()
+
+	if e := seedUserData(testData); e != nil {
+		t.Fatal(e)
+	}
+
+	testTable := []struct {
+		name string
+		body string
+	}{
+		{
+			name: "id",
+			body: `{"ID": "another ID"}`,
+		},
+		{
+			name: "is_active",
+			body: `{"Name": "User 01 UPDATED", "IsActive": true}`,
+		},
+		{
+			name: "sql_fragment",
+			body: `{"Name=Name, IsGoogleAuth": true}`,
+		},
+		{
+			name: "wrong_type",
+			body: `{"Name": 1}`,
+		},
+	}
+
+	for _, _tt := range testTable {
+		tt := _tt
+		t.Run(tt.name, func(t *testing.T) {
+			tSession, e := sessionSvc.CreateNewSession(context.Background(), testData[0], m.Client{})
+			if e != nil {
+				t.Errorf("cannot create a new session: %s", e.Error())
+			}
+			cookieSession := sessionCookie(tSession.ID)
+
+			req, e := http.NewRequest("PUT", "/user", bytes.NewReader([]byte(tt.body)))
+			if e != nil {
+				t.Errorf("failed to create a mew request: %s ", e.Error())
+			}
+
+			resp, _, e := caller.New(r).SetRequest(req).SetResponse(&helper.ResultInfo{}).
+				SetHeader("Cookie", cookieSession).
+				SetHeader("Content-Type", ContentTypeJson).Exec()
+			if e != nil {
+				t.Errorf("failed to call an API: %s ", e.Error())
+			}
+			if resp.Code != http.StatusBadRequest {
+				t.Errorf("status response is not correct, want %d, got %d", http.StatusBadRequest, resp.Code)
+			}
+
+			actualData, e := userService.GetById(context.Background(), testData[0].ID)
+			if e != nil {
+				t.Errorf("unable to get data: %s", e.Error())
+			}
+			if actualData.Name != testData[0].Name || actualData.IsActive != testData[0].IsActive {
+				t.Errorf("user should not be updated, got %+v", actualData)
+			}
+			if e := sessionRepo.DeleteAll(context.Background()); e != nil {
 				t.Errorf("unable to delete session data")
 			}
 		})
@@ -227,11 +310,11 @@ func TestGetDataSuccess(t *testing.T) {
 	if e != nil {
 		t.Errorf("cannot get data byte: %s", e.Error())
 	}
-	tSession, e := sessionSvc.CreateNewSession(expectedData)
+	tSession, e := sessionSvc.CreateNewSession(context.Background(), expectedData, m.Client{})
 	if e != nil {
 		t.Errorf("cannot create a new session: %s", e.Error())
 	}
-	cookieSession := fmt.Sprintf("%s=%s; %s=%s", helper.SESSION_COOKIE_KEY, tSession.ID, helper.EMAIL_COOKIE_KEY, expectedData.Email)
+	cookieSession := sessionCookie(tSession.ID)
 
 	req, e := http.NewRequest("GET", "/user", bytes.NewReader(dataBytes))
 	if e != nil {
@@ -255,7 +338,7 @@ func TestGetDataSuccess(t *testing.T) {
 	if !reflect.DeepEqual(expectedData, actualData) {
 		t.Errorf("data is not correct, \nwant: \n%+v, \ngot: \n%+v", expectedData, actualData)
 	}
-	if e := sessionRepo.DeleteAll(); e != nil {
+	if e := sessionRepo.DeleteAll(context.Background()); e != nil {
 		t.Errorf("unable to delete session data")
 	}
 }
@@ -280,8 +363,9 @@ func TestGetDataByIdFailed(t *testing.T) {
 		t.Fatal(err)
 	}
 
+	// a session of a user who no longer exists
 	dataNotFound := testData[0]
-	dataNotFound.Email = "not found"
+	dataNotFound.ID = "userid02"
 
 	testTable := []struct {
 		name               string
@@ -291,7 +375,7 @@ func TestGetDataByIdFailed(t *testing.T) {
 		expectedResp       interface{}
 	}{
 		{
-			name:               "email_not_found_redirected",
+			name:               "user_not_found_redirected",
 			validSessionCookie: true,
 			expectedStatusCode: http.StatusTemporaryRedirect,
 			expectedData:       dataNotFound,
@@ -310,7 +394,7 @@ func TestGetDataByIdFailed(t *testing.T) {
 		tt := _tt
 		t.Run(tt.name, func(t *testing.T) {
 			_data := tt.expectedData
-			tSession, e := sessionSvc.CreateNewSession(_data)
+			tSession, e := sessionSvc.CreateNewSession(context.Background(), _data, m.Client{})
 			if e != nil {
 				t.Errorf("cannot create a new session: %s", e.Error())
 			}
@@ -318,7 +402,7 @@ func TestGetDataByIdFailed(t *testing.T) {
 			if tt.validSessionCookie {
 				sessionID = tSession.ID
 			}
-			cookieSession := fmt.Sprintf("%s=%s; %s=%s", helper.SESSION_COOKIE_KEY, sessionID, helper.EMAIL_COOKIE_KEY, _data.Email)
+			cookieSession := sessionCookie(sessionID)
 
 			req, e := http.NewRequest("GET", "/user", nil)
 			if e != nil {
@@ -338,9 +422,397 @@ func TestGetDataByIdFailed(t *testing.T) {
 			if respBody != nil {
 				t.Errorf("response body should be nil")
 			}
-			if e := sessionRepo.DeleteAll(); e != nil {
+			if e := sessionRepo.DeleteAll(context.Background()); e != nil {
+				t.Errorf("unable to delete session data")
+			}
+		})
+	}
+}
+
+func TestDeleteAndRestoreUser(t *testing.T) {
+	testData := m.User{
+		Name:     "User 01",
+		Password: "Password.1",
+		ID:       "userid01",
+		Email:    "usermail01@gmail.com",
+		Address:  "User Address 01",
+	}
+
+	defer func() {
+		if e := cleanupUserData(); e != nil {
+			t.Fatal(e)
+		}
+	}()
+
+	user := testData
+	if e := userService.Store(context.Background(), &user); e != nil {
+		t.Fatal(e)
+	}
+	tSession, e := sessionSvc.CreateNewSession(context.Background(), user, m.Client{})
+	if e != nil {
+		t.Fatalf("cannot create a new session: %s", e.Error())
+	}
+	cookieSession := sessionCookie(tSession.ID)
+
+	req, e := http.NewRequest("DELETE", "/user", nil)
+	if e != nil {
+		t.Errorf("failed to create a mew request: %s ", e.Error())
+	}
+	resp, _, e := caller.New(r).SetRequest(req).SetResponse(&helper.ResultInfo{}).
+		SetHeader("Cookie", cookieSession).
+		SetHeader("Content-Type", ContentTypeJson).Exec()
+	if e != nil {
+		t.Errorf("failed to call an API: %s ", e.Error())
+	}
+	if resp.Code != http.StatusOK {
+		t.Errorf("status response is not correct, want %d, got %d", http.StatusOK, resp.Code)
+	}
+	if _, e := userService.GetById(context.Background(), user.ID); e == nil {
+		t.Errorf("deleted user should not be found")
+	}
+
+	// the session was revoked, so the same cookies are redirected
+	req, e = http.NewRequest("GET", "/user", nil)
+	if e != nil {
+		t.Errorf("failed to create a mew request: %s ", e.Error())
+	}
+	resp, _, e = caller.New(r).SetRequest(req).SetResponse(nil).
+		SetHeader("Cookie", cookieSession).
+		SetHeader("Content-Type", ContentTypeJson).Exec()
+	if e != nil {
+		t.Errorf("failed to call an API: %s ", e.Error())
+	}
+	if resp.Code != http.StatusTemporaryRedirect {
+		t.Errorf("status response is not correct, want %d, got %d", http.StatusTemporaryRedirect, resp.Code)
+	}
+
+	dataBytes, e := getBytes(m.User{Email: testData.Email, Password: testData.Password})
+	if e != nil {
+		t.Errorf("cannot get data byte: %s", e.Error())
+	}
+	req, e = http.NewRequest("POST", "/restore", bytes.NewReader(dataBytes))
+	if e != nil {
+		t.Errorf("failed to create a mew request: %s ", e.Error())
+	}
+	resp, _, e = caller.New(r).SetRequest(req).SetResponse(&helper.ResultInfo{}).
+		SetHeader("Content-Type", ContentTypeJson).Exec()
+	if e != nil {
+		t.Errorf("failed to call an API: %s ", e.Error())
+	}
+	if resp.Code != http.StatusOK {
+		t.Errorf("status response is not correct, want %d, got %d", http.StatusOK, resp.Code)
+	}
+	if _, e := userService.GetById(context.Background(), user.ID); e != nil {
+		t.Errorf("restored user should be found: %s", e.Error())
+	}
+}
+
+func TestRestoreLockout(t *testing.T) {
+	testData := m.User{
+		Name:     "User 02",
+		Password: "Password.1",
+		ID:       "userid02",
+		Email:    "usermail02@gmail.com",
+	}
+
+	defer func() {
+		if e := cleanupUserData(); e != nil {
+			t.Fatal(e)
+		}
+		if e := cleanupAttemptData(); e != nil {
+			t.Fatal(e)
+		}
+	}()
+
+	user := testData
+	if e := userService.Store(context.Background(), &user); e != nil {
+		t.Fatal(e)
+	}
+	now := time.Now().UTC()
+	if _, e := userRepo.Update(context.Background(), map[string]interface{}{"IsDeleted": true, "Deleted": &now}, user.ID); e != nil {
+		t.Fatal(e)
+	}
+
+	wrongPassword := testData
+	wrongPassword.Password = "Wrong.Password.1"
+	unknownEmail := testData
+	unknownEmail.Email = "unknown02@gmail.com"
+
+	testTable := []struct {
+		name               string
+		data               m.User
+		expectedStatusCode int
+	}{
+		{name: "unknown_email", data: unknownEmail, expectedStatusCode: http.StatusUnauthorized},
+		{name: "first_failure", data: wrongPassword, expectedStatusCode: http.StatusUnauthorized},
+		{name: "second_failure", data: wrongPassword, expectedStatusCode: http.StatusUnauthorized},
+		{name: "third_failure_backs_off", data: wrongPassword, expectedStatusCode: http.StatusUnauthorized},
+		{name: "correct_password_during_backoff", data: testData, expectedStatusCode: http.StatusTooManyRequests},
+	}
+
+	messages := map[string]bool{}
+	for _, tt := range testTable {
+		dataBytes, e := getBytes(tt.data)
+		if e != nil {
+			t.Fatalf("cannot get data byte: %s", e.Error())
+		}
+		req, e := http.NewRequest("POST", "/restore", bytes.NewReader(dataBytes))
+		if e != nil {
+			t.Fatalf("failed to create a mew request: %s ", e.Error())
+		}
+		req.RemoteAddr = "10.0.0.2:1234"
+
+		resp, respBody, e := caller.New(r).SetRequest(req).SetResponse(&helper.ResultInfo{}).
+			SetHeader("Content-Type", ContentTypeJson).Exec()
+		if e != nil {
+			t.Fatalf("failed to call an API: %s ", e.Error())
+		}
+		if resp.Code != tt.expectedStatusCode {
+			t.Errorf("%s: status response is not correct, want %d, got %d", tt.name, tt.expectedStatusCode, resp.Code)
+		}
+		if resp.Code == http.StatusUnauthorized {
+			messages[respBody.(*helper.ResultInfo).Message] = true
+		}
+	}
+	// an unknown email and a wrong password can't be told apart
+	if len(messages) != 1 {
+		t.Errorf("failed restores should have the same message, got %v", messages)
+	}
+}
+
+func TestListUsers(t *testing.T) {
+	testData := []m.User{{
+		Name:     "User 01",
+		Password: "Password.1",
+		ID:       "userid01",
+		Email:    "usermail01@gmail.com",
+	}, {
+		Name:     "User 02",
+		Password: "Password.1",
+		ID:       "userid02",
+		Email:    "usermail02@gmail.com",
+	}, {
+		Name:     "User 03",
+		Password: "Password.1",
+		ID:       "userid03",
+		Email:    "usermail03@gmail.com",
+	}}
+
+	defer func() {
+		os.Unsetenv("admins")
+		if e := cleanupUserData(); e != nil {
+			t.Fatal(e)
+		}
+		if e := cleanupTwoFactorData(); e != nil {
+			t.Fatal(e)
+		}
+	}()
+
+	if e := seedUserData(testData); e != nil {
+		t.Fatal(e)
+	}
+	// admins are listed by ID, listing the email of the third user doesn't make it an admin
+	os.Setenv("admins", testData[0].ID+","+testData[1].ID+","+testData[2].Email)
+	// only the first admin has two-factor authentication
+	if e := twoFactorRepo.Save(context.Background(), &m.TwoFactor{ID: testData[0].ID, Secret: "JBSWY3DPEHPK3PXP", IsEnabled: true, Created: time.Now().UTC()}); e != nil {
+		t.Fatal(e)
+	}
+
+	testTable := []struct {
+		name               string
+		user               m.User
+		twoFactorSession   bool
+		expectedStatusCode int
+		expectedTotal      int
+	}{
+		{
+			name:               "admin",
+			user:               testData[0],
+			twoFactorSession:   true,
+			expectedStatusCode: http.StatusOK,
+			expectedTotal:      len(testData),
+		},
+		{
+			name:               "admin_password_only_session_forbidden",
+			user:               testData[0],
+			expectedStatusCode: http.StatusForbidden,
+		},
+		{
+			name:               "admin_without_two_factor_forbidden",
+			user:               testData[1],
+			expectedStatusCode: http.StatusForbidden,
+		},
+		{
+			name:               "not_admin_forbidden",
+			user:               testData[2],
+			expectedStatusCode: http.StatusForbidden,
+		},
+	}
+
+	for _, _tt := range testTable {
+		tt := _tt
+		t.Run(tt.name, func(t *testing.T) {
+			tSession, e := sessionSvc.CreateNewSession(context.Background(), tt.user, m.Client{})
+			if e != nil {
+				t.Errorf("cannot create a new session: %s", e.Error())
+			}
+			if tt.twoFactorSession {
+				if _, e := sessionRepo.Update(context.Background(), map[string]interface{}{"IsTwoFactor": true}, tSession.ID); e != nil {
+					t.Fatal(e)
+				}
+			}
+			cookieSession := sessionCookie(tSession.ID)
+
+			req, e := http.NewRequest("GET", "/users?sort=Name&limit=1", nil)
+			if e != nil {
+				t.Errorf("failed to create a mew request: %s ", e.Error())
+			}
+			resp, respBody, e := caller.New(r).SetRequest(req).SetResponse(&helper.ResultInfo{}).
+				SetHeader("Cookie", cookieSession).
+				SetHeader("Content-Type", ContentTypeJson).Exec()
+			if e != nil {
+				t.Errorf("failed to call an API: %s ", e.Error())
+			}
+			if resp.Code != tt.expectedStatusCode {
+				t.Errorf("status response is not correct, want %d, got %d", tt.expectedStatusCode, resp.Code)
+			}
+			result := respBody.(*helper.ResultInfo)
+			if result.Total != tt.expectedTotal {
+				t.Errorf("total is not correct, want %d, got %d", tt.expectedTotal, result.Total)
+			}
+			if tt.expectedStatusCode == http.StatusOK {
+				page := result.Data.(map[string]interface{})
+				if users := page["Users"].([]interface{}); len(users) != 1 {
+					t.Errorf("want 1 user on the page, got %d", len(users))
+				}
+				if page["NextCursor"] == "" {
+					t.Errorf("first page should have a next cursor")
+				}
+			}
+			if e := sessionRepo.DeleteAll(context.Background()); e != nil {
 				t.Errorf("unable to delete session data")
 			}
 		})
 	}
 }
+
+func TestGetUserFromSession(t *testing.T) {
+	testData := []m.User{{
+		Name:     "User 01",
+		Password: "Password.1",
+		ID:       "userid01",
+		Email:    "usermail01@gmail.com",
+	}, {
+		Name:     "User 02",
+		Password: "Password.2",
+		ID:       "userid02",
+		Email:    "usermail02@gmail.com",
+	}}
+
+	defer func() {
+		if e := cleanupUserData(); e != nil {
+			t.Fatal(e)
+		}
+		if e := cleanupSessionData(); e != nil {
+			t.Fatal(e)
+		}
+	}()
+
+	if e := seedUserData(testData); e != nil {
+		t.Fatal(e)
+	}
+	tSession, e := sessionSvc.CreateNewSession(context.Background(), testData[0], m.Client{})
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	// the old email cookie of another user must not change whose profile it is
+	req, e := http.NewRequest("GET", "/user", nil)
+	if e != nil {
+		t.Fatal(e)
+	}
+	resp, respBody, e := caller.New(r).SetRequest(req).SetResponse(&helper.ResultInfo{}).
+		SetHeader("Cookie", sessionCookie(tSession.ID)+"; profileEmail="+testData[1].Email).
+		SetHeader("Content-Type", ContentTypeJson).Exec()
+	if e != nil {
+		t.Fatalf("failed to call an API: %s ", e.Error())
+	}
+	if resp.Code != http.StatusFound {
+		t.Fatalf("status response is not correct, want %d, got %d", http.StatusFound, resp.Code)
+	}
+	actualData := m.FromMapToUser(respBody.(*helper.ResultInfo).Data.(map[string]interface{}))
+	if actualData.ID != testData[0].ID || actualData.Email != testData[0].Email {
+		t.Errorf("user is not correct, want %s, got %s", testData[0].ID, actualData.ID)
+	}
+}
+
+func TestUpdateRotatesSession(t *testing.T) {
+	testData := m.User{
+		Name:     "User 01",
+		Password: "Password.1",
+		ID:       "userid01",
+		Email:    "usermail01@gmail.com",
+	}
+
+	defer func() {
+		if e := cleanupUserData(); e != nil {
+			t.Fatal(e)
+		}
+		if e := cleanupSessionData(); e != nil {
+			t.Fatal(e)
+		}
+	}()
+
+	if e := seedUserData([]m.User{testData}); e != nil {
+		t.Fatal(e)
+	}
+	tSession, e := sessionSvc.CreateNewSession(context.Background(), testData, m.Client{})
+	if e != nil {
+		t.Fatal(e)
+	}
+	sessionID := tSession.ID
+	call := func(method, body, sessionID string) *httptest.ResponseRecorder {
+		req, e := http.NewRequest(method, "/user", bytes.NewReader([]byte(body)))
+		if e != nil {
+			t.Fatal(e)
+		}
+		resp, _, e := caller.New(r).SetRequest(req).SetResponse(nil).
+			SetHeader("Cookie", sessionCookie(sessionID)).
+			SetHeader("Content-Type", ContentTypeJson).Exec()
+		if e != nil {
+			t.Fatalf("failed to call an API: %s ", e.Error())
+		}
+		return resp
+	}
+
+	testTable := []struct {
+		name            string
+		body            string
+		expectedRotated bool
+	}{
+		{name: "name_kept", body: `{"Name":"User 02"}`, expectedRotated: false},
+		{name: "email_rotated", body: `{"Email":"usermail02@gmail.com"}`, expectedRotated: true},
+		{name: "password_rotated", body: `{"Password":"New.Password.2"}`, expectedRotated: true},
+	}
+
+	for _, tt := range testTable {
+		resp := call("PUT", tt.body, sessionID)
+		if resp.Code != http.StatusOK {
+			t.Fatalf("%s: status response is not correct, want %d, got %d", tt.name, http.StatusOK, resp.Code)
+		}
+		newID := getCookie(resp.Result().Cookies(), helper.SESSION_COOKIE_KEY)
+		if rotated := newID != "" && newID != sessionID; rotated != tt.expectedRotated {
+			t.Fatalf("%s: rotation is not correct, want %t, got %t", tt.name, tt.expectedRotated, rotated)
+		}
+		if !tt.expectedRotated {
+			continue
+		}
+		if resp := call("GET", "", sessionID); resp.Code != http.StatusTemporaryRedirect {
+			t.Errorf("%s: old session ID should stop working, want %d, got %d", tt.name, http.StatusTemporaryRedirect, resp.Code)
+		}
+		sessionID = newID
+		if resp := call("GET", "", sessionID); resp.Code != http.StatusFound {
+			t.Errorf("%s: new session ID should work, want %d, got %d", tt.name, http.StatusFound, resp.Code)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package api
+
+import (
+	"io/ioutil"
+	"log"
+	"net/http"
+	"path"
+	"time"
+
+	"github.com/rinosukmandityo/user-profile/helper"
+	m "github.com/rinosukmandityo/user-profile/models"
+	svc "github.com/rinosukmandityo/user-profile/services"
+
+	"github.com/pkg/errors"
+)
+
+// verificationValidity is how long an email verification link can be used.
+const verificationValidity = 24 * time.Hour
+
+// VerifyEmailHandler activates the accounts signed up with a password, they can't
+// log in until the link sent to their email has been opened.
+type VerifyEmailHandler interface {
+	VerifyEmail(w http.ResponseWriter, r *http.Request)
+	ResendVerification(w http.ResponseWriter, r *http.Request)
+}
+
+type verifyEmailHandler struct {
+	tokenSvc svc.TokenService
+}
+
+func NewVerifyEmailHandler(tokenSvc svc.TokenService) VerifyEmailHandler {
+	return &verifyEmailHandler{tokenSvc}
+}
+
+func sendVerificationMail(r *http.Request, user m.User, tokenID string) error {
+	hostURL := helper.AppURL("/verifyemail?e=")
+	userMap := map[string]string{"id": user.ID, "fullname": user.Name, "email": user.Email}
+	return helper.VerifyEmailMailContent(userMap, tokenID, hostURL)
+}
+
+func (u *verifyEmailHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	data := map[string]interface{}{}
+
+	if q.Get("e") == "" {
+		data["ErrorMessage"] = helper.ErrInvalidToken
+		registerPage(w, path.Join(baseViewURL, "verify-error.html"), data)
+		return
+	}
+	if q.Get("d") == "" {
+		data["ErrorMessage"] = helper.ErrInvalidUserID
+		registerPage(w, path.Join(baseViewURL, "verify-error.html"), data)
+		return
+	}
+
+	if e := u.tokenSvc.VerifyEmail(r.Context(), q.Get("d"), q.Get("e")); e != nil {
+		log.Println("Error on verifying email:", e.Error())
+		data["ErrorMessage"] = errors.Cause(e).Error()
+		registerPage(w, path.Join(baseViewURL, "verify-error.html"), data)
+		return
+	}
+
+	registerPage(w, path.Join(baseViewURL, "verify-success.html"), data)
+}
+
+// ResendVerification answers the same way whether or not the email belongs to an
+// unverified account, so that accounts can't be enumerated.
+func (u *verifyEmailHandler) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	result := helper.NewResult(nil)
+	contentType := r.Header.Get("Content-Type")
+
+	requestBody, e := ioutil.ReadAll(r.Body)
+	if e != nil {
+		log.Println("Error on reading the request body:", e.Error())
+		ResponseWithResult(w, contentType, result.SetError(e), http.StatusBadRequest)
+		return
+	}
+	data, e := GetSerializer(contentType).DecodeMap(requestBody)
+	if e != nil {
+		log.Println("Error on decoding the request body:", e.Error())
+		ResponseWithResult(w, contentType, result.SetError(e), http.StatusBadRequest)
+		return
+	}
+	email, _ := data["Email"].(string)
+	user, tokenID, e := u.tokenSvc.VerificationByMail(r.Context(), email, verificationValidity)
+	if e != nil {
+		if cause := errors.Cause(e); cause != helper.ErrUserNotFound && cause != helper.ErrEmailVerified {
+			log.Println("Error on getting verification token:", e.Error())
+		}
+	} else if e = sendVerificationMail(r, user, tokenID); e != nil {
+		log.Println("Error on sending verification email:", e.Error())
+	}
+
+	ResponseWithResult(w, contentType, result.SetMessage(helper.VerificationSent), http.StatusOK)
+}
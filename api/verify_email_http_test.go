@@ -0,0 +1,156 @@
+package api_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"testing"
+
+	. "github.com/rinosukmandityo/user-profile/api"
+	"github.com/rinosukmandityo/user-profile/api/caller"
+	"github.com/rinosukmandityo/user-profile/helper"
+	m "github.com/rinosukmandityo/user-profile/models"
+)
+
+func TestVerifyEmail(t *testing.T) {
+	testData := m.User{
+		Name:     "User 01",
+		Password: "Password.1",
+		ID:       "userid01",
+		Email:    "usermail01@gmail.com",
+		IsActive: true,
+	}
+
+	defer func() {
+		if e := cleanupUserData(); e != nil {
+			t.Fatal(e)
+		}
+		if e := cleanupSessionData(); e != nil {
+			t.Fatal(e)
+		}
+		if e := cleanupTokenData(); e != nil {
+			t.Fatal(e)
+		}
+	}()
+
+	dataBytes, e := getBytes(testData)
+	if e != nil {
+		t.Fatal(e)
+	}
+	auth := func() int {
+		req, e := http.NewRequest("POST", "/auth", bytes.NewReader(dataBytes))
+		if e != nil {
+			t.Fatal(e)
+		}
+		resp, _, e := caller.New(r).SetRequest(req).SetResponse(&helper.ResultInfo{}).
+			SetHeader("Content-Type", ContentTypeJson).Exec()
+		if e != nil {
+			t.Fatalf("failed to call an API: %s ", e.Error())
+		}
+		return resp.Code
+	}
+
+	// IsActive of the request is ignored, the email has to be verified
+	req, e := http.NewRequest("POST", "/dosignup", bytes.NewReader(dataBytes))
+	if e != nil {
+		t.Fatal(e)
+	}
+	if resp, _, e := caller.New(r).SetRequest(req).SetResponse(&helper.ResultInfo{}).
+		SetHeader("Content-Type", ContentTypeJson).Exec(); e != nil || resp.Code != http.StatusOK {
+		t.Fatalf("failed to sign up: %v", e)
+	}
+	if code := auth(); code != http.StatusForbidden {
+		t.Errorf("unverified user can't log in, want %d, got %d", http.StatusForbidden, code)
+	}
+	token, e := tokenRepo.GetLatestToken(context.Background(), testData.ID, m.TokenVerifyEmail)
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	testTable := []struct {
+		name             string
+		query            string
+		expectedIsActive bool
+	}{
+		{name: "no_token", query: "?d=" + testData.ID, expectedIsActive: false},
+		{name: "wrong_token", query: "?e=wrongtoken&d=" + testData.ID, expectedIsActive: false},
+		{name: "wrong_user", query: "?e=" + token.ID + "&d=userid02", expectedIsActive: false},
+		{name: "valid_token", query: "?e=" + token.ID + "&d=" + testData.ID, expectedIsActive: true},
+	}
+
+	for _, tt := range testTable {
+		req, e := http.NewRequest("GET", "/verifyemail"+tt.query, nil)
+		if e != nil {
+			t.Fatal(e)
+		}
+		if _, _, e := caller.New(r).SetRequest(req).Exec(); e != nil {
+			t.Fatalf("failed to call an API: %s ", e.Error())
+		}
+		user, e := userService.GetById(context.Background(), testData.ID)
+		if e != nil {
+			t.Fatal(e)
+		}
+		if user.IsActive != tt.expectedIsActive {
+			t.Errorf("%s: IsActive is not correct, want %t, got %t", tt.name, tt.expectedIsActive, user.IsActive)
+		}
+	}
+
+	if code := auth(); code != http.StatusOK {
+		t.Errorf("verified user should log in, want %d, got %d", http.StatusOK, code)
+	}
+	if token, _ := tokenRepo.GetLatestToken(context.Background(), testData.ID, m.TokenVerifyEmail); !token.IsClaimed {
+		t.Error("verification token should be claimed")
+	}
+}
+
+func TestResendVerification(t *testing.T) {
+	testData := []m.User{{
+		Name:     "User 01",
+		Password: "Password.1",
+		ID:       "userid01",
+		Email:    "usermail01@gmail.com",
+		IsActive: true,
+	}}
+
+	defer func() {
+		if e := cleanupUserData(); e != nil {
+			t.Fatal(e)
+		}
+		if e := cleanupTokenData(); e != nil {
+			t.Fatal(e)
+		}
+	}()
+
+	if e := seedUserData(testData); e != nil {
+		t.Fatal(e)
+	}
+
+	testTable := []struct {
+		name  string
+		email string
+	}{
+		{name: "unknown_email", email: "usermail02@gmail.com"},
+		{name: "verified_email", email: testData[0].Email},
+	}
+
+	for _, tt := range testTable {
+		req, e := http.NewRequest("POST", "/resendverification", bytes.NewReader([]byte(`{"Email":"`+tt.email+`"}`)))
+		if e != nil {
+			t.Fatal(e)
+		}
+		resp, respBody, e := caller.New(r).SetRequest(req).SetResponse(&helper.ResultInfo{}).
+			SetHeader("Content-Type", ContentTypeJson).Exec()
+		if e != nil {
+			t.Fatalf("failed to call an API: %s ", e.Error())
+		}
+		if resp.Code != http.StatusOK {
+			t.Errorf("%s: status response is not correct, want %d, got %d", tt.name, http.StatusOK, resp.Code)
+		}
+		if msg := respBody.(*helper.ResultInfo).Message; msg != helper.VerificationSent {
+			t.Errorf("%s: message is not correct, want %s, got %s", tt.name, helper.VerificationSent, msg)
+		}
+	}
+	if _, e := tokenRepo.GetLatestToken(context.Background(), testData[0].ID, m.TokenVerifyEmail); e != helper.ErrTokenNotFound {
+		t.Errorf("verified user should not get a verification token, got %v", e)
+	}
+}
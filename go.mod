@@ -5,6 +5,10 @@ go 1.15
 require (
 	github.com/go-chi/chi v1.5.1
 	github.com/go-sql-driver/mysql v1.5.0
+	github.com/lib/pq v1.9.0
+	github.com/mattn/go-sqlite3 v1.14.6
 	github.com/pkg/errors v0.9.1
+	go.mongodb.org/mongo-driver v1.4.4
+	golang.org/x/crypto v0.0.0-20200622213623-75b288015ac9
 	golang.org/x/oauth2 v0.0.0-20201208152858-08078c50e5b5
 )
@@ -0,0 +1,30 @@
+package helper
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GracePeriod is how long a deleted account can still be restored before it
+// is purged, it is set in hours with graceperiod and defaults to 30 days.
+func GracePeriod() time.Duration {
+	hours, _ := strconv.Atoi(os.Getenv("graceperiod"))
+	if hours <= 0 {
+		hours = 720
+	}
+	return time.Duration(hours) * time.Hour
+}
+
+// IsAdmin reports whether userID is one of the comma separated admins, only
+// they can browse other accounts. Admins are listed by ID because users can
+// change their email.
+func IsAdmin(userID string) bool {
+	for _, admin := range strings.Split(os.Getenv("admins"), ",") {
+		if admin = strings.TrimSpace(admin); admin != "" && admin == userID {
+			return true
+		}
+	}
+	return false
+}
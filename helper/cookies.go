@@ -1,23 +1,142 @@
 package helper
 
 import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"log"
 	"net/http"
-	"net/url"
+	"strings"
+	"sync"
 	"time"
 )
 
 const (
 	SESSION_COOKIE_KEY = "profileSessionID"
-	EMAIL_COOKIE_KEY   = "profileEmail"
+	// PENDING_SESSION_COOKIE_KEY holds a login which still needs the second factor
+	PENDING_SESSION_COOKIE_KEY = "profilePendingSessionID"
+
+	// hostPrefix makes browsers accept a cookie only from a secure origin, for the
+	// whole host and not its subdomains
+	hostPrefix = "__Host-"
+)
+
+// CookieOptions hardens the cookies set by SetCookie. Values are signed with the
+// first of Keys and accepted when signed with any of them, so a new key is put first
+// and an old one is dropped once the cookies it signed have expired.
+type CookieOptions struct {
+	Keys       [][]byte
+	Secure     bool
+	SameSite   http.SameSite
+	HostPrefix bool
+}
+
+var (
+	cookieMu      sync.RWMutex
+	cookieOptions = CookieOptions{Keys: [][]byte{randomKey()}, SameSite: http.SameSiteLaxMode}
 )
 
+func randomKey() []byte {
+	key := make([]byte, 32)
+	if _, e := rand.Read(key); e != nil {
+		panic(e)
+	}
+	return key
+}
+
+// SetCookieOptions replaces the cookie options, a __Host- prefix and SameSite=None
+// both need Secure so it is turned on for them. Without keys a random key is made,
+// cookies signed with it don't survive a restart.
+func SetCookieOptions(opts CookieOptions) {
+	if opts.HostPrefix || opts.SameSite == http.SameSiteNoneMode {
+		opts.Secure = true
+	}
+	if len(opts.Keys) == 0 {
+		log.Println("No cookie keys are set, sessions end when the server restarts")
+		opts.Keys = [][]byte{randomKey()}
+	}
+	cookieMu.Lock()
+	cookieOptions = opts
+	cookieMu.Unlock()
+}
+
+func getCookieOptions() CookieOptions {
+	cookieMu.RLock()
+	defer cookieMu.RUnlock()
+	return cookieOptions
+}
+
+// CookieName is the name cookie name is sent under.
+func CookieName(name string) string {
+	if getCookieOptions().HostPrefix {
+		return hostPrefix + name
+	}
+	return name
+}
+
+func cookieMAC(key []byte, name, value string) []byte {
+	mac := hmac.New(sha256.New, key)
+	// the name is signed too so the value of one cookie can't be sent as another
+	mac.Write([]byte(name + "=" + value))
+	return mac.Sum(nil)
+}
+
+// SignCookie appends the signature of value as cookie name.
+func SignCookie(name, value string) string {
+	key := getCookieOptions().Keys[0]
+	return value + "." + base64.RawURLEncoding.EncodeToString(cookieMAC(key, name, value))
+}
+
+// VerifyCookie returns the value of a signed cookie name, ErrInvalidCookie when
+// it isn't signed with one of the keys.
+func VerifyCookie(name, signed string) (string, error) {
+	i := strings.LastIndex(signed, ".")
+	if i < 0 {
+		return "", ErrInvalidCookie
+	}
+	value := signed[:i]
+	sig, e := base64.RawURLEncoding.DecodeString(signed[i+1:])
+	if e != nil {
+		return "", ErrInvalidCookie
+	}
+	for _, key := range getCookieOptions().Keys {
+		if hmac.Equal(sig, cookieMAC(key, name, value)) {
+			return value, nil
+		}
+	}
+	return "", ErrInvalidCookie
+}
+
+// GetCookie returns the verified value of cookie name, http.ErrNoCookie when the
+// request doesn't have it and ErrInvalidCookie when it has been tampered with.
+func GetCookie(r *http.Request, name string) (string, error) {
+	c, e := r.Cookie(CookieName(name))
+	if e != nil {
+		return "", e
+	}
+	return VerifyCookie(name, c.Value)
+}
+
+// SetCookie sets a signed cookie which expires after expiresAfter, a negative
+// duration deletes the cookie and zero keeps it until the browser is closed.
+// The cookie is sent back to this host only, never to its subdomains.
 func SetCookie(w http.ResponseWriter, r *http.Request, name, value string, expiresAfter time.Duration) *http.Cookie {
-	c := &http.Cookie{Name: name, Value: value, HttpOnly: true}
-	c.Path = "/"
-	u, e := url.Parse(r.URL.String())
-	if e == nil {
+	opts := getCookieOptions()
+	c := &http.Cookie{
+		Name:     CookieName(name),
+		Value:    SignCookie(name, value),
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   opts.Secure || r.TLS != nil,
+		SameSite: opts.SameSite,
+	}
+	if expiresAfter < 0 {
+		c.Value = ""
+		c.MaxAge = -1
+	}
+	if expiresAfter != 0 {
 		c.Expires = time.Now().Add(expiresAfter)
-		c.Domain = u.Host
 	}
 	http.SetCookie(w, c)
 
@@ -4,8 +4,8 @@ import (
 	"fmt"
 	"log"
 	"net/smtp"
-	"net/url"
 	"os"
+	"strings"
 )
 
 var (
@@ -73,6 +73,122 @@ func ResetPasswordMailContent(user map[string]string, tokenid, url string) error
 	return nil
 }
 
+func VerifyEmailMailContent(user map[string]string, tokenid, url string) error {
+	fullname := user["fullname"]
+	tokenid += "&d=" + user["id"]
+
+	mailContent := `
+	<html>
+		<body>
+			<table>
+				<tbody>
+					<tr>
+						<td style="padding-bottom:20px">
+							<h2 style="margin:0;color:#262626;font-weight:700;font-size:20px;line-height:1.2">Hi ` + fullname + `,</h2>
+						</td>
+					</tr>
+					<tr>
+						<td style="padding-bottom:20px"> 
+							<p style="margin:0;color:#4c4c4c;font-weight:400;font-size:16px;line-height:1.25">
+								Thanks for signing up, please verify your email before you log in.
+							</p>
+						</td>
+					</tr>
+					<tr>
+						<td style="padding-bottom:20px"> 
+							<p style="margin:0;color:#4c4c4c;font-weight:400;font-size:16px;line-height:1.25">
+								To verify your email, click <a href="` + url + tokenid + `" style="color:#008cc9;display:inline-block;text-decoration:none" target="_blank">here</a> or paste the following link into your browser:
+							</p>
+						</td>
+					</tr>
+					<tr> 
+						<td style="padding-bottom:20px">
+							<p style="margin:0;color:#4c4c4c;font-weight:400;font-size:16px;line-height:1.25"><a href="` + url + tokenid + `">` + url + tokenid + `</a></p>
+						</td> 
+					</tr>
+					<tr>
+						<td style="padding-bottom:20px">
+							<p style="margin:0;color:#4c4c4c;font-weight:400;font-size:16px;line-height:1.25">This link will expire in 24 hours, you can ask for a new one from the login page.</p>
+						</td>
+					</tr>
+					<tr> 
+						<td style="padding-bottom:20px"> 
+							<p style="margin:0;color:#4c4c4c;font-weight:400;font-size:16px;line-height:1.25">Thank you for using TalentPro!</p> 
+							<p style="margin:0;color:#4c4c4c;font-weight:400;font-size:16px;line-height:1.25">The TalentPro Team</p>
+						</td>
+					</tr>
+				</tbody>
+			</table>
+		</body>
+	</html>
+	`
+	mailSubject := fullname + ", please verify your email"
+	if e := SendMail([]string{user["email"]}, mailSubject, mailContent); e != nil {
+		return e
+	}
+
+	log.Println("Mail sent!")
+	return nil
+}
+
+func MagicLinkMailContent(user map[string]string, tokenid, url string) error {
+	fullname := user["fullname"]
+	tokenid += "&d=" + user["id"]
+
+	mailContent := `
+	<html>
+		<body>
+			<table>
+				<tbody>
+					<tr>
+						<td style="padding-bottom:20px">
+							<h2 style="margin:0;color:#262626;font-weight:700;font-size:20px;line-height:1.2">Hi ` + fullname + `,</h2>
+						</td>
+					</tr>
+					<tr>
+						<td style="padding-bottom:20px"> 
+							<p style="margin:0;color:#4c4c4c;font-weight:400;font-size:16px;line-height:1.25">
+								Here is the link to log in without your password.
+							</p>
+						</td>
+					</tr>
+					<tr>
+						<td style="padding-bottom:20px"> 
+							<p style="margin:0;color:#4c4c4c;font-weight:400;font-size:16px;line-height:1.25">
+								To log in, click <a href="` + url + tokenid + `" style="color:#008cc9;display:inline-block;text-decoration:none" target="_blank">here</a> or paste the following link into your browser:
+							</p>
+						</td>
+					</tr>
+					<tr> 
+						<td style="padding-bottom:20px">
+							<p style="margin:0;color:#4c4c4c;font-weight:400;font-size:16px;line-height:1.25"><a href="` + url + tokenid + `">` + url + tokenid + `</a></p>
+						</td> 
+					</tr>
+					<tr>
+						<td style="padding-bottom:20px">
+							<p style="margin:0;color:#4c4c4c;font-weight:400;font-size:16px;line-height:1.25">This link can be used once and will expire in 15 minutes. If you didn't ask for it, you can ignore this email.</p>
+						</td>
+					</tr>
+					<tr> 
+						<td style="padding-bottom:20px"> 
+							<p style="margin:0;color:#4c4c4c;font-weight:400;font-size:16px;line-height:1.25">Thank you for using TalentPro!</p> 
+							<p style="margin:0;color:#4c4c4c;font-weight:400;font-size:16px;line-height:1.25">The TalentPro Team</p>
+						</td>
+					</tr>
+				</tbody>
+			</table>
+		</body>
+	</html>
+	`
+	mailSubject := fullname + ", here's your login link"
+	if e := SendMail([]string{user["email"]}, mailSubject, mailContent); e != nil {
+		return e
+	}
+
+	log.Println("Mail sent!")
+	return nil
+}
+
 func SendMail(to []string, subject, message string) error {
 	content := "Subject: " + subject + "\n" +
 		"MIME-version: 1.0;\nContent-Type: text/html; charset=\"UTF-8\";\n\n" +
@@ -88,7 +204,13 @@ func SendMail(to []string, subject, message string) error {
 	return nil
 }
 
-func ConstructEmailURL(rawurl string, config map[string]string) string {
-	u, _ := url.Parse(rawurl)
-	return u.Scheme + "://" + u.Host + config["suffix"]
+// AppURL joins path to BASE_URL, the address users reach the application at. Links
+// which carry a token are built from it and never from request headers, the client
+// controls those and could have the token sent to its own host.
+func AppURL(path string) string {
+	base := os.Getenv("BASE_URL")
+	if base == "" {
+		base = "http://localhost:8000"
+	}
+	return strings.TrimRight(base, "/") + path
 }
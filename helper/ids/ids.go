@@ -0,0 +1,98 @@
+// Package ids generates the identifiers of the stored records. Identifiers which
+// are handed out as credentials are random, identifiers of records are ULIDs
+// which sort by the time they were created.
+package ids
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"sync"
+	"time"
+)
+
+const (
+	// secretBytes is the entropy of a secret, 256 bits
+	secretBytes = 32
+	// SecretLength is the length of a secret, the columns holding one must fit it
+	SecretLength = 43
+	// ULIDLength is the length of a ULID
+	ULIDLength = 26
+)
+
+// crockford is the base32 alphabet of ULIDs, it leaves out I, L, O and U.
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// NewSecret returns 256 random bits encoded as unpadded base64url, it is used for
+// identifiers which grant access on their own such as session and token IDs.
+func NewSecret() (string, error) {
+	b := make([]byte, secretBytes)
+	if _, e := rand.Read(b); e != nil {
+		return "", e
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+var (
+	mu       sync.Mutex
+	lastMs   uint64
+	lastRand [10]byte
+)
+
+// NewULID returns a ULID of the current time. ULIDs made within the same
+// millisecond increment the random part of the previous one so they still sort
+// in the order they were made.
+func NewULID() (string, error) {
+	return newULID(time.Now())
+}
+
+func newULID(now time.Time) (string, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	ms := uint64(now.UnixNano() / int64(time.Millisecond))
+	switch {
+	case ms > lastMs:
+		if _, e := rand.Read(lastRand[:]); e != nil {
+			return "", e
+		}
+	case increment(&lastRand):
+		// the random part ran out within one millisecond, borrow the next one
+		ms = lastMs + 1
+	default:
+		// within the same millisecond or the clock went back
+		ms = lastMs
+	}
+	lastMs = ms
+
+	var b [16]byte
+	binary.BigEndian.PutUint16(b[0:2], uint16(ms>>32))
+	binary.BigEndian.PutUint32(b[2:6], uint32(ms))
+	copy(b[6:], lastRand[:])
+	return encode(b), nil
+}
+
+// increment adds one to the big endian r and reports whether it overflowed.
+func increment(r *[10]byte) (overflow bool) {
+	for i := len(r) - 1; i >= 0; i-- {
+		r[i]++
+		if r[i] != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// encode writes the 128 bits of b as 26 Crockford base32 characters, the first
+// character only carries 3 bits.
+func encode(b [16]byte) string {
+	out := make([]byte, ULIDLength)
+	hi := binary.BigEndian.Uint64(b[0:8])
+	lo := binary.BigEndian.Uint64(b[8:16])
+	for i := ULIDLength - 1; i >= 0; i-- {
+		out[i] = crockford[lo&31]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out)
+}
@@ -0,0 +1,78 @@
+package ids
+
+import (
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestNewSecret(t *testing.T) {
+	seen := map[string]bool{}
+	for i := 0; i < 1000; i++ {
+		secret, e := NewSecret()
+		if e != nil {
+			t.Fatal(e)
+		}
+		if len(secret) != SecretLength {
+			t.Fatalf("length is not correct, want %d, got %d", SecretLength, len(secret))
+		}
+		if seen[secret] {
+			t.Fatalf("secret %s is repeated", secret)
+		}
+		seen[secret] = true
+	}
+}
+
+func TestEncode(t *testing.T) {
+	testTable := []struct {
+		name     string
+		data     [16]byte
+		expected string
+	}{
+		{name: "zero", expected: "00000000000000000000000000"},
+		{name: "max", data: [16]byte{255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255}, expected: "7ZZZZZZZZZZZZZZZZZZZZZZZZZ"},
+		{name: "one", data: [16]byte{15: 1}, expected: "00000000000000000000000001"},
+		{name: "spec_timestamp", data: [16]byte{0x01, 0x56, 0x3d, 0xf3, 0x64, 0x81}, expected: "01ARYZ6S410000000000000000"},
+	}
+
+	for _, tt := range testTable {
+		if actual := encode(tt.data); actual != tt.expected {
+			t.Errorf("%s: ULID is not correct, want %s, got %s", tt.name, tt.expected, actual)
+		}
+	}
+}
+
+func TestNewULID(t *testing.T) {
+	now := time.Now()
+	list := []string{}
+	// many ULIDs within one millisecond, then one of a clock which went back
+	for i := 0; i < 1000; i++ {
+		id, e := newULID(now)
+		if e != nil {
+			t.Fatal(e)
+		}
+		list = append(list, id)
+	}
+	id, e := newULID(now.Add(-time.Second))
+	if e != nil {
+		t.Fatal(e)
+	}
+	list = append(list, id)
+	later, e := newULID(now.Add(time.Millisecond))
+	if e != nil {
+		t.Fatal(e)
+	}
+	list = append(list, later)
+
+	if !sort.StringsAreSorted(list) {
+		t.Error("ULIDs should sort in the order they were made")
+	}
+	for i := 1; i < len(list); i++ {
+		if list[i] == list[i-1] {
+			t.Fatalf("ULID %s is repeated", list[i])
+		}
+	}
+	if len(later) != ULIDLength {
+		t.Errorf("length is not correct, want %d, got %d", ULIDLength, len(later))
+	}
+}
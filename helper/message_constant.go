@@ -12,17 +12,50 @@ var (
 	ErrSessionNotFound      = errors.New("Session not found")
 	ErrTokenNotFound        = errors.New("Token not found")
 	ErrTokenNotMatch        = errors.New("Token doesn't match")
+	ErrRestoreExpired       = errors.New("Account can no longer be restored")
+	ErrInvalidCursor        = errors.New("Invalid cursor")
+	ErrInvalidSort          = errors.New("Invalid sort field")
+	ErrPasswordBreached     = errors.New("Password has appeared in a data breach, choose another one")
+	ErrAttemptNotFound      = errors.New("Login attempt not found")
+	ErrTooManyAttempts      = errors.New("Too many failed login attempts, try again later")
+	ErrTooManyRequests      = errors.New("Too many requests, try again later")
+	ErrTwoFactorNotFound    = errors.New("Two-factor authentication is not enabled")
+	ErrTwoFactorEnabled     = errors.New("Two-factor authentication is already enabled")
+	ErrInvalidCode          = errors.New("Authentication code is not valid")
+	ErrEmailNotVerified     = errors.New("Email has not been verified, check your inbox for the verification link")
+	ErrEmailVerified        = errors.New("Email has already been verified")
+	ErrInvalidCookie        = errors.New("Cookie is not valid")
 )
 
 const (
 	ErrAuthEmailMsg    = "Email entered does not exists"
 	ErrAuthPasswordMsg = "Password is incorrect"
+	ErrAuthFailedMsg   = "Email or password is incorrect"
 	ErrDuplicateEmail  = "Email already registered"
 	ErrTokenExpired    = "Token has been expired"
 	ErrTokenClaimed    = "Token has been claimed"
 	ErrInvalidUserID   = "Invalid user ID"
 	ErrInvalidToken    = "Invalid token"
+	ErrNotAdmin        = "Only admins can access this resource"
+	ErrTwoFactorNeeded = "Two-factor authentication is required to access this resource"
 
-	SuccessSignup = "Sign Up Successfully"
-	SuccessLogin  = "Login Successfully"
+	ErrPasswordPolicyMsg = "Password does not meet the password policy"
+	ErrPasswordTooShort  = "Password must be at least %d characters"
+	ErrPasswordTooLong   = "Password must be at most %d bytes"
+	ErrPasswordNoLower   = "Password must contain a lowercase letter"
+	ErrPasswordNoUpper   = "Password must contain an uppercase letter"
+	ErrPasswordNoDigit   = "Password must contain a digit"
+	ErrPasswordNoSymbol  = "Password must contain a symbol"
+	ErrPasswordHasEmail  = "Password must not contain your email"
+	ErrPasswordHasName   = "Password must not contain your name"
+
+	SuccessSignup    = "Sign Up Successfully"
+	SuccessLogin     = "Login Successfully"
+	SuccessDelete    = "Account Deleted Successfully"
+	SuccessRestore   = "Account Restored Successfully"
+	TwoFactorCode    = "Enter the code from your authenticator app"
+	VerificationSent = "If the email needs to be verified, a verification link has been sent to it"
+	MagicLinkSent    = "If the email belongs to an account, a login link has been sent to it"
+	SuccessRevoke    = "Device Signed Out Successfully"
+	SuccessRevokeAll = "Signed Out Of All Devices Successfully"
 )
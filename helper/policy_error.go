@@ -0,0 +1,14 @@
+package helper
+
+import (
+	"strings"
+)
+
+// PasswordPolicyError lists every rule of the password policy a password breaks.
+type PasswordPolicyError struct {
+	Violations []string
+}
+
+func (e *PasswordPolicyError) Error() string {
+	return ErrPasswordPolicyMsg + ": " + strings.Join(e.Violations, ", ")
+}
@@ -1,17 +1,49 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 
 	h "github.com/rinosukmandityo/user-profile/api"
+	"github.com/rinosukmandityo/user-profile/helper"
+	rh "github.com/rinosukmandityo/user-profile/repositories/helper"
+	"github.com/rinosukmandityo/user-profile/services/logic"
 )
 
 func main() {
+	// go run main.go migrate up|down [steps]|version
+	if len(os.Args) > 2 && os.Args[1] == "migrate" {
+		steps := 0
+		if len(os.Args) > 3 {
+			steps, _ = strconv.Atoi(os.Args[3])
+		}
+		if e := rh.Migrate(os.Args[2], steps); e != nil {
+			log.Fatal(e)
+		}
+		return
+	}
+	// go run main.go purge
+	if len(os.Args) > 1 && os.Args[1] == "purge" {
+		userRepo, sessionRepo, tokenRepo, attemptRepo, twoFactorRepo, transactor := rh.ChooseRepo()
+		count, e := logic.NewAccountService(userRepo, sessionRepo, tokenRepo, twoFactorRepo, attemptRepo, transactor, helper.GracePeriod()).Purge(context.Background())
+		if e != nil {
+			log.Fatal(e)
+		}
+		fmt.Printf("%d deleted user(s) purged\n", count)
+		count, e = logic.NewLockoutService(attemptRepo, h.LockoutPolicy()).Purge(context.Background())
+		if e != nil {
+			log.Fatal(e)
+		}
+		fmt.Printf("%d expired login attempt(s) purged\n", count)
+		return
+	}
+
 	r := h.RegisterHandler()
 
 	errs := make(chan error, 2)
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+)
+
+// LoginAttempt counts the failed logins of one account or one IP address, its ID
+// is the tracked key, e.g. "email:usermail01@gmail.com" or "ip:127.0.0.1".
+type LoginAttempt struct {
+	ID          string    `json:"ID" bson:"ID" msgpack:"ID" db:"ID"`
+	Failures    int       `json:"Failures" bson:"Failures" msgpack:"Failures" db:"Failures"`
+	LastFailed  time.Time `json:"LastFailed" bson:"LastFailed" msgpack:"LastFailed" db:"LastFailed"`
+	LockedUntil time.Time `json:"LockedUntil" bson:"LockedUntil" msgpack:"LockedUntil" db:"LockedUntil"`
+}
+
+func (a *LoginAttempt) TableName() string {
+	return "login_attempts"
+}
+
+func (a *LoginAttempt) SplitByField() []interface{} {
+	return []interface{}{
+		a.ID,
+		a.Failures,
+		a.LastFailed,
+		a.LockedUntil,
+	}
+}
+
+func (a *LoginAttempt) GetMapFormat() map[string]interface{} {
+	return map[string]interface{}{
+		"ID":          a.ID,
+		"Failures":    a.Failures,
+		"LastFailed":  a.LastFailed,
+		"LockedUntil": a.LockedUntil,
+	}
+}
+
+func FromMapToLoginAttempt(data map[string]interface{}) LoginAttempt {
+	return LoginAttempt{
+		ID:          data["ID"].(string),
+		Failures:    data["Failures"].(int),
+		LastFailed:  data["LastFailed"].(time.Time),
+		LockedUntil: data["LockedUntil"].(time.Time),
+	}
+}
@@ -4,12 +4,31 @@ import (
 	"time"
 )
 
+// Session is a login, a pending session only proves the password and is upgraded
+// to a full session once the second factor is verified. Every login gets its own
+// session so that a user can be signed in on several devices at once. A remembered
+// session lasts longer and has no idle timeout. IsTwoFactor marks a session whose
+// login has passed the second factor.
 type Session struct {
-	ID      string    `json:"ID" bson:"ID" msgpack:"ID" db:"ID"`
-	UserID  string    `json:"UserID" bson:"UserID" msgpack:"UserID" db:"UserID"`
-	Email   string    `json:"Email" bson:"Email" msgpack:"Email" db:"Email"`
-	Created time.Time `json:"Created" bson:"Created" msgpack:"Created" db:"Created"`
-	Expired time.Time `json:"Expired" bson:"Expired" msgpack:"Expired" db:"Expired"`
+	ID           string    `json:"ID" bson:"ID" msgpack:"ID" db:"ID"`
+	UserID       string    `json:"UserID" bson:"UserID" msgpack:"UserID" db:"UserID"`
+	Email        string    `json:"Email" bson:"Email" msgpack:"Email" db:"Email"`
+	Created      time.Time `json:"Created" bson:"Created" msgpack:"Created" db:"Created"`
+	Expired      time.Time `json:"Expired" bson:"Expired" msgpack:"Expired" db:"Expired"`
+	IsPending    bool      `json:"IsPending" bson:"IsPending" msgpack:"IsPending" db:"IsPending"`
+	IP           string    `json:"IP" bson:"IP" msgpack:"IP" db:"IP"`
+	UserAgent    string    `json:"UserAgent" bson:"UserAgent" msgpack:"UserAgent" db:"UserAgent"`
+	LastSeen     time.Time `json:"LastSeen" bson:"LastSeen" msgpack:"LastSeen" db:"LastSeen"`
+	IsRemembered bool      `json:"IsRemembered" bson:"IsRemembered" msgpack:"IsRemembered" db:"IsRemembered"`
+	IsTwoFactor  bool      `json:"IsTwoFactor" bson:"IsTwoFactor" msgpack:"IsTwoFactor" db:"IsTwoFactor"`
+}
+
+// Client is where a login came from, it is kept on the session so that users can
+// tell their devices apart. Remember asks for a long lived session on the device.
+type Client struct {
+	IP        string
+	UserAgent string
+	Remember  bool
 }
 
 func (s *Session) TableName() string {
@@ -23,5 +42,43 @@ func (s *Session) SplitByField() []interface{} {
 		s.Email,
 		s.Created,
 		s.Expired,
+		s.IsPending,
+		s.IP,
+		s.UserAgent,
+		s.LastSeen,
+		s.IsRemembered,
+		s.IsTwoFactor,
+	}
+}
+
+func (s *Session) GetMapFormat() map[string]interface{} {
+	return map[string]interface{}{
+		"ID":           s.ID,
+		"UserID":       s.UserID,
+		"Email":        s.Email,
+		"Created":      s.Created,
+		"Expired":      s.Expired,
+		"IsPending":    s.IsPending,
+		"IP":           s.IP,
+		"UserAgent":    s.UserAgent,
+		"LastSeen":     s.LastSeen,
+		"IsRemembered": s.IsRemembered,
+		"IsTwoFactor":  s.IsTwoFactor,
+	}
+}
+
+func FromMapToSession(data map[string]interface{}) Session {
+	return Session{
+		ID:           data["ID"].(string),
+		UserID:       data["UserID"].(string),
+		Email:        data["Email"].(string),
+		Created:      data["Created"].(time.Time),
+		Expired:      data["Expired"].(time.Time),
+		IsPending:    data["IsPending"].(bool),
+		IP:           data["IP"].(string),
+		UserAgent:    data["UserAgent"].(string),
+		LastSeen:     data["LastSeen"].(time.Time),
+		IsRemembered: data["IsRemembered"].(bool),
+		IsTwoFactor:  data["IsTwoFactor"].(bool),
 	}
 }
@@ -4,12 +4,20 @@ import (
 	"time"
 )
 
+// Purposes of a token, a token only ever grants what it was created for.
+const (
+	TokenResetPassword = "reset"
+	TokenVerifyEmail   = "verify"
+	TokenMagicLink     = "login"
+)
+
 type Token struct {
 	ID        string    `json:"ID" bson:"ID" msgpack:"ID" db:"ID"`
 	UserID    string    `json:"UserID" bson:"UserID" msgpack:"UserID" db:"UserID"`
 	Created   time.Time `json:"Created" bson:"Created" msgpack:"Created" db:"Created"`
 	Expired   time.Time `json:"Expired" bson:"Expired" msgpack:"Expired" db:"Expired"`
 	IsClaimed bool      `json:"IsClaimed" bson:"IsClaimed" msgpack:"IsClaimed" db:"IsClaimed"`
+	Purpose   string    `json:"Purpose" bson:"Purpose" msgpack:"Purpose" db:"Purpose"`
 }
 
 func (t *Token) TableName() string {
@@ -23,5 +31,28 @@ func (s *Token) SplitByField() []interface{} {
 		s.Created,
 		s.Expired,
 		s.IsClaimed,
+		s.Purpose,
+	}
+}
+
+func (s *Token) GetMapFormat() map[string]interface{} {
+	return map[string]interface{}{
+		"ID":        s.ID,
+		"UserID":    s.UserID,
+		"Created":   s.Created,
+		"Expired":   s.Expired,
+		"IsClaimed": s.IsClaimed,
+		"Purpose":   s.Purpose,
+	}
+}
+
+func FromMapToToken(data map[string]interface{}) Token {
+	return Token{
+		ID:        data["ID"].(string),
+		UserID:    data["UserID"].(string),
+		Created:   data["Created"].(time.Time),
+		Expired:   data["Expired"].(time.Time),
+		IsClaimed: data["IsClaimed"].(bool),
+		Purpose:   data["Purpose"].(string),
 	}
 }
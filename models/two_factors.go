@@ -0,0 +1,62 @@
+package models
+
+import (
+	"time"
+)
+
+// TwoFactor holds the TOTP enrolment of a user, its ID is the user ID. The secret
+// is only trusted once IsEnabled is set by the first valid code. RecoveryCodes
+// holds the SHA-256 of every unused recovery code separated by commas and
+// LastStep the time step of the last accepted code, so that no code is accepted twice.
+type TwoFactor struct {
+	ID            string    `json:"ID" bson:"ID" msgpack:"ID" db:"ID"`
+	Secret        string    `json:"-" bson:"Secret" msgpack:"-" db:"Secret"`
+	IsEnabled     bool      `json:"IsEnabled" bson:"IsEnabled" msgpack:"IsEnabled" db:"IsEnabled"`
+	RecoveryCodes string    `json:"-" bson:"RecoveryCodes" msgpack:"-" db:"RecoveryCodes"`
+	LastStep      int       `json:"-" bson:"LastStep" msgpack:"-" db:"LastStep"`
+	Created       time.Time `json:"Created" bson:"Created" msgpack:"Created" db:"Created"`
+}
+
+func (t *TwoFactor) TableName() string {
+	return "two_factors"
+}
+
+func (t *TwoFactor) SplitByField() []interface{} {
+	return []interface{}{
+		t.ID,
+		t.Secret,
+		t.IsEnabled,
+		t.RecoveryCodes,
+		t.LastStep,
+		t.Created,
+	}
+}
+
+func (t *TwoFactor) GetMapFormat() map[string]interface{} {
+	return map[string]interface{}{
+		"ID":            t.ID,
+		"Secret":        t.Secret,
+		"IsEnabled":     t.IsEnabled,
+		"RecoveryCodes": t.RecoveryCodes,
+		"LastStep":      t.LastStep,
+		"Created":       t.Created,
+	}
+}
+
+func FromMapToTwoFactor(data map[string]interface{}) TwoFactor {
+	return TwoFactor{
+		ID:            data["ID"].(string),
+		Secret:        data["Secret"].(string),
+		IsEnabled:     data["IsEnabled"].(bool),
+		RecoveryCodes: data["RecoveryCodes"].(string),
+		LastStep:      data["LastStep"].(int),
+		Created:       data["Created"].(time.Time),
+	}
+}
+
+// TwoFactorEnrollment is what an authenticator app needs to add an account,
+// URI is the otpauth URI which is usually shown as a QR code.
+type TwoFactorEnrollment struct {
+	Secret string `json:"Secret" msgpack:"Secret"`
+	URI    string `json:"URI" msgpack:"URI"`
+}
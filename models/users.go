@@ -1,14 +1,22 @@
 package models
 
+import (
+	"time"
+)
+
+// User holds an account. IsDeleted and Deleted are its soft delete state, they
+// are never part of the API payloads.
 type User struct {
-	ID           string `json:"ID" bson:"_id" msgpack:"_id" db:"ID"`
-	Name         string `json:"Name" bson:"Name" msgpack:"Name" db:"Name"`
-	Email        string `json:"Email" bson:"Email" msgpack:"Email" db:"Email"`
-	Password     string `json:"Password" bson:"Password" msgpack:"Password" db:"Password"`
-	Telephone    string `json:"Telephone" bson:"Telephone" msgpack:"Telephone" db:"Telephone"`
-	Address      string `json:"Address" bson:"Address" msgpack:"Address" db:"Address"`
-	IsActive     bool   `json:"IsActive" bson:"IsActive" msgpack:"IsActive" db:"IsActive"`
-	IsGoogleAuth bool   `json:"IsGoogleAuth" bson:"IsGoogleAuth" msgpack:"IsGoogleAuth" db:"IsGoogleAuth"`
+	ID           string     `json:"ID" bson:"_id" msgpack:"_id" db:"ID"`
+	Name         string     `json:"Name" bson:"Name" msgpack:"Name" db:"Name"`
+	Email        string     `json:"Email" bson:"Email" msgpack:"Email" db:"Email"`
+	Password     string     `json:"Password" bson:"Password" msgpack:"Password" db:"Password"`
+	Telephone    string     `json:"Telephone" bson:"Telephone" msgpack:"Telephone" db:"Telephone"`
+	Address      string     `json:"Address" bson:"Address" msgpack:"Address" db:"Address"`
+	IsActive     bool       `json:"IsActive" bson:"IsActive" msgpack:"IsActive" db:"IsActive"`
+	IsGoogleAuth bool       `json:"IsGoogleAuth" bson:"IsGoogleAuth" msgpack:"IsGoogleAuth" db:"IsGoogleAuth"`
+	IsDeleted    bool       `json:"-" bson:"IsDeleted" msgpack:"-" db:"IsDeleted"`
+	Deleted      *time.Time `json:"-" bson:"Deleted" msgpack:"-" db:"Deleted"`
 }
 
 func (m *User) TableName() string {
@@ -25,6 +33,8 @@ func (m *User) GetMapFormat() map[string]interface{} {
 		"Address":      m.Address,
 		"IsActive":     m.IsActive,
 		"IsGoogleAuth": m.IsGoogleAuth,
+		"IsDeleted":    m.IsDeleted,
+		"Deleted":      m.Deleted,
 	}
 }
 
@@ -38,10 +48,15 @@ func (m *User) SplitByField() []map[string]interface{} {
 		{"Address": m.Address},
 		{"IsActive": m.IsActive},
 		{"IsGoogleAuth": m.IsGoogleAuth},
+		{"IsDeleted": m.IsDeleted},
+		{"Deleted": m.Deleted},
 	}
 }
 
 func FromMapToUser(data map[string]interface{}) User {
+	// soft delete fields are left out of API payloads
+	isDeleted, _ := data["IsDeleted"].(bool)
+	deleted, _ := data["Deleted"].(*time.Time)
 	return User{
 		ID:           data["ID"].(string),
 		Name:         data["Name"].(string),
@@ -51,5 +66,53 @@ func FromMapToUser(data map[string]interface{}) User {
 		Address:      data["Address"].(string),
 		IsActive:     data["IsActive"].(bool),
 		IsGoogleAuth: data["IsGoogleAuth"].(bool),
+		IsDeleted:    isDeleted,
+		Deleted:      deleted,
+	}
+}
+
+// UserUpdate holds the profile fields a user is allowed to change through
+// PUT /user. Fields left nil are not updated.
+type UserUpdate struct {
+	Name      *string `json:"Name,omitempty" msgpack:"Name,omitempty"`
+	Email     *string `json:"Email,omitempty" msgpack:"Email,omitempty"`
+	Password  *string `json:"Password,omitempty" msgpack:"Password,omitempty"`
+	Telephone *string `json:"Telephone,omitempty" msgpack:"Telephone,omitempty"`
+	Address   *string `json:"Address,omitempty" msgpack:"Address,omitempty"`
+}
+
+func (m *UserUpdate) GetMapFormat() map[string]interface{} {
+	res := map[string]interface{}{}
+	for k, v := range map[string]*string{
+		"Name":      m.Name,
+		"Email":     m.Email,
+		"Password":  m.Password,
+		"Telephone": m.Telephone,
+		"Address":   m.Address,
+	} {
+		if v != nil {
+			res[k] = *v
+		}
 	}
+	return res
+}
+
+// UserListQuery selects a page of users. Nil flags and empty strings don't
+// filter, Cursor is the NextCursor of the previous page.
+type UserListQuery struct {
+	IsActive     *bool
+	IsGoogleAuth *bool
+	EmailDomain  string
+	NamePrefix   string
+	SortBy       string
+	Desc         bool
+	Cursor       string
+	Limit        int
+}
+
+// UserPage is one page of users, NextCursor is empty on the last page.
+type UserPage struct {
+	Users      []User
+	NextCursor string
+	Total      int `json:"-"`
 }
@@ -0,0 +1,51 @@
+package repositories
+
+import (
+	m "github.com/rinosukmandityo/user-profile/models"
+)
+
+// columns holds the known columns of every table. Filter and update keys are
+// checked against it before a query is built, so no caller supplied string
+// ever ends up in the query text.
+var columns = map[string]map[string]bool{}
+
+func init() {
+	registerColumns(new(m.User).TableName(), new(m.User).GetMapFormat())
+	registerColumns(new(m.Session).TableName(), new(m.Session).GetMapFormat())
+	registerColumns(new(m.Token).TableName(), new(m.Token).GetMapFormat())
+	registerColumns(new(m.LoginAttempt).TableName(), new(m.LoginAttempt).GetMapFormat())
+	registerColumns(new(m.TwoFactor).TableName(), new(m.TwoFactor).GetMapFormat())
+}
+
+func registerColumns(table string, fields map[string]interface{}) {
+	columns[table] = map[string]bool{}
+	for k := range fields {
+		columns[table][k] = true
+	}
+}
+
+// CheckColumns returns ErrUnknownColumn for the first key which is not a column of table.
+func CheckColumns(table string, fields ...map[string]interface{}) error {
+	for _, field := range fields {
+		for k := range field {
+			if !columns[table][k] {
+				return ErrUnknownColumn(k)
+			}
+		}
+	}
+	return nil
+}
+
+// NotDeleted limits a users filter to accounts which are not soft deleted,
+// a filter which already has an IsDeleted key is returned as is.
+func NotDeleted(filter map[string]interface{}) map[string]interface{} {
+	if _, ok := filter["IsDeleted"]; ok {
+		return filter
+	}
+	res := make(map[string]interface{}, len(filter)+1)
+	for k, v := range filter {
+		res[k] = v
+	}
+	res["IsDeleted"] = false
+	return res
+}
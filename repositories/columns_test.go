@@ -0,0 +1,47 @@
+package repositories
+
+import (
+	"testing"
+)
+
+func TestCheckColumns(t *testing.T) {
+	testTable := []struct {
+		name    string
+		table   string
+		fields  map[string]interface{}
+		wantErr bool
+	}{
+		{
+			name:   "known_columns",
+			table:  "users",
+			fields: map[string]interface{}{"Name": "User 01", "Email": "usermail01@gmail.com"},
+		},
+		{
+			name:    "column_of_another_table",
+			table:   "users",
+			fields:  map[string]interface{}{"UserID": "userid01"},
+			wantErr: true,
+		},
+		{
+			name:    "sql_fragment",
+			table:   "users",
+			fields:  map[string]interface{}{"Name=Name, IsGoogleAuth": true},
+			wantErr: true,
+		},
+		{
+			name:    "unknown_table",
+			table:   "accounts",
+			fields:  map[string]interface{}{"ID": "userid01"},
+			wantErr: true,
+		},
+	}
+
+	for _, _tt := range testTable {
+		tt := _tt
+		t.Run(tt.name, func(t *testing.T) {
+			if e := CheckColumns(tt.table, tt.fields); (e != nil) != tt.wantErr {
+				t.Errorf("error is not correct, want error %v, got %v", tt.wantErr, e)
+			}
+		})
+	}
+}
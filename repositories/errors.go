@@ -0,0 +1,18 @@
+package repositories
+
+import (
+	"fmt"
+)
+
+// ErrDuplicateEntry builds the error returned by every driver when a unique key
+// is violated. It follows the MySQL wording so callers see the same message
+// regardless of the configured driver.
+func ErrDuplicateEntry(value interface{}, key string) error {
+	return fmt.Errorf("Error 1062: Duplicate entry '%v' for key '%s'", value, key)
+}
+
+// ErrUnknownColumn is returned when a filter or update key is not a column of
+// the table, it follows the MySQL wording as well.
+func ErrUnknownColumn(column string) error {
+	return fmt.Errorf("Error 1054: Unknown column '%s' in 'field list'", column)
+}
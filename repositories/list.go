@@ -0,0 +1,83 @@
+package repositories
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+
+	"github.com/rinosukmandityo/user-profile/helper"
+	m "github.com/rinosukmandityo/user-profile/models"
+)
+
+const (
+	DefaultListLimit = 20
+	MaxListLimit     = 100
+)
+
+// sortFields are the columns a user list can be sorted by, ID breaks ties so
+// every sort order is total and a cursor always points at one position.
+var sortFields = map[string]func(m.User) string{
+	"ID":    func(u m.User) string { return u.ID },
+	"Name":  func(u m.User) string { return u.Name },
+	"Email": func(u m.User) string { return u.Email },
+}
+
+// Cursor is the position after the last user of a page.
+type Cursor struct {
+	Value string `json:"v"`
+	ID    string `json:"id"`
+}
+
+// PrepareList fills the defaults of a list query and decodes its cursor, the
+// cursor is nil for the first page.
+func PrepareList(query m.UserListQuery) (m.UserListQuery, *Cursor, error) {
+	if query.SortBy == "" {
+		query.SortBy = "ID"
+	}
+	if _, ok := sortFields[query.SortBy]; !ok {
+		return query, nil, helper.ErrInvalidSort
+	}
+	if query.Limit <= 0 {
+		query.Limit = DefaultListLimit
+	}
+	if query.Limit > MaxListLimit {
+		query.Limit = MaxListLimit
+	}
+	query.EmailDomain = strings.ToLower(strings.TrimPrefix(query.EmailDomain, "@"))
+	query.NamePrefix = strings.ToLower(query.NamePrefix)
+	if query.Cursor == "" {
+		return query, nil, nil
+	}
+	b, e := base64.RawURLEncoding.DecodeString(query.Cursor)
+	if e != nil {
+		return query, nil, helper.ErrInvalidCursor
+	}
+	cursor := new(Cursor)
+	if e := json.Unmarshal(b, cursor); e != nil {
+		return query, nil, helper.ErrInvalidCursor
+	}
+	return query, cursor, nil
+}
+
+// SortValue returns the value of the sort field of a user.
+func SortValue(user m.User, sortBy string) string {
+	return sortFields[sortBy](user)
+}
+
+// NewPage builds a page from up to Limit+1 users, the extra user only tells
+// that there is a next page.
+func NewPage(users []m.User, query m.UserListQuery, total int) m.UserPage {
+	page := m.UserPage{Users: users, Total: total}
+	if len(users) > query.Limit {
+		page.Users = users[:query.Limit]
+		last := page.Users[query.Limit-1]
+		b, _ := json.Marshal(Cursor{Value: SortValue(last, query.SortBy), ID: last.ID})
+		page.NextCursor = base64.RawURLEncoding.EncodeToString(b)
+	}
+	return page
+}
+
+// EscapeLike escapes the LIKE wildcards of s, queries use ESCAPE '!'.
+func EscapeLike(s string) string {
+	return strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(s)
+}
@@ -0,0 +1,169 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	repo "github.com/rinosukmandityo/user-profile/repositories"
+
+	"github.com/pkg/errors"
+)
+
+// Database keeps every table in process memory. Repositories created from the
+// same Database share their data, the same way MySQL repositories share a schema.
+type Database struct {
+	mu       sync.RWMutex
+	txMu     sync.Mutex
+	users    []map[string]interface{}
+	sessions []map[string]interface{}
+	tokens   []map[string]interface{}
+	attempts []map[string]interface{}
+
+	twoFactors []map[string]interface{}
+}
+
+func NewDatabase() *Database {
+	return new(Database)
+}
+
+type txKey struct{}
+
+type transactor struct {
+	db *Database
+}
+
+// NewTransactor returns a Transactor that serializes transactions and rolls
+// back by restoring a snapshot of every table. Writes made outside the
+// transaction while it runs are rolled back too, which is fine for a driver
+// meant for development and tests.
+func NewTransactor(db *Database) repo.Transactor {
+	return &transactor{db}
+}
+
+func (t *transactor) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if ctx.Value(txKey{}) != nil {
+		return fn(ctx)
+	}
+	t.db.txMu.Lock()
+	defer t.db.txMu.Unlock()
+
+	users, sessions, tokens, attempts, twoFactors := t.db.snapshot()
+	if e := fn(context.WithValue(ctx, txKey{}, true)); e != nil {
+		t.db.mu.Lock()
+		t.db.users, t.db.sessions, t.db.tokens, t.db.attempts, t.db.twoFactors = users, sessions, tokens, attempts, twoFactors
+		t.db.mu.Unlock()
+		return e
+	}
+	return nil
+}
+
+func (db *Database) snapshot() (users, sessions, tokens, attempts, twoFactors []map[string]interface{}) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	return copyTable(db.users), copyTable(db.sessions), copyTable(db.tokens), copyTable(db.attempts), copyTable(db.twoFactors)
+}
+
+func copyTable(table []map[string]interface{}) []map[string]interface{} {
+	res := make([]map[string]interface{}, len(table))
+	for i, row := range table {
+		res[i] = copyRow(row)
+	}
+	return res
+}
+
+func errUnknownColumn(column string) error {
+	return fmt.Errorf("Error 1054: Unknown column '%s' in 'where clause'", column)
+}
+
+func isEqual(a, b interface{}) bool {
+	if ta, ok := a.(time.Time); ok {
+		tb, ok := b.(time.Time)
+		return ok && ta.Equal(tb)
+	}
+	if ta, ok := a.(*time.Time); ok {
+		tb, ok := b.(*time.Time)
+		return ok && (ta == tb || ta != nil && tb != nil && ta.Equal(*tb))
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func isMatch(row, filter map[string]interface{}) (bool, error) {
+	for k, v := range filter {
+		val, ok := row[k]
+		if !ok {
+			return false, errUnknownColumn(k)
+		}
+		if !isEqual(val, v) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func findRow(table []map[string]interface{}, filter map[string]interface{}) (int, error) {
+	for i, row := range table {
+		found, e := isMatch(row, filter)
+		if e != nil {
+			return -1, e
+		}
+		if found {
+			return i, nil
+		}
+	}
+	return -1, nil
+}
+
+// deleteRows removes every row for which remove returns true and reports how many were removed.
+func deleteRows(table []map[string]interface{}, remove func(row map[string]interface{}) bool) ([]map[string]interface{}, int64) {
+	res := table[:0]
+	for _, row := range table {
+		if !remove(row) {
+			res = append(res, row)
+		}
+	}
+	return res, int64(len(table) - len(res))
+}
+
+func insertRow(table []map[string]interface{}, row map[string]interface{}) ([]map[string]interface{}, error) {
+	idx, e := findRow(table, map[string]interface{}{"ID": row["ID"]})
+	if e != nil {
+		return table, e
+	}
+	if idx >= 0 {
+		return table, repo.ErrDuplicateEntry(row["ID"], "ID")
+	}
+	return append(table, row), nil
+}
+
+// updateRow applies data to the row and reports whether any value actually
+// changed, mirroring the affected-rows count returned by MySQL.
+func updateRow(row, data map[string]interface{}) (bool, error) {
+	for k, v := range data {
+		val, ok := row[k]
+		if !ok {
+			return false, errors.Errorf("Error 1054: Unknown column '%s' in 'field list'", k)
+		}
+		if reflect.TypeOf(val) != reflect.TypeOf(v) {
+			return false, errors.Errorf("Error 1366: Incorrect value for column '%s'", k)
+		}
+	}
+	changed := false
+	for k, v := range data {
+		if !isEqual(row[k], v) {
+			row[k] = v
+			changed = true
+		}
+	}
+	return changed, nil
+}
+
+func copyRow(row map[string]interface{}) map[string]interface{} {
+	res := make(map[string]interface{}, len(row))
+	for k, v := range row {
+		res[k] = v
+	}
+	return res
+}
@@ -0,0 +1,128 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/rinosukmandityo/user-profile/helper"
+	m "github.com/rinosukmandityo/user-profile/models"
+	repo "github.com/rinosukmandityo/user-profile/repositories"
+
+	"github.com/pkg/errors"
+)
+
+type loginAttemptMemoryRepository struct {
+	db *Database
+}
+
+func NewLoginAttemptRepository(db *Database) repo.LoginAttemptRepository {
+	return &loginAttemptMemoryRepository{db}
+}
+
+func (r *loginAttemptMemoryRepository) GetBy(ctx context.Context, id string) (m.LoginAttempt, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	idx, e := findRow(r.db.attempts, map[string]interface{}{"ID": id})
+	if e != nil {
+		return m.LoginAttempt{}, errors.Wrap(e, "repository.LoginAttempt.GetBy")
+	}
+	if idx < 0 {
+		return m.LoginAttempt{}, errors.Wrap(helper.ErrAttemptNotFound, "repository.LoginAttempt.GetBy")
+	}
+
+	return m.FromMapToLoginAttempt(r.db.attempts[idx]), nil
+}
+
+func (r *loginAttemptMemoryRepository) Save(ctx context.Context, data *m.LoginAttempt) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	idx, e := findRow(r.db.attempts, map[string]interface{}{"ID": data.ID})
+	if e != nil {
+		return errors.Wrap(e, "repository.LoginAttempt.Save")
+	}
+	if idx < 0 {
+		r.db.attempts = append(r.db.attempts, data.GetMapFormat())
+	} else {
+		r.db.attempts[idx] = data.GetMapFormat()
+	}
+
+	return nil
+}
+
+func (r *loginAttemptMemoryRepository) Increment(ctx context.Context, id string, now, resetBefore time.Time) (m.LoginAttempt, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	idx, e := findRow(r.db.attempts, map[string]interface{}{"ID": id})
+	if e != nil {
+		return m.LoginAttempt{}, errors.Wrap(e, "repository.LoginAttempt.Increment")
+	}
+	if idx < 0 {
+		attempt := m.LoginAttempt{ID: id, Failures: 1, LastFailed: now, LockedUntil: now}
+		r.db.attempts = append(r.db.attempts, attempt.GetMapFormat())
+		return attempt, nil
+	}
+	attempt := m.FromMapToLoginAttempt(r.db.attempts[idx])
+	if attempt.LastFailed.Before(resetBefore) {
+		attempt.Failures = 0
+	}
+	attempt.Failures++
+	attempt.LastFailed = now
+	r.db.attempts[idx] = attempt.GetMapFormat()
+
+	return attempt, nil
+}
+
+func (r *loginAttemptMemoryRepository) Lock(ctx context.Context, id string, lockedUntil time.Time) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	idx, e := findRow(r.db.attempts, map[string]interface{}{"ID": id})
+	if e != nil {
+		return errors.Wrap(e, "repository.LoginAttempt.Lock")
+	}
+	if idx >= 0 && r.db.attempts[idx]["LockedUntil"].(time.Time).Before(lockedUntil) {
+		row := copyRow(r.db.attempts[idx])
+		row["LockedUntil"] = lockedUntil
+		r.db.attempts[idx] = row
+	}
+
+	return nil
+}
+
+func (r *loginAttemptMemoryRepository) Delete(ctx context.Context, id string) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	r.db.attempts, _ = deleteRows(r.db.attempts, func(row map[string]interface{}) bool {
+		return row["ID"] == id
+	})
+
+	return nil
+}
+
+func (r *loginAttemptMemoryRepository) Purge(ctx context.Context, lockedBefore time.Time) (int64, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	var count int64
+	r.db.attempts, count = deleteRows(r.db.attempts, func(row map[string]interface{}) bool {
+		return row["LockedUntil"].(time.Time).Before(lockedBefore)
+	})
+
+	return count, nil
+}
+
+func (r *loginAttemptMemoryRepository) DeleteAll(ctx context.Context) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if len(r.db.attempts) == 0 {
+		return errors.Wrap(helper.ErrAttemptNotFound, "repository.LoginAttempt.DeleteAll")
+	}
+	r.db.attempts = nil
+
+	return nil
+}
@@ -0,0 +1,148 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/rinosukmandityo/user-profile/helper"
+	m "github.com/rinosukmandityo/user-profile/models"
+	repo "github.com/rinosukmandityo/user-profile/repositories"
+
+	"github.com/pkg/errors"
+)
+
+type sessionMemoryRepository struct {
+	db *Database
+}
+
+func NewSessionRepository(db *Database) repo.SessionRepository {
+	return &sessionMemoryRepository{db}
+}
+
+func (r *sessionMemoryRepository) GetBy(ctx context.Context, filter map[string]interface{}) (m.Session, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	idx, e := findRow(r.db.sessions, filter)
+	if e != nil {
+		return m.Session{}, errors.Wrap(e, "repository.Session.GetBy")
+	}
+	if idx < 0 {
+		return m.Session{}, errors.Wrap(helper.ErrUserNotFound, "repository.Session.GetBy")
+	}
+
+	return m.FromMapToSession(r.db.sessions[idx]), nil
+}
+
+func (r *sessionMemoryRepository) Store(ctx context.Context, data *m.Session) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	sessions, e := insertRow(r.db.sessions, data.GetMapFormat())
+	if e != nil {
+		return errors.Wrap(e, "repository.Session.Store")
+	}
+	r.db.sessions = sessions
+
+	return nil
+}
+
+func (r *sessionMemoryRepository) Update(ctx context.Context, data map[string]interface{}, id string) (m.Session, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	idx, e := findRow(r.db.sessions, map[string]interface{}{"ID": id})
+	if e != nil {
+		return m.Session{}, errors.Wrap(e, "repository.Session.Update")
+	}
+	if idx < 0 {
+		return m.Session{}, errors.Wrap(helper.ErrUserNotFound, "repository.Session.Update")
+	}
+	row := copyRow(r.db.sessions[idx])
+	changed, e := updateRow(row, data)
+	if e != nil {
+		return m.Session{}, errors.Wrap(e, "repository.Session.Update")
+	}
+	if !changed {
+		return m.Session{}, errors.Wrap(helper.ErrUserNotFound, "repository.Session.Update")
+	}
+	r.db.sessions[idx] = row
+
+	return m.FromMapToSession(row), nil
+}
+
+func (r *sessionMemoryRepository) Touch(ctx context.Context, sess m.Session, lastSeen, expired time.Time) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	idx, e := findRow(r.db.sessions, map[string]interface{}{"ID": sess.ID, "Expired": sess.Expired, "IsPending": sess.IsPending})
+	if e != nil {
+		return errors.Wrap(e, "repository.Session.Touch")
+	}
+	if idx < 0 {
+		return errors.Wrap(helper.ErrSessionNotFound, "repository.Session.Touch")
+	}
+	row := copyRow(r.db.sessions[idx])
+	row["LastSeen"] = lastSeen
+	row["Expired"] = expired
+	r.db.sessions[idx] = row
+
+	return nil
+}
+
+func (r *sessionMemoryRepository) ListByUser(ctx context.Context, userID string) ([]m.Session, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	sessions := []m.Session{}
+	for _, row := range r.db.sessions {
+		if row["UserID"] == userID {
+			sessions = append(sessions, m.FromMapToSession(row))
+		}
+	}
+
+	return sessions, nil
+}
+
+func (r *sessionMemoryRepository) Authenticate(ctx context.Context, email, password string) (bool, m.User, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	idx, e := findRow(r.db.users, repo.NotDeleted(map[string]interface{}{"Email": email}))
+	if e != nil {
+		return false, m.User{}, errors.Wrap(e, "repository.Session.Authenticate")
+	}
+	if idx < 0 {
+		return false, m.User{}, errors.Wrap(helper.ErrUserNotFound, "repository.Session.Authenticate")
+	}
+	res := m.FromMapToUser(r.db.users[idx])
+
+	if !repo.IsPasswordMatch(password, res.Password) {
+		return false, res, errors.Wrap(helper.ErrPasswordDoesNotMatch, "repository.Session.Authenticate")
+	}
+
+	return true, res, nil
+}
+
+func (r *sessionMemoryRepository) DeleteByUser(ctx context.Context, userID string) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	r.db.sessions, _ = deleteRows(r.db.sessions, func(row map[string]interface{}) bool {
+		return row["UserID"] == userID
+	})
+
+	return nil
+}
+
+func (r *sessionMemoryRepository) DeleteAll(ctx context.Context) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if len(r.db.sessions) == 0 {
+		return errors.Wrap(helper.ErrSessionNotFound, "repository.Session.DeleteAll")
+	}
+	r.db.sessions = nil
+
+	return nil
+}
@@ -0,0 +1,119 @@
+package memory
+
+import (
+	"context"
+	"github.com/rinosukmandityo/user-profile/helper"
+	m "github.com/rinosukmandityo/user-profile/models"
+	repo "github.com/rinosukmandityo/user-profile/repositories"
+
+	"github.com/pkg/errors"
+)
+
+type tokenMemoryRepository struct {
+	db *Database
+}
+
+func NewTokenRepository(db *Database) repo.TokenRepository {
+	return &tokenMemoryRepository{db}
+}
+
+func (r *tokenMemoryRepository) GetLatestToken(ctx context.Context, userid, purpose string) (m.Token, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	token := m.Token{}
+	found := false
+	for _, row := range r.db.tokens {
+		if row["UserID"] != userid || row["Purpose"] != purpose {
+			continue
+		}
+		item := m.FromMapToToken(row)
+		if !found || item.Expired.After(token.Expired) {
+			token = item
+			found = true
+		}
+	}
+	if !found {
+		return token, helper.ErrTokenNotFound
+	}
+
+	return token, nil
+}
+
+func (r *tokenMemoryRepository) Store(ctx context.Context, data *m.Token) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	tokens, e := insertRow(r.db.tokens, data.GetMapFormat())
+	if e != nil {
+		return errors.Wrap(e, "repository.Token.Store")
+	}
+	r.db.tokens = tokens
+
+	return nil
+}
+
+func (r *tokenMemoryRepository) Update(ctx context.Context, data map[string]interface{}, id string) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	idx, e := findRow(r.db.tokens, map[string]interface{}{"ID": id})
+	if e != nil {
+		return errors.Wrap(e, "repository.Token.Update")
+	}
+	if idx < 0 {
+		return errors.Wrap(helper.ErrUserNotFound, "repository.Token.Update")
+	}
+	row := copyRow(r.db.tokens[idx])
+	changed, e := updateRow(row, data)
+	if e != nil {
+		return errors.Wrap(e, "repository.Token.Update")
+	}
+	if !changed {
+		return errors.Wrap(helper.ErrUserNotFound, "repository.Token.Update")
+	}
+	r.db.tokens[idx] = row
+
+	return nil
+}
+
+func (r *tokenMemoryRepository) Claim(ctx context.Context, id string) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	idx, e := findRow(r.db.tokens, map[string]interface{}{"ID": id, "IsClaimed": false})
+	if e != nil {
+		return errors.Wrap(e, "repository.Token.Claim")
+	}
+	if idx < 0 {
+		return errors.Wrap(helper.ErrTokenNotFound, "repository.Token.Claim")
+	}
+	row := copyRow(r.db.tokens[idx])
+	row["IsClaimed"] = true
+	r.db.tokens[idx] = row
+
+	return nil
+}
+
+func (r *tokenMemoryRepository) DeleteByUser(ctx context.Context, userID string) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	r.db.tokens, _ = deleteRows(r.db.tokens, func(row map[string]interface{}) bool {
+		return row["UserID"] == userID
+	})
+
+	return nil
+}
+
+func (r *tokenMemoryRepository) DeleteAll(ctx context.Context) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if len(r.db.tokens) == 0 {
+		return errors.Wrap(helper.ErrTokenNotFound, "repository.Token.DeleteAll")
+	}
+	r.db.tokens = nil
+
+	return nil
+}
@@ -0,0 +1,93 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/rinosukmandityo/user-profile/helper"
+	m "github.com/rinosukmandityo/user-profile/models"
+	repo "github.com/rinosukmandityo/user-profile/repositories"
+
+	"github.com/pkg/errors"
+)
+
+type twoFactorMemoryRepository struct {
+	db *Database
+}
+
+func NewTwoFactorRepository(db *Database) repo.TwoFactorRepository {
+	return &twoFactorMemoryRepository{db}
+}
+
+func (r *twoFactorMemoryRepository) GetBy(ctx context.Context, userID string) (m.TwoFactor, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	idx, e := findRow(r.db.twoFactors, map[string]interface{}{"ID": userID})
+	if e != nil {
+		return m.TwoFactor{}, errors.Wrap(e, "repository.TwoFactor.GetBy")
+	}
+	if idx < 0 {
+		return m.TwoFactor{}, errors.Wrap(helper.ErrTwoFactorNotFound, "repository.TwoFactor.GetBy")
+	}
+
+	return m.FromMapToTwoFactor(r.db.twoFactors[idx]), nil
+}
+
+func (r *twoFactorMemoryRepository) Save(ctx context.Context, data *m.TwoFactor) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	idx, e := findRow(r.db.twoFactors, map[string]interface{}{"ID": data.ID})
+	if e != nil {
+		return errors.Wrap(e, "repository.TwoFactor.Save")
+	}
+	if idx < 0 {
+		r.db.twoFactors = append(r.db.twoFactors, data.GetMapFormat())
+	} else {
+		r.db.twoFactors[idx] = data.GetMapFormat()
+	}
+
+	return nil
+}
+
+func (r *twoFactorMemoryRepository) UpdateCodes(ctx context.Context, prev m.TwoFactor, lastStep int, recoveryCodes string) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	idx, e := findRow(r.db.twoFactors, map[string]interface{}{"ID": prev.ID, "LastStep": prev.LastStep, "RecoveryCodes": prev.RecoveryCodes})
+	if e != nil {
+		return errors.Wrap(e, "repository.TwoFactor.UpdateCodes")
+	}
+	if idx < 0 {
+		return errors.Wrap(helper.ErrTwoFactorNotFound, "repository.TwoFactor.UpdateCodes")
+	}
+	row := copyRow(r.db.twoFactors[idx])
+	row["LastStep"] = lastStep
+	row["RecoveryCodes"] = recoveryCodes
+	r.db.twoFactors[idx] = row
+
+	return nil
+}
+
+func (r *twoFactorMemoryRepository) Delete(ctx context.Context, userID string) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	r.db.twoFactors, _ = deleteRows(r.db.twoFactors, func(row map[string]interface{}) bool {
+		return row["ID"] == userID
+	})
+
+	return nil
+}
+
+func (r *twoFactorMemoryRepository) DeleteAll(ctx context.Context) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if len(r.db.twoFactors) == 0 {
+		return errors.Wrap(helper.ErrTwoFactorNotFound, "repository.TwoFactor.DeleteAll")
+	}
+	r.db.twoFactors = nil
+
+	return nil
+}
@@ -0,0 +1,161 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rinosukmandityo/user-profile/helper"
+	m "github.com/rinosukmandityo/user-profile/models"
+	repo "github.com/rinosukmandityo/user-profile/repositories"
+
+	"github.com/pkg/errors"
+)
+
+type userMemoryRepository struct {
+	db *Database
+}
+
+func NewUserRepository(db *Database) repo.UserRepository {
+	return &userMemoryRepository{db}
+}
+
+func (r *userMemoryRepository) GetBy(ctx context.Context, filter map[string]interface{}) (m.User, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	idx, e := findRow(r.db.users, repo.NotDeleted(filter))
+	if e != nil {
+		return m.User{}, errors.Wrap(e, "repository.User.GetBy")
+	}
+	if idx < 0 {
+		return m.User{}, errors.Wrap(helper.ErrUserNotFound, "repository.User.GetBy")
+	}
+
+	return m.FromMapToUser(r.db.users[idx]), nil
+}
+
+func (r *userMemoryRepository) Store(ctx context.Context, data *m.User) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	users, e := insertRow(r.db.users, data.GetMapFormat())
+	if e != nil {
+		return errors.Wrap(e, "repository.User.Store")
+	}
+	r.db.users = users
+
+	return nil
+}
+
+func (r *userMemoryRepository) Update(ctx context.Context, data map[string]interface{}, id string) (m.User, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	idx, e := findRow(r.db.users, map[string]interface{}{"ID": id})
+	if e != nil {
+		return m.User{}, errors.Wrap(e, "repository.User.Update")
+	}
+	if idx < 0 {
+		return m.User{}, errors.Wrap(helper.ErrUserNotFound, "repository.User.Update")
+	}
+	row := copyRow(r.db.users[idx])
+	changed, e := updateRow(row, data)
+	if e != nil {
+		return m.User{}, errors.Wrap(e, "repository.User.Update")
+	}
+	if !changed {
+		return m.User{}, errors.Wrap(helper.ErrUserNotFound, "repository.User.Update")
+	}
+	r.db.users[idx] = row
+
+	return m.FromMapToUser(row), nil
+}
+
+func (r *userMemoryRepository) List(ctx context.Context, query m.UserListQuery) (m.UserPage, error) {
+	query, cursor, e := repo.PrepareList(query)
+	if e != nil {
+		return m.UserPage{}, errors.Wrap(e, "repository.User.List")
+	}
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	matches := []m.User{}
+	for _, row := range r.db.users {
+		if user := m.FromMapToUser(row); isListMatch(user, query) {
+			matches = append(matches, user)
+		}
+	}
+	// same order as ORDER BY <SortBy>, ID
+	less := func(a, b m.User) bool {
+		va, vb := repo.SortValue(a, query.SortBy), repo.SortValue(b, query.SortBy)
+		if va != vb {
+			return va < vb != query.Desc
+		}
+		return a.ID < b.ID != query.Desc
+	}
+	sort.Slice(matches, func(i, j int) bool { return less(matches[i], matches[j]) })
+
+	users := []m.User{}
+	for _, user := range matches {
+		// the cursor is compared as a user holding its sort value
+		if cursor != nil && !less(m.User{ID: cursor.ID, Name: cursor.Value, Email: cursor.Value}, user) {
+			continue
+		}
+		if len(users) > query.Limit {
+			break
+		}
+		users = append(users, user)
+	}
+
+	return repo.NewPage(users, query, len(matches)), nil
+}
+
+func isListMatch(user m.User, query m.UserListQuery) bool {
+	if user.IsDeleted {
+		return false
+	}
+	if query.IsActive != nil && user.IsActive != *query.IsActive {
+		return false
+	}
+	if query.IsGoogleAuth != nil && user.IsGoogleAuth != *query.IsGoogleAuth {
+		return false
+	}
+	if query.EmailDomain != "" && !strings.HasSuffix(strings.ToLower(user.Email), "@"+query.EmailDomain) {
+		return false
+	}
+	if query.NamePrefix != "" && !strings.HasPrefix(strings.ToLower(user.Name), query.NamePrefix) {
+		return false
+	}
+	return true
+}
+
+func (r *userMemoryRepository) Purge(ctx context.Context, deletedBefore time.Time) ([]m.User, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	purged := []m.User{}
+	r.db.users, _ = deleteRows(r.db.users, func(row map[string]interface{}) bool {
+		user := m.FromMapToUser(row)
+		if user.IsDeleted && user.Deleted != nil && user.Deleted.Before(deletedBefore) {
+			purged = append(purged, user)
+			return true
+		}
+		return false
+	})
+
+	return purged, nil
+}
+
+func (r *userMemoryRepository) DeleteAll(ctx context.Context) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if len(r.db.users) == 0 {
+		return errors.Wrap(helper.ErrUserNotFound, "repository.User.DeleteAll")
+	}
+	r.db.users = nil
+
+	return nil
+}
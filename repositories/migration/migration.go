@@ -0,0 +1,270 @@
+// Package migration applies versioned schema changes and records them in the
+// schema_migrations table. Up and Down hold a database lock while they run so
+// instances starting at the same time don't apply a migration twice.
+//
+// Each version is applied in a transaction, which makes it atomic on Postgres and
+// SQLite. MySQL commits implicitly after every DDL statement, so a version which
+// fails halfway there leaves its earlier statements applied and unrecorded, they
+// have to be reverted by hand before running Up again.
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const tablename = "schema_migrations"
+
+// Migration is one versioned schema change. Statements are executed one by one
+// because not every driver accepts several statements in a single Exec.
+type Migration struct {
+	Version int
+	Name    string
+	Up      []string
+	Down    []string
+}
+
+// Placeholder returns the bind variable for the n-th (1-based) query parameter.
+type Placeholder func(n int) string
+
+func QuestionMark(n int) string {
+	return "?"
+}
+
+func Dollar(n int) string {
+	return fmt.Sprintf("$%d", n)
+}
+
+// Lock takes a lock which is held by conn until the returned release is called.
+type Lock func(conn *sql.Conn) (release func() error, e error)
+
+// lockName and lockID identify the migration lock, a name for MySQL and a number for Postgres
+const (
+	lockName    = tablename
+	lockID      = 7263501948
+	lockTimeout = 10 * time.Minute
+)
+
+func NoLock(conn *sql.Conn) (func() error, error) {
+	return func() error { return nil }, nil
+}
+
+// GetLock takes a MySQL named lock, waiting at most lockTimeout for it.
+func GetLock(conn *sql.Conn) (func() error, error) {
+	var acquired sql.NullInt64
+	e := conn.QueryRowContext(context.Background(), "SELECT GET_LOCK(?, ?)",
+		lockName, int(lockTimeout.Seconds())).Scan(&acquired)
+	if e != nil {
+		return nil, e
+	}
+	if acquired.Int64 != 1 {
+		return nil, errors.Errorf("lock %s not acquired within %s", lockName, lockTimeout)
+	}
+	return func() error {
+		_, e := conn.ExecContext(context.Background(), "SELECT RELEASE_LOCK(?)", lockName)
+		return e
+	}, nil
+}
+
+// AdvisoryLock takes a Postgres session level advisory lock.
+func AdvisoryLock(conn *sql.Conn) (func() error, error) {
+	if _, e := conn.ExecContext(context.Background(), "SELECT pg_advisory_lock($1)", lockID); e != nil {
+		return nil, e
+	}
+	return func() error {
+		_, e := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", lockID)
+		return e
+	}, nil
+}
+
+// conn is what migrations run on, *sql.DB and the *sql.Conn holding the lock
+type conn interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+}
+
+type Migrator struct {
+	db          *sql.DB
+	migrations  []Migration
+	placeholder Placeholder
+	lock        Lock
+}
+
+func New(db *sql.DB, migrations []Migration, placeholder Placeholder, lock Lock) *Migrator {
+	sorted := make([]Migration, len(migrations))
+	copy(sorted, migrations)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Version < sorted[j].Version
+	})
+	return &Migrator{db, sorted, placeholder, lock}
+}
+
+// withLock runs fn on a connection which holds the migration lock, the lock belongs
+// to the connection so it stays open until the lock is released.
+func (m *Migrator) withLock(fn func(c conn) error) error {
+	c, e := m.db.Conn(context.Background())
+	if e != nil {
+		return errors.Wrap(e, "migration.Lock")
+	}
+	defer c.Close()
+	release, e := m.lock(c)
+	if e != nil {
+		return errors.Wrap(e, "migration.Lock")
+	}
+	e = fn(c)
+	if re := release(); re != nil && e == nil {
+		e = errors.Wrap(re, "migration.Unlock")
+	}
+	return e
+}
+
+func (m *Migrator) createTable(c conn) error {
+	schema := `CREATE TABLE IF NOT EXISTS ` + tablename + ` (
+		version BIGINT NOT NULL PRIMARY KEY,
+		name VARCHAR(255) NOT NULL,
+		applied_at TIMESTAMP NOT NULL
+	)`
+	if _, e := c.ExecContext(context.Background(), schema); e != nil {
+		return errors.Wrap(e, "migration.CreateTable")
+	}
+	return nil
+}
+
+func (m *Migrator) validate() error {
+	for i := 1; i < len(m.migrations); i++ {
+		if m.migrations[i].Version == m.migrations[i-1].Version {
+			return errors.Errorf("migration.Validate: duplicate migration version %d", m.migrations[i].Version)
+		}
+	}
+	return nil
+}
+
+func (m *Migrator) appliedVersions(c conn) (map[int]bool, error) {
+	res := map[int]bool{}
+	rows, e := c.QueryContext(context.Background(), "SELECT version FROM "+tablename)
+	if e != nil {
+		return res, errors.Wrap(e, "migration.AppliedVersions")
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var version int
+		if e = rows.Scan(&version); e != nil {
+			return res, errors.Wrap(e, "migration.AppliedVersions")
+		}
+		res[version] = true
+	}
+	return res, errors.Wrap(rows.Err(), "migration.AppliedVersions")
+}
+
+func (m *Migrator) run(c conn, item Migration, statements []string, bookkeeping string, args ...interface{}) error {
+	tx, e := c.BeginTx(context.Background(), nil)
+	if e != nil {
+		return e
+	}
+	for _, statement := range statements {
+		if _, e = tx.Exec(statement); e != nil {
+			tx.Rollback()
+			return errors.Wrapf(e, "version %d %s", item.Version, item.Name)
+		}
+	}
+	if _, e = tx.Exec(bookkeeping, args...); e != nil {
+		tx.Rollback()
+		return errors.Wrapf(e, "version %d %s", item.Version, item.Name)
+	}
+	return tx.Commit()
+}
+
+// Up applies every pending migration in version order and returns how many were applied.
+func (m *Migrator) Up() (int, error) {
+	if e := m.validate(); e != nil {
+		return 0, e
+	}
+	count := 0
+	e := m.withLock(func(c conn) (e error) {
+		count, e = m.up(c)
+		return e
+	})
+	return count, e
+}
+
+func (m *Migrator) up(c conn) (int, error) {
+	if e := m.createTable(c); e != nil {
+		return 0, e
+	}
+	applied, e := m.appliedVersions(c)
+	if e != nil {
+		return 0, e
+	}
+	q := fmt.Sprintf("INSERT INTO %s (version, name, applied_at) VALUES (%s, %s, %s)",
+		tablename, m.placeholder(1), m.placeholder(2), m.placeholder(3))
+	count := 0
+	for _, item := range m.migrations {
+		if applied[item.Version] {
+			continue
+		}
+		if e = m.run(c, item, item.Up, q, item.Version, item.Name, time.Now().UTC()); e != nil {
+			return count, errors.Wrap(e, "migration.Up")
+		}
+		fmt.Printf("Migration %d '%s' applied\n", item.Version, item.Name)
+		count++
+	}
+	return count, nil
+}
+
+// Down reverts the latest applied migrations, at most steps of them.
+func (m *Migrator) Down(steps int) (int, error) {
+	count := 0
+	e := m.withLock(func(c conn) (e error) {
+		count, e = m.down(c, steps)
+		return e
+	})
+	return count, e
+}
+
+func (m *Migrator) down(c conn, steps int) (int, error) {
+	if e := m.createTable(c); e != nil {
+		return 0, e
+	}
+	applied, e := m.appliedVersions(c)
+	if e != nil {
+		return 0, e
+	}
+	q := fmt.Sprintf("DELETE FROM %s WHERE version=%s", tablename, m.placeholder(1))
+	count := 0
+	for i := len(m.migrations) - 1; i >= 0 && count < steps; i-- {
+		item := m.migrations[i]
+		if !applied[item.Version] {
+			continue
+		}
+		if e = m.run(c, item, item.Down, q, item.Version); e != nil {
+			return count, errors.Wrap(e, "migration.Down")
+		}
+		fmt.Printf("Migration %d '%s' reverted\n", item.Version, item.Name)
+		count++
+	}
+	return count, nil
+}
+
+// Version returns the latest applied migration version, 0 when nothing is applied.
+func (m *Migrator) Version() (int, error) {
+	if e := m.createTable(m.db); e != nil {
+		return 0, e
+	}
+	applied, e := m.appliedVersions(m.db)
+	if e != nil {
+		return 0, e
+	}
+	version := 0
+	for v := range applied {
+		if v > version {
+			version = v
+		}
+	}
+	return version, nil
+}
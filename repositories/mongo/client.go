@@ -0,0 +1,50 @@
+package mongo
+
+import (
+	"context"
+	"time"
+
+	repo "github.com/rinosukmandityo/user-profile/repositories"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+// NewClient connects the client shared by all MongoDB repositories. The driver
+// keeps its own connection pool, PoolConfig only tunes its size and idle time.
+func NewClient(URL string, timeout int, pool repo.PoolConfig) (*mongo.Client, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeout)*time.Second)
+	defer cancel()
+	opts := options.Client().ApplyURI(URL)
+	if pool.MaxOpenConns > 0 {
+		opts.SetMaxPoolSize(uint64(pool.MaxOpenConns))
+	}
+	if pool.ConnMaxIdleTime > 0 {
+		opts.SetMaxConnIdleTime(pool.ConnMaxIdleTime)
+	}
+	client, e := mongo.Connect(ctx, opts)
+	if e != nil {
+		return nil, e
+	}
+	if e = client.Ping(ctx, readpref.Primary()); e != nil {
+		client.Disconnect(context.Background())
+		return nil, e
+	}
+
+	return client, nil
+}
+
+func createIndexes(ctx context.Context, coll *mongo.Collection, indexes []mongo.IndexModel) error {
+	_, e := coll.Indexes().CreateMany(ctx, indexes)
+	return e
+}
+
+func uniqueIndex(keys bson.D) mongo.IndexModel {
+	return mongo.IndexModel{Keys: keys, Options: options.Index().SetUnique(true)}
+}
+
+func index(keys bson.D) mongo.IndexModel {
+	return mongo.IndexModel{Keys: keys}
+}
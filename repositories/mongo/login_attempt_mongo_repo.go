@@ -0,0 +1,150 @@
+package mongo
+
+import (
+	"context"
+	"time"
+
+	"github.com/rinosukmandityo/user-profile/helper"
+	m "github.com/rinosukmandityo/user-profile/models"
+	repo "github.com/rinosukmandityo/user-profile/repositories"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type loginAttemptMongoRepository struct {
+	client   *mongo.Client
+	database string
+	timeout  time.Duration
+}
+
+func (r *loginAttemptMongoRepository) collection() *mongo.Collection {
+	return r.client.Database(r.database).Collection(new(m.LoginAttempt).TableName())
+}
+
+func (r *loginAttemptMongoRepository) createIndexes() error {
+	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+	defer cancel()
+	if e := createIndexes(ctx, r.collection(), []mongo.IndexModel{
+		uniqueIndex(bson.D{{Key: "ID", Value: 1}}),
+		index(bson.D{{Key: "LockedUntil", Value: 1}}),
+	}); e != nil {
+		return errors.Wrap(e, "repository.LoginAttempt.CreateIndexes")
+	}
+	return nil
+}
+
+func NewLoginAttemptRepository(client *mongo.Client, DB string, timeout int) (repo.LoginAttemptRepository, error) {
+	repo := &loginAttemptMongoRepository{
+		client:   client,
+		database: DB,
+		timeout:  time.Duration(timeout) * time.Second,
+	}
+	if e := repo.createIndexes(); e != nil {
+		return nil, e
+	}
+	return repo, nil
+}
+
+func (r *loginAttemptMongoRepository) GetBy(ctx context.Context, id string) (m.LoginAttempt, error) {
+	res := m.LoginAttempt{}
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	if e := r.collection().FindOne(ctx, bson.M{"ID": id}).Decode(&res); e != nil {
+		if e == mongo.ErrNoDocuments {
+			return res, errors.Wrap(helper.ErrAttemptNotFound, "repository.LoginAttempt.GetBy")
+		}
+		return res, errors.Wrap(e, "repository.LoginAttempt.GetBy")
+	}
+
+	return res, nil
+}
+
+func (r *loginAttemptMongoRepository) Save(ctx context.Context, data *m.LoginAttempt) error {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	opts := options.Replace().SetUpsert(true)
+	if _, e := r.collection().ReplaceOne(ctx, bson.M{"ID": data.ID}, data, opts); e != nil {
+		return errors.Wrap(e, "repository.LoginAttempt.Save")
+	}
+
+	return nil
+}
+
+func (r *loginAttemptMongoRepository) Increment(ctx context.Context, id string, now, resetBefore time.Time) (m.LoginAttempt, error) {
+	res := m.LoginAttempt{}
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	// an update pipeline compares LastFailed before setting it, a missing counter is
+	// inserted with its fields unset which counts as a first failure
+	update := mongo.Pipeline{{{Key: "$set", Value: bson.M{
+		"Failures": bson.M{"$cond": bson.A{
+			bson.M{"$lt": bson.A{bson.M{"$ifNull": bson.A{"$LastFailed", time.Time{}}}, resetBefore}},
+			1,
+			bson.M{"$add": bson.A{"$Failures", 1}},
+		}},
+		"LastFailed":  now,
+		"LockedUntil": bson.M{"$ifNull": bson.A{"$LockedUntil", now}},
+	}}}}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	if e := r.collection().FindOneAndUpdate(ctx, bson.M{"ID": id}, update, opts).Decode(&res); e != nil {
+		return res, errors.Wrap(e, "repository.LoginAttempt.Increment")
+	}
+
+	return res, nil
+}
+
+func (r *loginAttemptMongoRepository) Lock(ctx context.Context, id string, lockedUntil time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	filter := bson.M{"ID": id, "LockedUntil": bson.M{"$lt": lockedUntil}}
+	if _, e := r.collection().UpdateOne(ctx, filter, bson.M{"$set": bson.M{"LockedUntil": lockedUntil}}); e != nil {
+		return errors.Wrap(e, "repository.LoginAttempt.Lock")
+	}
+
+	return nil
+}
+
+func (r *loginAttemptMongoRepository) Delete(ctx context.Context, id string) error {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	if _, e := r.collection().DeleteOne(ctx, bson.M{"ID": id}); e != nil {
+		return errors.Wrap(e, "repository.LoginAttempt.Delete")
+	}
+
+	return nil
+}
+
+func (r *loginAttemptMongoRepository) Purge(ctx context.Context, lockedBefore time.Time) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	res, e := r.collection().DeleteMany(ctx, bson.M{"LockedUntil": bson.M{"$lt": lockedBefore}})
+	if e != nil {
+		return 0, errors.Wrap(e, "repository.LoginAttempt.Purge")
+	}
+
+	return res.DeletedCount, nil
+}
+
+func (r *loginAttemptMongoRepository) DeleteAll(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	res, e := r.collection().DeleteMany(ctx, bson.M{})
+	if e != nil {
+		return errors.Wrap(e, "repository.LoginAttempt.DeleteAll")
+	}
+	if res.DeletedCount == 0 {
+		return errors.Wrap(helper.ErrAttemptNotFound, "repository.LoginAttempt.DeleteAll")
+	}
+
+	return nil
+}
@@ -0,0 +1,119 @@
+package mongo
+
+import (
+	"regexp"
+	"strings"
+
+	m "github.com/rinosukmandityo/user-profile/models"
+	repo "github.com/rinosukmandityo/user-profile/repositories"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const duplicateKeyCode = 11000
+
+func constructUserField(field string) string {
+	// users keep their ID in the default _id field, see the bson tag on models.User
+	if field == "ID" {
+		return "_id"
+	}
+	return field
+}
+
+func constructUserFilter(filter map[string]interface{}) bson.M {
+	q := constructUserFields(filter)
+	// documents stored before soft delete existed have no IsDeleted field
+	if v, ok := q["IsDeleted"]; ok && v == false {
+		q["IsDeleted"] = bson.M{"$ne": true}
+	}
+	return q
+}
+
+func constructUserFields(data map[string]interface{}) bson.M {
+	q := bson.M{}
+	for k, v := range data {
+		q[constructUserField(k)] = v
+	}
+	return q
+}
+
+func constructUserUpdate(data map[string]interface{}) bson.M {
+	return bson.M{"$set": constructUserFields(data)}
+}
+
+func constructListFilter(query m.UserListQuery, cursor *repo.Cursor) bson.M {
+	q := constructUserFilter(repo.NotDeleted(map[string]interface{}{}))
+	if query.IsActive != nil {
+		q["IsActive"] = *query.IsActive
+	}
+	if query.IsGoogleAuth != nil {
+		q["IsGoogleAuth"] = *query.IsGoogleAuth
+	}
+	if query.EmailDomain != "" {
+		q["Email"] = primitive.Regex{Pattern: "@" + regexp.QuoteMeta(query.EmailDomain) + "$", Options: "i"}
+	}
+	if query.NamePrefix != "" {
+		q["Name"] = primitive.Regex{Pattern: "^" + regexp.QuoteMeta(query.NamePrefix), Options: "i"}
+	}
+	if cursor != nil {
+		op := "$gt"
+		if query.Desc {
+			op = "$lt"
+		}
+		if query.SortBy == "ID" {
+			q["_id"] = bson.M{op: cursor.ID}
+		} else {
+			q["$or"] = bson.A{
+				bson.M{query.SortBy: bson.M{op: cursor.Value}},
+				bson.M{query.SortBy: cursor.Value, "_id": bson.M{op: cursor.ID}},
+			}
+		}
+	}
+	return q
+}
+
+func constructListOptions(query m.UserListQuery) *options.FindOptions {
+	order := 1
+	if query.Desc {
+		order = -1
+	}
+	sort := bson.D{{Key: constructUserField(query.SortBy), Value: order}}
+	if query.SortBy != "ID" {
+		sort = append(sort, bson.E{Key: "_id", Value: order})
+	}
+	return options.Find().SetSort(sort).SetLimit(int64(query.Limit + 1))
+}
+
+func constructFilter(filter map[string]interface{}) bson.M {
+	q := bson.M{}
+	for k, v := range filter {
+		q[k] = v
+	}
+	return q
+}
+
+func constructUpdate(data map[string]interface{}) bson.M {
+	return bson.M{"$set": constructFilter(data)}
+}
+
+// duplicateKey returns the indexed field which caused a duplicate key error,
+// or an empty string when e is not a duplicate key error.
+func duplicateKey(e error) string {
+	we, ok := e.(mongo.WriteException)
+	if !ok {
+		return ""
+	}
+	for _, item := range we.WriteErrors {
+		if item.Code != duplicateKeyCode {
+			continue
+		}
+		if strings.Contains(item.Message, "Email_1") {
+			return "Email"
+		}
+		return "ID"
+	}
+	return ""
+}
@@ -0,0 +1,186 @@
+package mongo
+
+import (
+	"context"
+	"time"
+
+	"github.com/rinosukmandityo/user-profile/helper"
+	m "github.com/rinosukmandityo/user-profile/models"
+	repo "github.com/rinosukmandityo/user-profile/repositories"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type sessionMongoRepository struct {
+	client   *mongo.Client
+	database string
+	timeout  time.Duration
+}
+
+func (r *sessionMongoRepository) collection() *mongo.Collection {
+	return r.client.Database(r.database).Collection(new(m.Session).TableName())
+}
+
+func (r *sessionMongoRepository) userCollection() *mongo.Collection {
+	return r.client.Database(r.database).Collection(new(m.User).TableName())
+}
+
+func (r *sessionMongoRepository) createIndexes() error {
+	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+	defer cancel()
+	if e := createIndexes(ctx, r.collection(), []mongo.IndexModel{
+		uniqueIndex(bson.D{{Key: "ID", Value: 1}}),
+		index(bson.D{{Key: "Email", Value: 1}}),
+		index(bson.D{{Key: "UserID", Value: 1}}),
+	}); e != nil {
+		return errors.Wrap(e, "repository.Session.CreateIndexes")
+	}
+	return nil
+}
+
+func NewSessionRepository(client *mongo.Client, DB string, timeout int) (repo.SessionRepository, error) {
+	repo := &sessionMongoRepository{
+		client:   client,
+		database: DB,
+		timeout:  time.Duration(timeout) * time.Second,
+	}
+	if e := repo.createIndexes(); e != nil {
+		return nil, e
+	}
+	return repo, nil
+}
+
+func (r *sessionMongoRepository) GetBy(ctx context.Context, filter map[string]interface{}) (m.Session, error) {
+	if e := repo.CheckColumns(new(m.Session).TableName(), filter); e != nil {
+		return m.Session{}, errors.Wrap(e, "repository.Session.GetBy")
+	}
+	res := m.Session{}
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	if e := r.collection().FindOne(ctx, constructFilter(filter)).Decode(&res); e != nil {
+		if e == mongo.ErrNoDocuments {
+			return res, errors.Wrap(helper.ErrUserNotFound, "repository.Session.GetBy")
+		}
+		return res, errors.Wrap(e, "repository.Session.GetBy")
+	}
+
+	return res, nil
+}
+
+func (r *sessionMongoRepository) Store(ctx context.Context, data *m.Session) error {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	if _, e := r.collection().InsertOne(ctx, data); e != nil {
+		if duplicateKey(e) != "" {
+			return errors.Wrap(repo.ErrDuplicateEntry(data.ID, "ID"), "repository.Session.Store")
+		}
+		return errors.Wrap(e, "repository.Session.Store")
+	}
+
+	return nil
+}
+
+func (r *sessionMongoRepository) Update(ctx context.Context, data map[string]interface{}, id string) (m.Session, error) {
+	if e := repo.CheckColumns(new(m.Session).TableName(), data); e != nil {
+		return m.Session{}, errors.Wrap(e, "repository.Session.Update")
+	}
+	session := m.Session{}
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	filter := map[string]interface{}{"ID": id}
+	res, e := r.collection().UpdateOne(ctx, constructFilter(filter), constructUpdate(data))
+	if e != nil {
+		return session, errors.Wrap(e, "repository.Session.Update")
+	}
+	if res.ModifiedCount == 0 {
+		return session, errors.Wrap(helper.ErrUserNotFound, "repository.Session.Update")
+	}
+	session, e = r.GetBy(ctx, filter)
+	if e != nil {
+		return session, errors.Wrap(e, "repository.Session.Update")
+	}
+
+	return session, nil
+}
+
+func (r *sessionMongoRepository) Touch(ctx context.Context, sess m.Session, lastSeen, expired time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	filter := map[string]interface{}{"ID": sess.ID, "Expired": sess.Expired, "IsPending": sess.IsPending}
+	res, e := r.collection().UpdateOne(ctx, constructFilter(filter), constructUpdate(map[string]interface{}{"LastSeen": lastSeen, "Expired": expired}))
+	if e != nil {
+		return errors.Wrap(e, "repository.Session.Touch")
+	}
+	if res.MatchedCount == 0 {
+		return errors.Wrap(helper.ErrSessionNotFound, "repository.Session.Touch")
+	}
+
+	return nil
+}
+
+func (r *sessionMongoRepository) ListByUser(ctx context.Context, userID string) ([]m.Session, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	results, e := r.collection().Find(ctx, bson.M{"UserID": userID})
+	if e != nil {
+		return nil, errors.Wrap(e, "repository.Session.ListByUser")
+	}
+	sessions := []m.Session{}
+	if e := results.All(ctx, &sessions); e != nil {
+		return nil, errors.Wrap(e, "repository.Session.ListByUser")
+	}
+
+	return sessions, nil
+}
+
+func (r *sessionMongoRepository) Authenticate(ctx context.Context, email, password string) (bool, m.User, error) {
+	res := m.User{}
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	if e := r.userCollection().FindOne(ctx, constructUserFilter(repo.NotDeleted(map[string]interface{}{"Email": email}))).Decode(&res); e != nil {
+		if e == mongo.ErrNoDocuments {
+			return false, res, errors.Wrap(helper.ErrUserNotFound, "repository.Session.Authenticate")
+		}
+		return false, res, errors.Wrap(e, "repository.Session.Authenticate")
+	}
+
+	if !repo.IsPasswordMatch(password, res.Password) {
+		return false, res, errors.Wrap(helper.ErrPasswordDoesNotMatch, "repository.Session.Authenticate")
+	}
+
+	return true, res, nil
+}
+
+func (r *sessionMongoRepository) DeleteByUser(ctx context.Context, userID string) error {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	if _, e := r.collection().DeleteMany(ctx, bson.M{"UserID": userID}); e != nil {
+		return errors.Wrap(e, "repository.Session.DeleteByUser")
+	}
+
+	return nil
+}
+
+func (r *sessionMongoRepository) DeleteAll(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	res, e := r.collection().DeleteMany(ctx, bson.M{})
+	if e != nil {
+		return errors.Wrap(e, "repository.Session.DeleteAll")
+	}
+	if res.DeletedCount == 0 {
+		return errors.Wrap(helper.ErrSessionNotFound, "repository.Session.DeleteAll")
+	}
+
+	return nil
+}
@@ -0,0 +1,138 @@
+package mongo
+
+import (
+	"context"
+	"time"
+
+	"github.com/rinosukmandityo/user-profile/helper"
+	m "github.com/rinosukmandityo/user-profile/models"
+	repo "github.com/rinosukmandityo/user-profile/repositories"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type tokenMongoRepository struct {
+	client   *mongo.Client
+	database string
+	timeout  time.Duration
+}
+
+func (r *tokenMongoRepository) collection() *mongo.Collection {
+	return r.client.Database(r.database).Collection(new(m.Token).TableName())
+}
+
+func (r *tokenMongoRepository) createIndexes() error {
+	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+	defer cancel()
+	if e := createIndexes(ctx, r.collection(), []mongo.IndexModel{
+		uniqueIndex(bson.D{{Key: "ID", Value: 1}}),
+		index(bson.D{{Key: "UserID", Value: 1}, {Key: "Purpose", Value: 1}, {Key: "Expired", Value: -1}}),
+	}); e != nil {
+		return errors.Wrap(e, "repository.Token.CreateIndexes")
+	}
+	return nil
+}
+
+func NewTokenRepository(client *mongo.Client, DB string, timeout int) (repo.TokenRepository, error) {
+	repo := &tokenMongoRepository{
+		client:   client,
+		database: DB,
+		timeout:  time.Duration(timeout) * time.Second,
+	}
+	if e := repo.createIndexes(); e != nil {
+		return nil, e
+	}
+	return repo, nil
+}
+
+func (r *tokenMongoRepository) GetLatestToken(ctx context.Context, userid, purpose string) (m.Token, error) {
+	token := m.Token{}
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	opts := options.FindOne().SetSort(bson.D{{Key: "Expired", Value: -1}})
+	if e := r.collection().FindOne(ctx, bson.M{"UserID": userid, "Purpose": purpose}, opts).Decode(&token); e != nil {
+		if e == mongo.ErrNoDocuments {
+			return token, helper.ErrTokenNotFound
+		}
+		return token, errors.Wrap(e, "repository.Token.GetLatestToken")
+	}
+
+	return token, nil
+}
+
+func (r *tokenMongoRepository) Store(ctx context.Context, data *m.Token) error {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	if _, e := r.collection().InsertOne(ctx, data); e != nil {
+		if duplicateKey(e) != "" {
+			return errors.Wrap(repo.ErrDuplicateEntry(data.ID, "ID"), "repository.Token.Store")
+		}
+		return errors.Wrap(e, "repository.Token.Store")
+	}
+
+	return nil
+}
+
+func (r *tokenMongoRepository) Update(ctx context.Context, data map[string]interface{}, id string) error {
+	if e := repo.CheckColumns(new(m.Token).TableName(), data); e != nil {
+		return errors.Wrap(e, "repository.Token.Update")
+	}
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	res, e := r.collection().UpdateOne(ctx, bson.M{"ID": id}, constructUpdate(data))
+	if e != nil {
+		return errors.Wrap(e, "repository.Token.Update")
+	}
+	if res.ModifiedCount == 0 {
+		return errors.Wrap(helper.ErrUserNotFound, "repository.Token.Update")
+	}
+
+	return nil
+}
+
+func (r *tokenMongoRepository) Claim(ctx context.Context, id string) error {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	res, e := r.collection().UpdateOne(ctx, bson.M{"ID": id, "IsClaimed": false}, constructUpdate(map[string]interface{}{"IsClaimed": true}))
+	if e != nil {
+		return errors.Wrap(e, "repository.Token.Claim")
+	}
+	if res.ModifiedCount == 0 {
+		return errors.Wrap(helper.ErrTokenNotFound, "repository.Token.Claim")
+	}
+
+	return nil
+}
+
+func (r *tokenMongoRepository) DeleteByUser(ctx context.Context, userID string) error {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	if _, e := r.collection().DeleteMany(ctx, bson.M{"UserID": userID}); e != nil {
+		return errors.Wrap(e, "repository.Token.DeleteByUser")
+	}
+
+	return nil
+}
+
+func (r *tokenMongoRepository) DeleteAll(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	res, e := r.collection().DeleteMany(ctx, bson.M{})
+	if e != nil {
+		return errors.Wrap(e, "repository.Token.DeleteAll")
+	}
+	if res.DeletedCount == 0 {
+		return errors.Wrap(helper.ErrTokenNotFound, "repository.Token.DeleteAll")
+	}
+
+	return nil
+}
@@ -0,0 +1,37 @@
+package mongo
+
+import (
+	"context"
+
+	repo "github.com/rinosukmandityo/user-profile/repositories"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type transactor struct {
+	client *mongo.Client
+}
+
+// NewTransactor returns a Transactor backed by MongoDB sessions. Transactions
+// need the server to run as a replica set.
+func NewTransactor(client *mongo.Client) repo.Transactor {
+	return &transactor{client}
+}
+
+func (t *transactor) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if mongo.SessionFromContext(ctx) != nil {
+		return fn(ctx)
+	}
+	session, e := t.client.StartSession()
+	if e != nil {
+		return errors.Wrap(e, "repository.Transaction.Begin")
+	}
+	defer session.EndSession(ctx)
+
+	// collections pick the session up from any context derived from sessCtx
+	_, e = session.WithTransaction(ctx, func(sessCtx mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sessCtx)
+	})
+	return e
+}
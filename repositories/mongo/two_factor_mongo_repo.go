@@ -0,0 +1,117 @@
+package mongo
+
+import (
+	"context"
+	"time"
+
+	"github.com/rinosukmandityo/user-profile/helper"
+	m "github.com/rinosukmandityo/user-profile/models"
+	repo "github.com/rinosukmandityo/user-profile/repositories"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type twoFactorMongoRepository struct {
+	client   *mongo.Client
+	database string
+	timeout  time.Duration
+}
+
+func (r *twoFactorMongoRepository) collection() *mongo.Collection {
+	return r.client.Database(r.database).Collection(new(m.TwoFactor).TableName())
+}
+
+func (r *twoFactorMongoRepository) createIndexes() error {
+	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+	defer cancel()
+	if e := createIndexes(ctx, r.collection(), []mongo.IndexModel{
+		uniqueIndex(bson.D{{Key: "ID", Value: 1}}),
+	}); e != nil {
+		return errors.Wrap(e, "repository.TwoFactor.CreateIndexes")
+	}
+	return nil
+}
+
+func NewTwoFactorRepository(client *mongo.Client, DB string, timeout int) (repo.TwoFactorRepository, error) {
+	repo := &twoFactorMongoRepository{
+		client:   client,
+		database: DB,
+		timeout:  time.Duration(timeout) * time.Second,
+	}
+	if e := repo.createIndexes(); e != nil {
+		return nil, e
+	}
+	return repo, nil
+}
+
+func (r *twoFactorMongoRepository) GetBy(ctx context.Context, userID string) (m.TwoFactor, error) {
+	res := m.TwoFactor{}
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	if e := r.collection().FindOne(ctx, bson.M{"ID": userID}).Decode(&res); e != nil {
+		if e == mongo.ErrNoDocuments {
+			return res, errors.Wrap(helper.ErrTwoFactorNotFound, "repository.TwoFactor.GetBy")
+		}
+		return res, errors.Wrap(e, "repository.TwoFactor.GetBy")
+	}
+
+	return res, nil
+}
+
+func (r *twoFactorMongoRepository) Save(ctx context.Context, data *m.TwoFactor) error {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	opts := options.Replace().SetUpsert(true)
+	if _, e := r.collection().ReplaceOne(ctx, bson.M{"ID": data.ID}, data, opts); e != nil {
+		return errors.Wrap(e, "repository.TwoFactor.Save")
+	}
+
+	return nil
+}
+
+func (r *twoFactorMongoRepository) UpdateCodes(ctx context.Context, prev m.TwoFactor, lastStep int, recoveryCodes string) error {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	filter := bson.M{"ID": prev.ID, "LastStep": prev.LastStep, "RecoveryCodes": prev.RecoveryCodes}
+	res, e := r.collection().UpdateOne(ctx, filter, bson.M{"$set": bson.M{"LastStep": lastStep, "RecoveryCodes": recoveryCodes}})
+	if e != nil {
+		return errors.Wrap(e, "repository.TwoFactor.UpdateCodes")
+	}
+	if res.MatchedCount == 0 {
+		return errors.Wrap(helper.ErrTwoFactorNotFound, "repository.TwoFactor.UpdateCodes")
+	}
+
+	return nil
+}
+
+func (r *twoFactorMongoRepository) Delete(ctx context.Context, userID string) error {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	if _, e := r.collection().DeleteOne(ctx, bson.M{"ID": userID}); e != nil {
+		return errors.Wrap(e, "repository.TwoFactor.Delete")
+	}
+
+	return nil
+}
+
+func (r *twoFactorMongoRepository) DeleteAll(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	res, e := r.collection().DeleteMany(ctx, bson.M{})
+	if e != nil {
+		return errors.Wrap(e, "repository.TwoFactor.DeleteAll")
+	}
+	if res.DeletedCount == 0 {
+		return errors.Wrap(helper.ErrTwoFactorNotFound, "repository.TwoFactor.DeleteAll")
+	}
+
+	return nil
+}
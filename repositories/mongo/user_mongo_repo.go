@@ -0,0 +1,181 @@
+package mongo
+
+import (
+	"context"
+	"time"
+
+	"github.com/rinosukmandityo/user-profile/helper"
+	m "github.com/rinosukmandityo/user-profile/models"
+	repo "github.com/rinosukmandityo/user-profile/repositories"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type userMongoRepository struct {
+	client   *mongo.Client
+	database string
+	timeout  time.Duration
+}
+
+func (r *userMongoRepository) collection() *mongo.Collection {
+	return r.client.Database(r.database).Collection(new(m.User).TableName())
+}
+
+func (r *userMongoRepository) createIndexes() error {
+	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+	defer cancel()
+	if e := createIndexes(ctx, r.collection(), []mongo.IndexModel{
+		uniqueIndex(bson.D{{Key: "Email", Value: 1}}),
+	}); e != nil {
+		return errors.Wrap(e, "repository.User.CreateIndexes")
+	}
+	return nil
+}
+
+func NewUserRepository(client *mongo.Client, DB string, timeout int) (repo.UserRepository, error) {
+	repo := &userMongoRepository{
+		client:   client,
+		database: DB,
+		timeout:  time.Duration(timeout) * time.Second,
+	}
+	if e := repo.createIndexes(); e != nil {
+		return nil, e
+	}
+	return repo, nil
+}
+
+func (r *userMongoRepository) GetBy(ctx context.Context, filter map[string]interface{}) (m.User, error) {
+	return r.getBy(ctx, repo.NotDeleted(filter))
+}
+
+// getBy doesn't skip soft deleted users, Update uses it to read back any row it changed.
+func (r *userMongoRepository) getBy(ctx context.Context, filter map[string]interface{}) (m.User, error) {
+	if e := repo.CheckColumns(new(m.User).TableName(), filter); e != nil {
+		return m.User{}, errors.Wrap(e, "repository.User.GetBy")
+	}
+	res := m.User{}
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	if e := r.collection().FindOne(ctx, constructUserFilter(filter)).Decode(&res); e != nil {
+		if e == mongo.ErrNoDocuments {
+			return res, errors.Wrap(helper.ErrUserNotFound, "repository.User.GetBy")
+		}
+		return res, errors.Wrap(e, "repository.User.GetBy")
+	}
+
+	return res, nil
+}
+
+func (r *userMongoRepository) Store(ctx context.Context, data *m.User) error {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	if _, e := r.collection().InsertOne(ctx, data); e != nil {
+		switch duplicateKey(e) {
+		case "ID":
+			return errors.Wrap(repo.ErrDuplicateEntry(data.ID, "ID"), "repository.User.Store")
+		case "Email":
+			return errors.Wrap(repo.ErrDuplicateEntry(data.Email, "Email"), "repository.User.Store")
+		}
+		return errors.Wrap(e, "repository.User.Store")
+	}
+
+	return nil
+}
+
+func (r *userMongoRepository) Update(ctx context.Context, data map[string]interface{}, id string) (m.User, error) {
+	if e := repo.CheckColumns(new(m.User).TableName(), data); e != nil {
+		return m.User{}, errors.Wrap(e, "repository.User.Update")
+	}
+	user := m.User{}
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	filter := map[string]interface{}{"ID": id}
+	res, e := r.collection().UpdateOne(ctx, constructUserFilter(filter), constructUserUpdate(data))
+	if e != nil {
+		return user, errors.Wrap(e, "repository.User.Update")
+	}
+	if res.ModifiedCount == 0 {
+		return user, errors.Wrap(helper.ErrUserNotFound, "repository.User.Update")
+	}
+	user, e = r.getBy(ctx, filter)
+	if e != nil {
+		return user, errors.Wrap(e, "repository.User.Update")
+	}
+
+	return user, nil
+}
+
+func (r *userMongoRepository) List(ctx context.Context, query m.UserListQuery) (m.UserPage, error) {
+	query, cursor, e := repo.PrepareList(query)
+	if e != nil {
+		return m.UserPage{}, errors.Wrap(e, "repository.User.List")
+	}
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	total, e := r.collection().CountDocuments(ctx, constructListFilter(query, nil))
+	if e != nil {
+		return m.UserPage{}, errors.Wrap(e, "repository.User.List")
+	}
+	results, e := r.collection().Find(ctx, constructListFilter(query, cursor), constructListOptions(query))
+	if e != nil {
+		return m.UserPage{}, errors.Wrap(e, "repository.User.List")
+	}
+	users := []m.User{}
+	if e := results.All(ctx, &users); e != nil {
+		return m.UserPage{}, errors.Wrap(e, "repository.User.List")
+	}
+
+	return repo.NewPage(users, query, int(total)), nil
+}
+
+func (r *userMongoRepository) Purge(ctx context.Context, deletedBefore time.Time) ([]m.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	filter := bson.M{"IsDeleted": true, "Deleted": bson.M{"$lt": deletedBefore}}
+	results, e := r.collection().Find(ctx, filter)
+	if e != nil {
+		return nil, errors.Wrap(e, "repository.User.Purge")
+	}
+	candidates := []m.User{}
+	if e := results.All(ctx, &candidates); e != nil {
+		return nil, errors.Wrap(e, "repository.User.Purge")
+	}
+
+	// every user is deleted only while it is still deleted, so a user restored
+	// since it was listed is left alone
+	users := []m.User{}
+	for _, user := range candidates {
+		filter["_id"] = user.ID
+		res, e := r.collection().DeleteOne(ctx, filter)
+		if e != nil {
+			return nil, errors.Wrap(e, "repository.User.Purge")
+		}
+		if res.DeletedCount > 0 {
+			users = append(users, user)
+		}
+	}
+
+	return users, nil
+}
+
+func (r *userMongoRepository) DeleteAll(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	res, e := r.collection().DeleteMany(ctx, bson.M{})
+	if e != nil {
+		return errors.Wrap(e, "repository.User.DeleteAll")
+	}
+	if res.DeletedCount == 0 {
+		return errors.Wrap(helper.ErrUserNotFound, "repository.User.DeleteAll")
+	}
+
+	return nil
+}
@@ -0,0 +1,30 @@
+package mysql
+
+import (
+	"database/sql"
+	"strings"
+
+	repo "github.com/rinosukmandityo/user-profile/repositories"
+
+	_ "github.com/go-sql-driver/mysql"
+)
+
+// NewClient opens the connection pool shared by all MySQL repositories.
+func NewClient(URL string, pool repo.PoolConfig) (*sql.DB, error) {
+	if strings.Contains(URL, "?") {
+		URL += "&parseTime=true"
+	} else {
+		URL += "?parseTime=true"
+	}
+	db, e := sql.Open("mysql", URL)
+	if e != nil {
+		return nil, e
+	}
+	pool.Apply(db)
+	if e = db.Ping(); e != nil {
+		db.Close()
+		return nil, e
+	}
+
+	return db, nil
+}
@@ -0,0 +1,131 @@
+package mysql
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rinosukmandityo/user-profile/helper"
+	m "github.com/rinosukmandityo/user-profile/models"
+	repo "github.com/rinosukmandityo/user-profile/repositories"
+
+	"database/sql"
+	"github.com/pkg/errors"
+)
+
+type loginAttemptMySQLRepository struct {
+	db      *sql.DB
+	timeout time.Duration
+}
+
+func NewLoginAttemptRepository(db *sql.DB, timeout int) (repo.LoginAttemptRepository, error) {
+	repo := &loginAttemptMySQLRepository{
+		db:      db,
+		timeout: time.Duration(timeout) * time.Second,
+	}
+	return repo, nil
+}
+
+func (r *loginAttemptMySQLRepository) GetBy(ctx context.Context, id string) (m.LoginAttempt, error) {
+	res := m.LoginAttempt{}
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	q, dataFields := constructLoginAttemptGetBy(id)
+	if e := repo.Conn(ctx, r.db).QueryRowContext(ctx, q, dataFields...).Scan(&res.ID, &res.Failures, &res.LastFailed, &res.LockedUntil); e != nil {
+		if e == sql.ErrNoRows {
+			return res, errors.Wrap(helper.ErrAttemptNotFound, "repository.LoginAttempt.GetBy")
+		}
+		return res, errors.Wrap(e, "repository.LoginAttempt.GetBy")
+	}
+
+	return res, nil
+}
+
+func (r *loginAttemptMySQLRepository) Save(ctx context.Context, data *m.LoginAttempt) error {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	q, dataFields := constructLoginAttemptSaveQuery(data)
+	if _, e := repo.Conn(ctx, r.db).ExecContext(ctx, q, dataFields...); e != nil {
+		return errors.Wrap(e, "repository.LoginAttempt.Save")
+	}
+
+	return nil
+}
+
+func (r *loginAttemptMySQLRepository) Increment(ctx context.Context, id string, now, resetBefore time.Time) (m.LoginAttempt, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	q, dataFields := constructLoginAttemptIncrementQuery(id, now, resetBefore)
+	if _, e := repo.Conn(ctx, r.db).ExecContext(ctx, q, dataFields...); e != nil {
+		return m.LoginAttempt{}, errors.Wrap(e, "repository.LoginAttempt.Increment")
+	}
+	res, e := r.GetBy(ctx, id)
+	if e != nil {
+		return res, errors.Wrap(e, "repository.LoginAttempt.Increment")
+	}
+
+	return res, nil
+}
+
+func (r *loginAttemptMySQLRepository) Lock(ctx context.Context, id string, lockedUntil time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	q, dataFields := constructLoginAttemptLockQuery(id, lockedUntil)
+	if _, e := repo.Conn(ctx, r.db).ExecContext(ctx, q, dataFields...); e != nil {
+		return errors.Wrap(e, "repository.LoginAttempt.Lock")
+	}
+
+	return nil
+}
+
+func (r *loginAttemptMySQLRepository) Delete(ctx context.Context, id string) error {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	q, dataFields := constructLoginAttemptDeleteQuery(id)
+	if _, e := repo.Conn(ctx, r.db).ExecContext(ctx, q, dataFields...); e != nil {
+		return errors.Wrap(e, "repository.LoginAttempt.Delete")
+	}
+
+	return nil
+}
+
+func (r *loginAttemptMySQLRepository) Purge(ctx context.Context, lockedBefore time.Time) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	q, dataFields := constructLoginAttemptPurgeQuery(lockedBefore)
+	res, e := repo.Conn(ctx, r.db).ExecContext(ctx, q, dataFields...)
+	if e != nil {
+		return 0, errors.Wrap(e, "repository.LoginAttempt.Purge")
+	}
+	count, e := res.RowsAffected()
+	if e != nil {
+		return 0, errors.Wrap(e, "repository.LoginAttempt.Purge")
+	}
+
+	return count, nil
+}
+
+func (r *loginAttemptMySQLRepository) DeleteAll(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	res, e := repo.Conn(ctx, r.db).ExecContext(ctx, fmt.Sprintf("DELETE FROM %s ", new(m.LoginAttempt).TableName()))
+	if e != nil {
+		return errors.Wrap(e, "repository.LoginAttempt.DeleteAll")
+	}
+	count, e := res.RowsAffected()
+	if e != nil {
+		return errors.Wrap(e, "repository.LoginAttempt.DeleteAll")
+	}
+	if count == 0 {
+		return errors.Wrap(helper.ErrAttemptNotFound, "repository.LoginAttempt.DeleteAll")
+	}
+
+	return nil
+}
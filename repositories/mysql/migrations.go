@@ -0,0 +1,161 @@
+package mysql
+
+import (
+	"database/sql"
+
+	"github.com/rinosukmandityo/user-profile/repositories/migration"
+	sc "github.com/rinosukmandityo/user-profile/repositories/schema"
+)
+
+// migrations must only be appended to, an applied migration is never edited.
+var migrations = []migration.Migration{
+	{
+		Version: 1,
+		Name:    "create_tables",
+		Up:      []string{sc.Users, sc.Sessions, sc.Tokens},
+		Down:    []string{"DROP TABLE tokens", "DROP TABLE sessions", "DROP TABLE users"},
+	},
+	{
+		Version: 2,
+		Name:    "widen_user_columns",
+		Up: []string{
+			"ALTER TABLE users MODIFY Name VARCHAR(100)",
+			"ALTER TABLE users MODIFY Address VARCHAR(255)",
+		},
+		Down: []string{
+			"ALTER TABLE users MODIFY Name VARCHAR(30)",
+			"ALTER TABLE users MODIFY Address VARCHAR(50)",
+		},
+	},
+	{
+		Version: 3,
+		Name:    "add_lookup_indexes",
+		Up: []string{
+			"CREATE INDEX idx_users_email ON users (Email)",
+			"CREATE INDEX idx_sessions_userid ON sessions (UserID)",
+			"CREATE INDEX idx_sessions_email ON sessions (Email)",
+			"CREATE INDEX idx_tokens_userid_expired ON tokens (UserID, Expired)",
+		},
+		Down: []string{
+			"DROP INDEX idx_tokens_userid_expired ON tokens",
+			"DROP INDEX idx_sessions_email ON sessions",
+			"DROP INDEX idx_sessions_userid ON sessions",
+			"DROP INDEX idx_users_email ON users",
+		},
+	},
+	{
+		Version: 4,
+		Name:    "soft_delete_users",
+		Up: []string{
+			"ALTER TABLE users ADD COLUMN IsDeleted boolean NOT NULL DEFAULT FALSE",
+			"ALTER TABLE users ADD COLUMN Deleted TIMESTAMP NULL DEFAULT NULL",
+			"CREATE INDEX idx_users_deleted ON users (IsDeleted, Deleted)",
+		},
+		Down: []string{
+			"DROP INDEX idx_users_deleted ON users",
+			"ALTER TABLE users DROP COLUMN Deleted",
+			"ALTER TABLE users DROP COLUMN IsDeleted",
+		},
+	},
+	{
+		// bcrypt and argon2id hashes don't fit the old MD5 sized column
+		Version: 5,
+		Name:    "widen_password_column",
+		Up:      []string{"ALTER TABLE users MODIFY Password VARCHAR(255)"},
+		Down:    []string{"ALTER TABLE users MODIFY Password VARCHAR(50)"},
+	},
+	{
+		Version: 6,
+		Name:    "create_login_attempts",
+		Up: []string{
+			sc.LoginAttempts,
+			"CREATE INDEX idx_login_attempts_lockeduntil ON login_attempts (LockedUntil)",
+		},
+		Down: []string{"DROP TABLE login_attempts"},
+	},
+	{
+		Version: 7,
+		Name:    "add_two_factor",
+		Up: []string{
+			"ALTER TABLE sessions ADD COLUMN IsPending boolean NOT NULL DEFAULT FALSE",
+			sc.TwoFactors,
+		},
+		Down: []string{
+			"DROP TABLE two_factors",
+			"ALTER TABLE sessions DROP COLUMN IsPending",
+		},
+	},
+	{
+		// accounts created before email verification are treated as verified,
+		// Down leaves them active
+		Version: 8,
+		Name:    "verify_email",
+		Up: []string{
+			"ALTER TABLE tokens ADD COLUMN Purpose VARCHAR(20) NOT NULL DEFAULT 'reset'",
+			"UPDATE users SET IsActive=TRUE",
+		},
+		Down: []string{"ALTER TABLE tokens DROP COLUMN Purpose"},
+	},
+	{
+		// session and token IDs are 256 bit secrets now, user IDs are ULIDs which fit already
+		Version: 9,
+		Name:    "widen_id_columns",
+		Up: []string{
+			"ALTER TABLE sessions MODIFY ID VARCHAR(64) NOT NULL",
+			"ALTER TABLE tokens MODIFY ID VARCHAR(64) NOT NULL",
+		},
+		Down: []string{
+			"ALTER TABLE tokens MODIFY ID VARCHAR(30) NOT NULL",
+			"ALTER TABLE sessions MODIFY ID VARCHAR(30) NOT NULL",
+		},
+	},
+	{
+		// sessions which existed before are treated as last seen when they were created
+		Version: 10,
+		Name:    "add_session_devices",
+		Up: []string{
+			"ALTER TABLE sessions ADD COLUMN IP VARCHAR(45) NOT NULL DEFAULT ''",
+			"ALTER TABLE sessions ADD COLUMN UserAgent VARCHAR(255) NOT NULL DEFAULT ''",
+			"ALTER TABLE sessions ADD COLUMN LastSeen TIMESTAMP NULL DEFAULT NULL",
+			"UPDATE sessions SET LastSeen=Created",
+		},
+		Down: []string{
+			"ALTER TABLE sessions DROP COLUMN LastSeen",
+			"ALTER TABLE sessions DROP COLUMN UserAgent",
+			"ALTER TABLE sessions DROP COLUMN IP",
+		},
+	},
+	{
+		Version: 11,
+		Name:    "remember_sessions",
+		Up:      []string{"ALTER TABLE sessions ADD COLUMN IsRemembered boolean NOT NULL DEFAULT FALSE"},
+		Down:    []string{"ALTER TABLE sessions DROP COLUMN IsRemembered"},
+	},
+	{
+		// without explicit_defaults_for_timestamp the first TIMESTAMP column of a table
+		// is set to the current time on every update, Created has to keep the time the
+		// session or token was made. Rows updated before this keep their moved Created.
+		Version: 12,
+		Name:    "fix_created_columns",
+		Up: []string{
+			"ALTER TABLE sessions MODIFY Created TIMESTAMP NULL DEFAULT NULL",
+			"ALTER TABLE tokens MODIFY Created TIMESTAMP NULL DEFAULT NULL",
+		},
+		Down: []string{
+			"ALTER TABLE tokens MODIFY Created TIMESTAMP",
+			"ALTER TABLE sessions MODIFY Created TIMESTAMP",
+		},
+	},
+	{
+		// sessions which existed before have to pass the second factor again for the
+		// routes which need it
+		Version: 13,
+		Name:    "add_session_two_factor",
+		Up:      []string{"ALTER TABLE sessions ADD COLUMN IsTwoFactor boolean NOT NULL DEFAULT FALSE"},
+		Down:    []string{"ALTER TABLE sessions DROP COLUMN IsTwoFactor"},
+	},
+}
+
+func NewMigrator(db *sql.DB) *migration.Migrator {
+	return migration.New(db, migrations, migration.QuestionMark, migration.GetLock)
+}
@@ -0,0 +1,103 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+
+	m "github.com/rinosukmandityo/user-profile/models"
+	repo "github.com/rinosukmandityo/user-profile/repositories"
+)
+
+// The *Unpooled benchmarks open a new client for every call the way the
+// repositories used to, so both variants can be compared with
+//   go test -run=^$ -bench=. ./repositories/mysql
+
+var benchUser = m.User{
+	ID:       "benchuser01",
+	Name:     "Bench User 01",
+	Email:    "benchmail01@gmail.com",
+	Password: benchPassword(),
+}
+
+func benchPassword() string {
+	password, _ := repo.EncryptPassword("Password.1")
+	return password
+}
+
+func benchmarkURL() string {
+	if url := os.Getenv("url"); url != "" {
+		return url
+	}
+	return "user:Password.1@tcp(127.0.0.1:3306)/users"
+}
+
+func setupBenchmark(b *testing.B) *sql.DB {
+	db, e := NewClient(benchmarkURL(), repo.PoolConfig{MaxOpenConns: 25, MaxIdleConns: 25})
+	if e != nil {
+		b.Skipf("mysql is not reachable: %s", e.Error())
+	}
+	userRepo, _ := NewUserRepository(db, 10)
+	NewSessionRepository(db, 10)
+	userRepo.Store(context.Background(), &benchUser)
+	b.Cleanup(func() {
+		db.Exec("DELETE FROM users WHERE ID=?", benchUser.ID)
+		db.Close()
+	})
+	return db
+}
+
+func BenchmarkGetBy(b *testing.B) {
+	db := setupBenchmark(b)
+	userRepo, _ := NewUserRepository(db, 10)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, e := userRepo.GetBy(context.Background(), map[string]interface{}{"ID": benchUser.ID}); e != nil {
+			b.Fatal(e)
+		}
+	}
+}
+
+func BenchmarkGetByUnpooled(b *testing.B) {
+	setupBenchmark(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		db, e := NewClient(benchmarkURL(), repo.PoolConfig{})
+		if e != nil {
+			b.Fatal(e)
+		}
+		userRepo := &userMySQLRepository{db: db}
+		if _, e := userRepo.GetBy(context.Background(), map[string]interface{}{"ID": benchUser.ID}); e != nil {
+			b.Fatal(e)
+		}
+		db.Close()
+	}
+}
+
+func BenchmarkAuthenticate(b *testing.B) {
+	db := setupBenchmark(b)
+	sessionRepo, _ := NewSessionRepository(db, 10)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, _, e := sessionRepo.Authenticate(context.Background(), benchUser.Email, "Password.1"); e != nil {
+			b.Fatal(e)
+		}
+	}
+}
+
+func BenchmarkAuthenticateUnpooled(b *testing.B) {
+	setupBenchmark(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		db, e := NewClient(benchmarkURL(), repo.PoolConfig{})
+		if e != nil {
+			b.Fatal(e)
+		}
+		sessionRepo := &sessionMySQLRepository{db: db}
+		if _, _, e := sessionRepo.Authenticate(context.Background(), benchUser.Email, "Password.1"); e != nil {
+			b.Fatal(e)
+		}
+		db.Close()
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	m "github.com/rinosukmandityo/user-profile/models"
+	repo "github.com/rinosukmandityo/user-profile/repositories"
 )
 
 func constructUpdateQuery(data, filter map[string]interface{}) (string, []interface{}) {
@@ -18,10 +19,10 @@ func constructUpdateQuery(data, filter map[string]interface{}) (string, []interf
 	q = strings.TrimSuffix(q, ",")
 	q += " WHERE"
 	for k, v := range filter {
-		q += fmt.Sprintf(" %s=?,", k)
+		q += fmt.Sprintf(" %s=? AND", k)
 		values = append(values, v)
 	}
-	q = strings.TrimSuffix(q, ",")
+	q = strings.TrimSuffix(q, " AND")
 
 	return q, values
 }
@@ -31,14 +32,28 @@ func constructDeleteQuery(filter map[string]interface{}) (string, []interface{})
 	q := fmt.Sprintf("DELETE FROM %s WHERE", new(m.User).TableName())
 	values := []interface{}{}
 	for k, v := range filter {
-		q += fmt.Sprintf(" %s=?,", k)
+		q += fmt.Sprintf(" %s=? AND", k)
 		values = append(values, v)
 	}
-	q = strings.TrimSuffix(q, ",")
+	q = strings.TrimSuffix(q, " AND")
 
 	return q, values
 }
 
+func constructPurgeListQuery(deletedBefore interface{}) (string, []interface{}) {
+	// 	"SELECT * FROM <tablename> WHERE IsDeleted=? AND Deleted<?"
+	q := fmt.Sprintf("SELECT * FROM %s WHERE IsDeleted=? AND Deleted<?", new(m.User).TableName())
+
+	return q, []interface{}{true, deletedBefore}
+}
+
+func constructPurgeQuery(id string, deletedBefore interface{}) (string, []interface{}) {
+	// 	"DELETE FROM <tablename> WHERE ID=? AND IsDeleted=? AND Deleted<?"
+	q := fmt.Sprintf("DELETE FROM %s WHERE ID=? AND IsDeleted=? AND Deleted<?", new(m.User).TableName())
+
+	return q, []interface{}{id, true, deletedBefore}
+}
+
 func constructStoreQuery(data *m.User) (string, []interface{}) {
 	// 	"INSERT INTO <tablename> VALUES(?, ?, ?, ?)"
 	dataFields := data.SplitByField()
@@ -64,14 +79,62 @@ func constructGetBy(filter map[string]interface{}) (string, []interface{}) {
 	q := fmt.Sprintf("SELECT * FROM %s WHERE", new(m.User).TableName())
 	dataFields := []interface{}{}
 	for k, v := range filter {
-		q += fmt.Sprintf(" %s=?,", k)
+		q += fmt.Sprintf(" %s=? AND", k)
 		dataFields = append(dataFields, v)
 	}
-	q = strings.TrimSuffix(q, ",")
+	q = strings.TrimSuffix(q, " AND")
 
 	return q, dataFields
 }
 
-func constructGetAll() string {
-	return "select * from users"
+func constructListWhere(query m.UserListQuery, cursor *repo.Cursor) (string, []interface{}) {
+	// WHERE IsDeleted=? AND LOWER(Email) LIKE ? ESCAPE '!' AND (Name>? OR (Name=? AND ID>?))
+	q := " WHERE IsDeleted=?"
+	values := []interface{}{false}
+	if query.IsActive != nil {
+		q += " AND IsActive=?"
+		values = append(values, *query.IsActive)
+	}
+	if query.IsGoogleAuth != nil {
+		q += " AND IsGoogleAuth=?"
+		values = append(values, *query.IsGoogleAuth)
+	}
+	if query.EmailDomain != "" {
+		q += " AND LOWER(Email) LIKE ? ESCAPE '!'"
+		values = append(values, "%@"+repo.EscapeLike(query.EmailDomain))
+	}
+	if query.NamePrefix != "" {
+		q += " AND LOWER(Name) LIKE ? ESCAPE '!'"
+		values = append(values, repo.EscapeLike(query.NamePrefix)+"%")
+	}
+	if cursor != nil {
+		op := ">"
+		if query.Desc {
+			op = "<"
+		}
+		q += fmt.Sprintf(" AND (%s%s? OR (%s=? AND ID%s?))", query.SortBy, op, query.SortBy, op)
+		values = append(values, cursor.Value, cursor.Value, cursor.ID)
+	}
+
+	return q, values
+}
+
+func constructListQuery(query m.UserListQuery, cursor *repo.Cursor) (string, []interface{}) {
+	// SELECT * FROM <tablename> WHERE ... ORDER BY Name ASC, ID ASC LIMIT 21
+	where, values := constructListWhere(query, cursor)
+	order := "ASC"
+	if query.Desc {
+		order = "DESC"
+	}
+	q := fmt.Sprintf("SELECT * FROM %s%s ORDER BY %s %s, ID %s LIMIT %d", new(m.User).TableName(), where, query.SortBy, order, order, query.Limit+1)
+
+	return q, values
+}
+
+func constructCountQuery(query m.UserListQuery) (string, []interface{}) {
+	// SELECT COUNT(*) FROM <tablename> WHERE ...
+	where, values := constructListWhere(query, nil)
+	q := fmt.Sprintf("SELECT COUNT(*) FROM %s%s", new(m.User).TableName(), where)
+
+	return q, values
 }
@@ -0,0 +1,60 @@
+package mysql
+
+import (
+	"fmt"
+	"strings"
+
+	m "github.com/rinosukmandityo/user-profile/models"
+)
+
+func constructLoginAttemptGetBy(id string) (string, []interface{}) {
+	// SELECT * FROM <tablename> WHERE ID=?
+	q := fmt.Sprintf("SELECT * FROM %s WHERE ID=?", new(m.LoginAttempt).TableName())
+
+	return q, []interface{}{id}
+}
+
+func constructLoginAttemptSaveQuery(data *m.LoginAttempt) (string, []interface{}) {
+	// 	"INSERT INTO <tablename> VALUES(?, ?) ON DUPLICATE KEY UPDATE field2=VALUES(field2)"
+	dataFields := data.SplitByField()
+	q := fmt.Sprintf("INSERT INTO %s VALUES(", data.TableName())
+	values := []interface{}{}
+	for _, v := range dataFields {
+		q += "?,"
+		values = append(values, v)
+	}
+	q = strings.TrimSuffix(q, ",") + ")"
+	q += " ON DUPLICATE KEY UPDATE Failures=VALUES(Failures), LastFailed=VALUES(LastFailed), LockedUntil=VALUES(LockedUntil)"
+
+	return q, values
+}
+
+func constructLoginAttemptIncrementQuery(id string, now, resetBefore interface{}) (string, []interface{}) {
+	// 	"INSERT INTO <tablename> VALUES(?, 1, ?, ?) ON DUPLICATE KEY UPDATE Failures=IF(LastFailed<?, 1, Failures+1), LastFailed=VALUES(LastFailed)"
+	// Failures is assigned first so it compares the LastFailed from before this failure
+	q := fmt.Sprintf("INSERT INTO %s VALUES(?, 1, ?, ?) ON DUPLICATE KEY UPDATE Failures=IF(LastFailed<?, 1, Failures+1), LastFailed=VALUES(LastFailed)",
+		new(m.LoginAttempt).TableName())
+
+	return q, []interface{}{id, now, now, resetBefore}
+}
+
+func constructLoginAttemptLockQuery(id string, lockedUntil interface{}) (string, []interface{}) {
+	// 	"UPDATE <tablename> SET LockedUntil=? WHERE ID=? AND LockedUntil<?"
+	q := fmt.Sprintf("UPDATE %s SET LockedUntil=? WHERE ID=? AND LockedUntil<?", new(m.LoginAttempt).TableName())
+
+	return q, []interface{}{lockedUntil, id, lockedUntil}
+}
+
+func constructLoginAttemptDeleteQuery(id string) (string, []interface{}) {
+	// 	"DELETE FROM <tablename> WHERE ID=?"
+	q := fmt.Sprintf("DELETE FROM %s WHERE ID=?", new(m.LoginAttempt).TableName())
+
+	return q, []interface{}{id}
+}
+
+func constructLoginAttemptPurgeQuery(lockedBefore interface{}) (string, []interface{}) {
+	// 	"DELETE FROM <tablename> WHERE LockedUntil<?"
+	q := fmt.Sprintf("DELETE FROM %s WHERE LockedUntil<?", new(m.LoginAttempt).TableName())
+
+	return q, []interface{}{lockedBefore}
+}
@@ -18,10 +18,10 @@ func constructSessionUpdateQuery(data, filter map[string]interface{}) (string, [
 	q = strings.TrimSuffix(q, ",")
 	q += " WHERE"
 	for k, v := range filter {
-		q += fmt.Sprintf(" %s=?,", k)
+		q += fmt.Sprintf(" %s=? AND", k)
 		values = append(values, v)
 	}
-	q = strings.TrimSuffix(q, ",")
+	q = strings.TrimSuffix(q, " AND")
 
 	return q, values
 }
@@ -31,10 +31,10 @@ func constructSessionDeleteQuery(filter map[string]interface{}) (string, []inter
 	q := fmt.Sprintf("DELETE FROM %s WHERE", new(m.Session).TableName())
 	values := []interface{}{}
 	for k, v := range filter {
-		q += fmt.Sprintf(" %s=?,", k)
+		q += fmt.Sprintf(" %s=? AND", k)
 		values = append(values, v)
 	}
-	q = strings.TrimSuffix(q, ",")
+	q = strings.TrimSuffix(q, " AND")
 
 	return q, values
 }
@@ -58,26 +58,10 @@ func constructSessionGetBy(filter map[string]interface{}) (string, []interface{}
 	q := fmt.Sprintf("SELECT * FROM %s WHERE", new(m.Session).TableName())
 	dataFields := []interface{}{}
 	for k, v := range filter {
-		q += fmt.Sprintf(" %s=?,", k)
+		q += fmt.Sprintf(" %s=? AND", k)
 		dataFields = append(dataFields, v)
 	}
-	q = strings.TrimSuffix(q, ",")
+	q = strings.TrimSuffix(q, " AND")
 
 	return q, dataFields
 }
-
-func constructAuth(filter map[string]interface{}) (string, []interface{}) {
-	q := fmt.Sprintf("SELECT * FROM %s WHERE", new(m.User).TableName())
-	count := 0
-	dataFields := []interface{}{}
-	for k, v := range filter {
-		if count == 0 {
-			q += fmt.Sprintf(" %s=?", k)
-		} else {
-			q += fmt.Sprintf(" OR %s=?", k)
-		}
-		dataFields = append(dataFields, v)
-		count++
-	}
-	return q, dataFields
-}
@@ -18,10 +18,23 @@ func constructTokenUpdateQuery(data, filter map[string]interface{}) (string, []i
 	q = strings.TrimSuffix(q, ",")
 	q += " WHERE"
 	for k, v := range filter {
-		q += fmt.Sprintf(" %s=?,", k)
+		q += fmt.Sprintf(" %s=? AND", k)
 		values = append(values, v)
 	}
-	q = strings.TrimSuffix(q, ",")
+	q = strings.TrimSuffix(q, " AND")
+
+	return q, values
+}
+
+func constructTokenDeleteQuery(filter map[string]interface{}) (string, []interface{}) {
+	// 	"DELETE <tablename> WHERE filter1=?"
+	q := fmt.Sprintf("DELETE FROM %s WHERE", new(m.Token).TableName())
+	values := []interface{}{}
+	for k, v := range filter {
+		q += fmt.Sprintf(" %s=? AND", k)
+		values = append(values, v)
+	}
+	q = strings.TrimSuffix(q, " AND")
 
 	return q, values
 }
@@ -45,10 +58,10 @@ func constructTokenGetBy(filter map[string]interface{}) (string, []interface{})
 	q := fmt.Sprintf("SELECT * FROM %s WHERE", new(m.Token).TableName())
 	dataFields := []interface{}{}
 	for k, v := range filter {
-		q += fmt.Sprintf(" %s=?,", k)
+		q += fmt.Sprintf(" %s=? AND", k)
 		dataFields = append(dataFields, v)
 	}
-	q = strings.TrimSuffix(q, ",")
+	q = strings.TrimSuffix(q, " AND")
 
 	return q, dataFields
 }
@@ -58,10 +71,10 @@ func constructTokenGetLatest(filter map[string]interface{}) (string, []interface
 	q := fmt.Sprintf("SELECT * FROM %s WHERE", new(m.Token).TableName())
 	dataFields := []interface{}{}
 	for k, v := range filter {
-		q += fmt.Sprintf(" %s=?,", k)
+		q += fmt.Sprintf(" %s=? AND", k)
 		dataFields = append(dataFields, v)
 	}
-	q = strings.TrimSuffix(q, ",")
+	q = strings.TrimSuffix(q, " AND")
 	q += " ORDER BY Expired DESC"
 
 	return q, dataFields
@@ -0,0 +1,44 @@
+package mysql
+
+import (
+	"fmt"
+	"strings"
+
+	m "github.com/rinosukmandityo/user-profile/models"
+)
+
+func constructTwoFactorGetBy(id string) (string, []interface{}) {
+	// SELECT * FROM <tablename> WHERE ID=?
+	q := fmt.Sprintf("SELECT * FROM %s WHERE ID=?", new(m.TwoFactor).TableName())
+
+	return q, []interface{}{id}
+}
+
+func constructTwoFactorSaveQuery(data *m.TwoFactor) (string, []interface{}) {
+	// 	"INSERT INTO <tablename> VALUES(?, ?) ON DUPLICATE KEY UPDATE field2=VALUES(field2)"
+	dataFields := data.SplitByField()
+	q := fmt.Sprintf("INSERT INTO %s VALUES(", data.TableName())
+	values := []interface{}{}
+	for _, v := range dataFields {
+		q += "?,"
+		values = append(values, v)
+	}
+	q = strings.TrimSuffix(q, ",") + ")"
+	q += " ON DUPLICATE KEY UPDATE Secret=VALUES(Secret), IsEnabled=VALUES(IsEnabled), RecoveryCodes=VALUES(RecoveryCodes), LastStep=VALUES(LastStep), Created=VALUES(Created)"
+
+	return q, values
+}
+
+func constructTwoFactorUpdateCodesQuery(prev m.TwoFactor, lastStep int, recoveryCodes string) (string, []interface{}) {
+	// 	"UPDATE <tablename> SET LastStep=?, RecoveryCodes=? WHERE ID=? AND LastStep=? AND RecoveryCodes=?"
+	q := fmt.Sprintf("UPDATE %s SET LastStep=?, RecoveryCodes=? WHERE ID=? AND LastStep=? AND RecoveryCodes=?", prev.TableName())
+
+	return q, []interface{}{lastStep, recoveryCodes, prev.ID, prev.LastStep, prev.RecoveryCodes}
+}
+
+func constructTwoFactorDeleteQuery(id string) (string, []interface{}) {
+	// 	"DELETE FROM <tablename> WHERE ID=?"
+	q := fmt.Sprintf("DELETE FROM %s WHERE ID=?", new(m.TwoFactor).TableName())
+
+	return q, []interface{}{id}
+}
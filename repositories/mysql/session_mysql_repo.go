@@ -10,69 +10,37 @@ import (
 	repo "github.com/rinosukmandityo/user-profile/repositories"
 
 	"database/sql"
-	_ "github.com/go-sql-driver/mysql"
 	"github.com/pkg/errors"
 )
 
 type sessionMySQLRepository struct {
-	url     string
+	db      *sql.DB
 	timeout time.Duration
 }
 
-func newSessionClient(URL string) (*sql.DB, error) {
-	db, e := sql.Open("mysql", URL)
-	if e != nil {
-		return nil, e
-	}
-	if e = db.Ping(); e != nil {
-		return nil, e
-	}
-
-	return db, e
-}
-
-func (r *sessionMySQLRepository) createNewTable() error {
-	tablename := new(m.Session).TableName()
-	schema := `CREATE TABLE ` + tablename + ` (
-		ID VARCHAR(30) NOT NULL UNIQUE,
-		UserID VARCHAR(30),
-		Email VARCHAR(50),
-		Created TIMESTAMP,
-		Expired TIMESTAMP DEFAULT '1970-01-01 00:00:01'
-	);`
-	db, e := newSessionClient(r.url)
-
-	if e != nil {
-		return errors.Wrap(e, "repository.Session.CreateTable")
-	}
-	defer db.Close()
-	res, e := db.Exec(schema)
-
-	if res != nil && e == nil {
-		fmt.Printf("Table '%s' created\n", tablename)
-	}
-	return nil
-}
-
-func NewSessionRepository(URL, DB string, timeout int) (repo.SessionRepository, error) {
+func NewSessionRepository(db *sql.DB, timeout int) (repo.SessionRepository, error) {
 	repo := &sessionMySQLRepository{
-		url:     fmt.Sprintf("%s?parseTime=true", URL),
+		db:      db,
 		timeout: time.Duration(timeout) * time.Second,
 	}
-	repo.createNewTable()
 	return repo, nil
 }
 
-func (r *sessionMySQLRepository) GetBy(filter map[string]interface{}) (m.Session, error) {
-	res := m.Session{}
-	db, e := newSessionClient(r.url)
-	if e != nil {
-		return res, errors.Wrap(e, "repository.Session.GetBy")
+func scanSession(row interface{ Scan(...interface{}) error }, res *m.Session) error {
+	return row.Scan(&res.ID, &res.UserID, &res.Email, &res.Created, &res.Expired, &res.IsPending, &res.IP, &res.UserAgent, &res.LastSeen, &res.IsRemembered, &res.IsTwoFactor)
+}
+
+func (r *sessionMySQLRepository) GetBy(ctx context.Context, filter map[string]interface{}) (m.Session, error) {
+	if e := repo.CheckColumns(new(m.Session).TableName(), filter); e != nil {
+		return m.Session{}, errors.Wrap(e, "repository.Session.GetBy")
 	}
-	defer db.Close()
+	res := m.Session{}
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
 	q, dataFields := constructSessionGetBy(filter)
 
-	if e = db.QueryRow(q, dataFields...).Scan(&res.ID, &res.UserID, &res.Email, &res.Created, &res.Expired); e != nil {
+	if e := scanSession(repo.Conn(ctx, r.db).QueryRowContext(ctx, q, dataFields...), &res); e != nil {
 		if e == sql.ErrNoRows {
 			return res, errors.Wrap(helper.ErrUserNotFound, "repository.Session.GetBy")
 		}
@@ -82,26 +50,17 @@ func (r *sessionMySQLRepository) GetBy(filter map[string]interface{}) (m.Session
 	return res, nil
 
 }
-func (r *sessionMySQLRepository) Store(data *m.Session) error {
-	db, e := newSessionClient(r.url)
-	if e != nil {
-		return errors.Wrap(e, "repository.Session.Store")
-	}
-	defer db.Close()
-	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+func (r *sessionMySQLRepository) Store(ctx context.Context, data *m.Session) error {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
 	defer cancel()
-	conn, e := db.Conn(ctx)
-	if e != nil {
-		return errors.Wrap(e, "repository.Session.Store")
-	}
 
 	q, dataField := constructSessionStoreQuery(data)
-	stmt, e := conn.PrepareContext(ctx, q)
+	stmt, e := repo.Conn(ctx, r.db).PrepareContext(ctx, q)
 	if e != nil {
 		return errors.Wrap(e, "repository.Session.Store")
 	}
 	defer stmt.Close()
-	if _, e := stmt.Exec(dataField...); e != nil {
+	if _, e := stmt.ExecContext(ctx, dataField...); e != nil {
 		return errors.Wrap(e, "repository.Session.Store")
 	}
 
@@ -109,30 +68,23 @@ func (r *sessionMySQLRepository) Store(data *m.Session) error {
 
 }
 
-func (r *sessionMySQLRepository) Update(data map[string]interface{}, id string) (m.Session, error) {
+func (r *sessionMySQLRepository) Update(ctx context.Context, data map[string]interface{}, id string) (m.Session, error) {
+	if e := repo.CheckColumns(new(m.Session).TableName(), data); e != nil {
+		return m.Session{}, errors.Wrap(e, "repository.Session.Update")
+	}
 	user := m.Session{}
 	var e error
-	db, e := newSessionClient(r.url)
-	if e != nil {
-		return user, errors.Wrap(e, "repository.Session.Update")
-	}
-	defer db.Close()
-	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
 	defer cancel()
-	conn, e := db.Conn(ctx)
-	if e != nil {
-		return user, errors.Wrap(e, "repository.Session.Update")
-	}
-	defer conn.Close()
 
 	filter := map[string]interface{}{"ID": id}
 	q, dataField := constructSessionUpdateQuery(data, filter)
-	stmt, e := conn.PrepareContext(ctx, q)
+	stmt, e := repo.Conn(ctx, r.db).PrepareContext(ctx, q)
 	if e != nil {
 		return user, errors.Wrap(e, "repository.Session.Update")
 	}
 	defer stmt.Close()
-	if res, e := stmt.Exec(dataField...); e != nil {
+	if res, e := stmt.ExecContext(ctx, dataField...); e != nil {
 		return user, errors.Wrap(e, "repository.Session.Update")
 	} else {
 		count, e := res.RowsAffected()
@@ -143,7 +95,7 @@ func (r *sessionMySQLRepository) Update(data map[string]interface{}, id string)
 			return user, errors.Wrap(helper.ErrUserNotFound, "repository.Session.Update")
 		}
 	}
-	user, e = r.GetBy(filter)
+	user, e = r.GetBy(ctx, filter)
 	if e != nil {
 		return user, errors.Wrap(e, "repository.Session.Update")
 	}
@@ -152,16 +104,59 @@ func (r *sessionMySQLRepository) Update(data map[string]interface{}, id string)
 
 }
 
-func (r *sessionMySQLRepository) Authenticate(email, password string) (bool, m.User, error) {
-	res := m.User{}
-	db, e := newUserClient(r.url)
+func (r *sessionMySQLRepository) Touch(ctx context.Context, sess m.Session, lastSeen, expired time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	q, dataFields := constructSessionUpdateQuery(map[string]interface{}{"LastSeen": lastSeen, "Expired": expired}, map[string]interface{}{"ID": sess.ID, "Expired": sess.Expired, "IsPending": sess.IsPending})
+	res, e := repo.Conn(ctx, r.db).ExecContext(ctx, q, dataFields...)
 	if e != nil {
-		return false, res, errors.Wrap(e, "repository.Session.Authenticate")
+		return errors.Wrap(e, "repository.Session.Touch")
+	}
+	count, e := res.RowsAffected()
+	if e != nil {
+		return errors.Wrap(e, "repository.Session.Touch")
+	}
+	if count == 0 {
+		return errors.Wrap(helper.ErrSessionNotFound, "repository.Session.Touch")
+	}
+
+	return nil
+}
+
+func (r *sessionMySQLRepository) ListByUser(ctx context.Context, userID string) ([]m.Session, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	q, dataFields := constructSessionGetBy(map[string]interface{}{"UserID": userID})
+	results, e := repo.Conn(ctx, r.db).QueryContext(ctx, q, dataFields...)
+	if e != nil {
+		return nil, errors.Wrap(e, "repository.Session.ListByUser")
+	}
+	defer results.Close()
+	sessions := []m.Session{}
+	for results.Next() {
+		var item m.Session
+		if e := scanSession(results, &item); e != nil {
+			return nil, errors.Wrap(e, "repository.Session.ListByUser")
+		}
+		sessions = append(sessions, item)
+	}
+	if e := results.Err(); e != nil {
+		return nil, errors.Wrap(e, "repository.Session.ListByUser")
 	}
-	defer db.Close()
-	q, dataFields := constructAuth(map[string]interface{}{"Email": email})
 
-	if e = db.QueryRow(q, dataFields...).Scan(&res.ID, &res.Name, &res.Email, &res.Password, &res.Telephone, &res.Address, &res.IsActive, &res.IsGoogleAuth); e != nil {
+	return sessions, nil
+}
+
+func (r *sessionMySQLRepository) Authenticate(ctx context.Context, email, password string) (bool, m.User, error) {
+	res := m.User{}
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	q, dataFields := constructGetBy(repo.NotDeleted(map[string]interface{}{"Email": email}))
+
+	if e := scanUser(repo.Conn(ctx, r.db).QueryRowContext(ctx, q, dataFields...), &res); e != nil {
 		if e == sql.ErrNoRows {
 			return false, res, errors.Wrap(helper.ErrUserNotFound, "repository.Session.Authenticate")
 		}
@@ -179,26 +174,28 @@ func (r *sessionMySQLRepository) Authenticate(email, password string) (bool, m.U
 	return true, res, nil
 }
 
-func (r *sessionMySQLRepository) DeleteAll() error {
-	db, e := newSessionClient(r.url)
-	if e != nil {
-		return errors.Wrap(e, "repository.Session.DeleteAll")
-	}
-	defer db.Close()
-	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+func (r *sessionMySQLRepository) DeleteByUser(ctx context.Context, userID string) error {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
 	defer cancel()
-	conn, e := db.Conn(ctx)
-	if e != nil {
-		return errors.Wrap(e, "repository.Session.DeleteAll")
+
+	q, dataFields := constructSessionDeleteQuery(map[string]interface{}{"UserID": userID})
+	if _, e := repo.Conn(ctx, r.db).ExecContext(ctx, q, dataFields...); e != nil {
+		return errors.Wrap(e, "repository.Session.DeleteByUser")
 	}
-	defer conn.Close()
 
-	stmt, e := conn.PrepareContext(ctx, fmt.Sprintf("DELETE FROM %s ", new(m.Session).TableName()))
+	return nil
+}
+
+func (r *sessionMySQLRepository) DeleteAll(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	stmt, e := repo.Conn(ctx, r.db).PrepareContext(ctx, fmt.Sprintf("DELETE FROM %s ", new(m.Session).TableName()))
 	if e != nil {
 		return errors.Wrap(e, "repository.Session.DeleteAll")
 	}
 	defer stmt.Close()
-	if res, e := stmt.Exec(); e != nil {
+	if res, e := stmt.ExecContext(ctx); e != nil {
 		return errors.Wrap(e, "repository.Session.DeleteAll")
 	} else {
 		count, e := res.RowsAffected()
@@ -10,76 +10,38 @@ import (
 	repo "github.com/rinosukmandityo/user-profile/repositories"
 
 	"database/sql"
-	_ "github.com/go-sql-driver/mysql"
 	"github.com/pkg/errors"
 )
 
 type tokenMySQLRepository struct {
-	url     string
+	db      *sql.DB
 	timeout time.Duration
 }
 
-func newTokenClient(URL string) (*sql.DB, error) {
-	db, e := sql.Open("mysql", URL)
-	if e != nil {
-		return nil, e
-	}
-	if e = db.Ping(); e != nil {
-		return nil, e
-	}
-
-	return db, e
-}
-
-func (r *tokenMySQLRepository) createNewTable() error {
-	tablename := new(m.Token).TableName()
-	schema := `CREATE TABLE ` + tablename + ` (
-		ID VARCHAR(30) NOT NULL UNIQUE,
-		UserID VARCHAR(30),
-		Created TIMESTAMP,
-		Expired TIMESTAMP  DEFAULT '1970-01-01 00:00:01',
-		IsClaimed boolean
-	);`
-
-	db, e := newTokenClient(r.url)
-
-	if e != nil {
-		return errors.Wrap(e, "repository.Token.CreateTable")
-	}
-	defer db.Close()
-	res, e := db.Exec(schema)
-	if res != nil && e == nil {
-		fmt.Printf("Table '%s' created\n", tablename)
-	}
-	return nil
-}
-
-func NewTokenRepository(URL, DB string, timeout int) (repo.TokenRepository, error) {
+func NewTokenRepository(db *sql.DB, timeout int) (repo.TokenRepository, error) {
 	repo := &tokenMySQLRepository{
-		url:     fmt.Sprintf("%s?parseTime=true", URL),
+		db:      db,
 		timeout: time.Duration(timeout) * time.Second,
 	}
-	repo.createNewTable()
 	return repo, nil
 }
 
-func (r *tokenMySQLRepository) GetLatestToken(userid string) (m.Token, error) {
+func (r *tokenMySQLRepository) GetLatestToken(ctx context.Context, userid, purpose string) (m.Token, error) {
 	res := []m.Token{}
 	token := m.Token{}
-	db, e := newTokenClient(r.url)
-	if e != nil {
-		return token, errors.Wrap(e, "repository.Token.GetLatestToken")
-	}
-	defer db.Close()
-	q, dataFields := constructTokenGetLatest(map[string]interface{}{"UserID": userid})
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
 
-	results, e := db.Query(q, dataFields...)
+	q, dataFields := constructTokenGetLatest(map[string]interface{}{"UserID": userid, "Purpose": purpose})
+
+	results, e := repo.Conn(ctx, r.db).QueryContext(ctx, q, dataFields...)
 	if e != nil {
 		return token, errors.Wrap(e, "repository.Token.GetLatestToken")
 	}
+	defer results.Close()
 	for results.Next() {
 		var item m.Token
-		if e := results.Scan(&item.ID, &item.UserID, &item.Created, &item.Expired, &item.IsClaimed); e != nil {
+		if e := results.Scan(&item.ID, &item.UserID, &item.Created, &item.Expired, &item.IsClaimed, &item.Purpose); e != nil {
 			return token, errors.Wrap(e, "repository.Token.GetLatestToken")
 		}
 		res = append(res, item)
@@ -92,26 +54,17 @@ func (r *tokenMySQLRepository) GetLatestToken(userid string) (m.Token, error) {
 	return token, nil
 
 }
-func (r *tokenMySQLRepository) Store(data *m.Token) error {
-	db, e := newTokenClient(r.url)
-	if e != nil {
-		return errors.Wrap(e, "repository.Token.Store")
-	}
-	defer db.Close()
-	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+func (r *tokenMySQLRepository) Store(ctx context.Context, data *m.Token) error {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
 	defer cancel()
-	conn, e := db.Conn(ctx)
-	if e != nil {
-		return errors.Wrap(e, "repository.Token.Store")
-	}
 
 	q, dataField := constructTokenStoreQuery(data)
-	stmt, e := conn.PrepareContext(ctx, q)
+	stmt, e := repo.Conn(ctx, r.db).PrepareContext(ctx, q)
 	if e != nil {
 		return errors.Wrap(e, "repository.Token.Store")
 	}
 	defer stmt.Close()
-	if _, e := stmt.Exec(dataField...); e != nil {
+	if _, e := stmt.ExecContext(ctx, dataField...); e != nil {
 		return errors.Wrap(e, "repository.Token.Store")
 	}
 
@@ -119,29 +72,21 @@ func (r *tokenMySQLRepository) Store(data *m.Token) error {
 
 }
 
-func (r *tokenMySQLRepository) Update(data map[string]interface{}, id string) error {
-	var e error
-	db, e := newTokenClient(r.url)
-	if e != nil {
+func (r *tokenMySQLRepository) Update(ctx context.Context, data map[string]interface{}, id string) error {
+	if e := repo.CheckColumns(new(m.Token).TableName(), data); e != nil {
 		return errors.Wrap(e, "repository.Token.Update")
 	}
-	defer db.Close()
-	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
 	defer cancel()
-	conn, e := db.Conn(ctx)
-	if e != nil {
-		return errors.Wrap(e, "repository.Token.Update")
-	}
-	defer conn.Close()
 
 	filter := map[string]interface{}{"ID": id}
 	q, dataField := constructTokenUpdateQuery(data, filter)
-	stmt, e := conn.PrepareContext(ctx, q)
+	stmt, e := repo.Conn(ctx, r.db).PrepareContext(ctx, q)
 	if e != nil {
 		return errors.Wrap(e, "repository.Token.Update")
 	}
 	defer stmt.Close()
-	if res, e := stmt.Exec(dataField...); e != nil {
+	if res, e := stmt.ExecContext(ctx, dataField...); e != nil {
 		return errors.Wrap(e, "repository.Token.Update")
 	} else {
 		count, e := res.RowsAffected()
@@ -157,26 +102,48 @@ func (r *tokenMySQLRepository) Update(data map[string]interface{}, id string) er
 
 }
 
-func (r *tokenMySQLRepository) DeleteAll() error {
-	db, e := newTokenClient(r.url)
+func (r *tokenMySQLRepository) Claim(ctx context.Context, id string) error {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	q, dataFields := constructTokenUpdateQuery(map[string]interface{}{"IsClaimed": true}, map[string]interface{}{"ID": id, "IsClaimed": false})
+	res, e := repo.Conn(ctx, r.db).ExecContext(ctx, q, dataFields...)
 	if e != nil {
-		return errors.Wrap(e, "repository.Token.DeleteAll")
+		return errors.Wrap(e, "repository.Token.Claim")
 	}
-	defer db.Close()
-	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
-	defer cancel()
-	conn, e := db.Conn(ctx)
+	count, e := res.RowsAffected()
 	if e != nil {
-		return errors.Wrap(e, "repository.Token.DeleteAll")
+		return errors.Wrap(e, "repository.Token.Claim")
+	}
+	if count == 0 {
+		return errors.Wrap(helper.ErrTokenNotFound, "repository.Token.Claim")
 	}
-	defer conn.Close()
 
-	stmt, e := conn.PrepareContext(ctx, fmt.Sprintf("DELETE FROM %s ", new(m.Token).TableName()))
+	return nil
+}
+
+func (r *tokenMySQLRepository) DeleteByUser(ctx context.Context, userID string) error {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	q, dataFields := constructTokenDeleteQuery(map[string]interface{}{"UserID": userID})
+	if _, e := repo.Conn(ctx, r.db).ExecContext(ctx, q, dataFields...); e != nil {
+		return errors.Wrap(e, "repository.Token.DeleteByUser")
+	}
+
+	return nil
+}
+
+func (r *tokenMySQLRepository) DeleteAll(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	stmt, e := repo.Conn(ctx, r.db).PrepareContext(ctx, fmt.Sprintf("DELETE FROM %s ", new(m.Token).TableName()))
 	if e != nil {
 		return errors.Wrap(e, "repository.Token.DeleteAll")
 	}
 	defer stmt.Close()
-	if res, e := stmt.Exec(); e != nil {
+	if res, e := stmt.ExecContext(ctx); e != nil {
 		return errors.Wrap(e, "repository.Token.DeleteAll")
 	} else {
 		count, e := res.RowsAffected()
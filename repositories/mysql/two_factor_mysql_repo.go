@@ -0,0 +1,106 @@
+package mysql
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rinosukmandityo/user-profile/helper"
+	m "github.com/rinosukmandityo/user-profile/models"
+	repo "github.com/rinosukmandityo/user-profile/repositories"
+
+	"database/sql"
+	"github.com/pkg/errors"
+)
+
+type twoFactorMySQLRepository struct {
+	db      *sql.DB
+	timeout time.Duration
+}
+
+func NewTwoFactorRepository(db *sql.DB, timeout int) (repo.TwoFactorRepository, error) {
+	repo := &twoFactorMySQLRepository{
+		db:      db,
+		timeout: time.Duration(timeout) * time.Second,
+	}
+	return repo, nil
+}
+
+func (r *twoFactorMySQLRepository) GetBy(ctx context.Context, userID string) (m.TwoFactor, error) {
+	res := m.TwoFactor{}
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	q, dataFields := constructTwoFactorGetBy(userID)
+	if e := repo.Conn(ctx, r.db).QueryRowContext(ctx, q, dataFields...).Scan(&res.ID, &res.Secret, &res.IsEnabled, &res.RecoveryCodes, &res.LastStep, &res.Created); e != nil {
+		if e == sql.ErrNoRows {
+			return res, errors.Wrap(helper.ErrTwoFactorNotFound, "repository.TwoFactor.GetBy")
+		}
+		return res, errors.Wrap(e, "repository.TwoFactor.GetBy")
+	}
+
+	return res, nil
+}
+
+func (r *twoFactorMySQLRepository) Save(ctx context.Context, data *m.TwoFactor) error {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	q, dataFields := constructTwoFactorSaveQuery(data)
+	if _, e := repo.Conn(ctx, r.db).ExecContext(ctx, q, dataFields...); e != nil {
+		return errors.Wrap(e, "repository.TwoFactor.Save")
+	}
+
+	return nil
+}
+
+func (r *twoFactorMySQLRepository) UpdateCodes(ctx context.Context, prev m.TwoFactor, lastStep int, recoveryCodes string) error {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	q, dataFields := constructTwoFactorUpdateCodesQuery(prev, lastStep, recoveryCodes)
+	res, e := repo.Conn(ctx, r.db).ExecContext(ctx, q, dataFields...)
+	if e != nil {
+		return errors.Wrap(e, "repository.TwoFactor.UpdateCodes")
+	}
+	count, e := res.RowsAffected()
+	if e != nil {
+		return errors.Wrap(e, "repository.TwoFactor.UpdateCodes")
+	}
+	if count == 0 {
+		return errors.Wrap(helper.ErrTwoFactorNotFound, "repository.TwoFactor.UpdateCodes")
+	}
+
+	return nil
+}
+
+func (r *twoFactorMySQLRepository) Delete(ctx context.Context, userID string) error {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	q, dataFields := constructTwoFactorDeleteQuery(userID)
+	if _, e := repo.Conn(ctx, r.db).ExecContext(ctx, q, dataFields...); e != nil {
+		return errors.Wrap(e, "repository.TwoFactor.Delete")
+	}
+
+	return nil
+}
+
+func (r *twoFactorMySQLRepository) DeleteAll(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	res, e := repo.Conn(ctx, r.db).ExecContext(ctx, fmt.Sprintf("DELETE FROM %s ", new(m.TwoFactor).TableName()))
+	if e != nil {
+		return errors.Wrap(e, "repository.TwoFactor.DeleteAll")
+	}
+	count, e := res.RowsAffected()
+	if e != nil {
+		return errors.Wrap(e, "repository.TwoFactor.DeleteAll")
+	}
+	if count == 0 {
+		return errors.Wrap(helper.ErrTwoFactorNotFound, "repository.TwoFactor.DeleteAll")
+	}
+
+	return nil
+}
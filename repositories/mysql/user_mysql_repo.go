@@ -10,71 +10,42 @@ import (
 	repo "github.com/rinosukmandityo/user-profile/repositories"
 
 	"database/sql"
-	_ "github.com/go-sql-driver/mysql"
 	"github.com/pkg/errors"
 )
 
 type userMySQLRepository struct {
-	url     string
+	db      *sql.DB
 	timeout time.Duration
 }
 
-func newUserClient(URL string) (*sql.DB, error) {
-	db, e := sql.Open("mysql", URL)
-	if e != nil {
-		return nil, e
-	}
-	if e = db.Ping(); e != nil {
-		return nil, e
+func NewUserRepository(db *sql.DB, timeout int) (repo.UserRepository, error) {
+	repo := &userMySQLRepository{
+		db:      db,
+		timeout: time.Duration(timeout) * time.Second,
 	}
-
-	return db, e
+	return repo, nil
 }
 
-func (r *userMySQLRepository) createNewTable() error {
-	tablename := new(m.User).TableName()
-	schema := `CREATE TABLE ` + tablename + ` (
-		ID VARCHAR(30) NOT NULL UNIQUE,
-		Name VARCHAR(30),
-		Email VARCHAR(50),
-		Password VARCHAR(50),
-		Telephone VARCHAR(30),
-		Address VARCHAR(50),
-		IsActive boolean,
-		IsGoogleAuth boolean
-	);`
-	db, e := newUserClient(r.url)
-
-	if e != nil {
-		return errors.Wrap(e, "repository.User.CreateTable")
-	}
-	defer db.Close()
-	res, e := db.Exec(schema)
-	if res != nil && e == nil {
-		fmt.Printf("Table '%s' created\n", tablename)
-	}
-	return nil
+func scanUser(row interface{ Scan(...interface{}) error }, res *m.User) error {
+	return row.Scan(&res.ID, &res.Name, &res.Email, &res.Password, &res.Telephone, &res.Address, &res.IsActive, &res.IsGoogleAuth, &res.IsDeleted, &res.Deleted)
 }
 
-func NewUserRepository(URL, DB string, timeout int) (repo.UserRepository, error) {
-	repo := &userMySQLRepository{
-		url:     fmt.Sprintf("%s?parseTime=true", URL),
-		timeout: time.Duration(timeout) * time.Second,
-	}
-	repo.createNewTable()
-	return repo, nil
+func (r *userMySQLRepository) GetBy(ctx context.Context, filter map[string]interface{}) (m.User, error) {
+	return r.getBy(ctx, repo.NotDeleted(filter))
 }
 
-func (r *userMySQLRepository) GetBy(filter map[string]interface{}) (m.User, error) {
-	res := m.User{}
-	db, e := newUserClient(r.url)
-	if e != nil {
-		return res, errors.Wrap(e, "repository.User.GetBy")
+// getBy doesn't skip soft deleted users, Update uses it to read back any row it changed.
+func (r *userMySQLRepository) getBy(ctx context.Context, filter map[string]interface{}) (m.User, error) {
+	if e := repo.CheckColumns(new(m.User).TableName(), filter); e != nil {
+		return m.User{}, errors.Wrap(e, "repository.User.GetBy")
 	}
-	defer db.Close()
+	res := m.User{}
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
 	q, dataFields := constructGetBy(filter)
 
-	if e = db.QueryRow(q, dataFields...).Scan(&res.ID, &res.Name, &res.Email, &res.Password, &res.Telephone, &res.Address, &res.IsActive, &res.IsGoogleAuth); e != nil {
+	if e := scanUser(repo.Conn(ctx, r.db).QueryRowContext(ctx, q, dataFields...), &res); e != nil {
 		if e == sql.ErrNoRows {
 			return res, errors.Wrap(helper.ErrUserNotFound, "repository.User.GetBy")
 		}
@@ -84,26 +55,17 @@ func (r *userMySQLRepository) GetBy(filter map[string]interface{}) (m.User, erro
 	return res, nil
 
 }
-func (r *userMySQLRepository) Store(data *m.User) error {
-	db, e := newUserClient(r.url)
-	if e != nil {
-		return errors.Wrap(e, "repository.User.Store")
-	}
-	defer db.Close()
-	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+func (r *userMySQLRepository) Store(ctx context.Context, data *m.User) error {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
 	defer cancel()
-	conn, e := db.Conn(ctx)
-	if e != nil {
-		return errors.Wrap(e, "repository.User.Store")
-	}
 
 	q, dataField := constructStoreQuery(data)
-	stmt, e := conn.PrepareContext(ctx, q)
+	stmt, e := repo.Conn(ctx, r.db).PrepareContext(ctx, q)
 	if e != nil {
 		return errors.Wrap(e, "repository.User.Store")
 	}
 	defer stmt.Close()
-	if _, e := stmt.Exec(dataField...); e != nil {
+	if _, e := stmt.ExecContext(ctx, dataField...); e != nil {
 		return errors.Wrap(e, "repository.User.Store")
 	}
 
@@ -111,30 +73,23 @@ func (r *userMySQLRepository) Store(data *m.User) error {
 
 }
 
-func (r *userMySQLRepository) Update(data map[string]interface{}, id string) (m.User, error) {
+func (r *userMySQLRepository) Update(ctx context.Context, data map[string]interface{}, id string) (m.User, error) {
+	if e := repo.CheckColumns(new(m.User).TableName(), data); e != nil {
+		return m.User{}, errors.Wrap(e, "repository.User.Update")
+	}
 	user := m.User{}
 	var e error
-	db, e := newUserClient(r.url)
-	if e != nil {
-		return user, errors.Wrap(e, "repository.User.Update")
-	}
-	defer db.Close()
-	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
 	defer cancel()
-	conn, e := db.Conn(ctx)
-	if e != nil {
-		return user, errors.Wrap(e, "repository.User.Update")
-	}
-	defer conn.Close()
 
 	filter := map[string]interface{}{"ID": id}
 	q, dataField := constructUpdateQuery(data, filter)
-	stmt, e := conn.PrepareContext(ctx, q)
+	stmt, e := repo.Conn(ctx, r.db).PrepareContext(ctx, q)
 	if e != nil {
 		return user, errors.Wrap(e, "repository.User.Update")
 	}
 	defer stmt.Close()
-	if res, e := stmt.Exec(dataField...); e != nil {
+	if res, e := stmt.ExecContext(ctx, dataField...); e != nil {
 		return user, errors.Wrap(e, "repository.User.Update")
 	} else {
 		count, e := res.RowsAffected()
@@ -145,7 +100,7 @@ func (r *userMySQLRepository) Update(data map[string]interface{}, id string) (m.
 			return user, errors.Wrap(helper.ErrUserNotFound, "repository.User.Update")
 		}
 	}
-	user, e = r.GetBy(filter)
+	user, e = r.getBy(ctx, filter)
 	if e != nil {
 		return user, errors.Wrap(e, "repository.User.Update")
 	}
@@ -154,26 +109,95 @@ func (r *userMySQLRepository) Update(data map[string]interface{}, id string) (m.
 
 }
 
-func (r *userMySQLRepository) DeleteAll() error {
-	db, e := newUserClient(r.url)
+func (r *userMySQLRepository) List(ctx context.Context, query m.UserListQuery) (m.UserPage, error) {
+	query, cursor, e := repo.PrepareList(query)
 	if e != nil {
-		return errors.Wrap(e, "repository.User.DeleteAll")
+		return m.UserPage{}, errors.Wrap(e, "repository.User.List")
 	}
-	defer db.Close()
-	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
 	defer cancel()
-	conn, e := db.Conn(ctx)
+
+	total := 0
+	q, dataFields := constructCountQuery(query)
+	if e := repo.Conn(ctx, r.db).QueryRowContext(ctx, q, dataFields...).Scan(&total); e != nil {
+		return m.UserPage{}, errors.Wrap(e, "repository.User.List")
+	}
+
+	q, dataFields = constructListQuery(query, cursor)
+	results, e := repo.Conn(ctx, r.db).QueryContext(ctx, q, dataFields...)
 	if e != nil {
-		return errors.Wrap(e, "repository.User.DeleteAll")
+		return m.UserPage{}, errors.Wrap(e, "repository.User.List")
+	}
+	defer results.Close()
+	users := []m.User{}
+	for results.Next() {
+		var item m.User
+		if e := scanUser(results, &item); e != nil {
+			return m.UserPage{}, errors.Wrap(e, "repository.User.List")
+		}
+		users = append(users, item)
+	}
+	if e := results.Err(); e != nil {
+		return m.UserPage{}, errors.Wrap(e, "repository.User.List")
 	}
-	defer conn.Close()
 
-	stmt, e := conn.PrepareContext(ctx, fmt.Sprintf("DELETE FROM %s ", new(m.User).TableName()))
+	return repo.NewPage(users, query, total), nil
+}
+
+func (r *userMySQLRepository) Purge(ctx context.Context, deletedBefore time.Time) ([]m.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	q, dataFields := constructPurgeListQuery(deletedBefore)
+	results, e := repo.Conn(ctx, r.db).QueryContext(ctx, q, dataFields...)
+	if e != nil {
+		return nil, errors.Wrap(e, "repository.User.Purge")
+	}
+	defer results.Close()
+	candidates := []m.User{}
+	for results.Next() {
+		var item m.User
+		if e := scanUser(results, &item); e != nil {
+			return nil, errors.Wrap(e, "repository.User.Purge")
+		}
+		candidates = append(candidates, item)
+	}
+	if e := results.Err(); e != nil {
+		return nil, errors.Wrap(e, "repository.User.Purge")
+	}
+	results.Close()
+
+	// every user is deleted only while it is still deleted, so a user restored
+	// since it was listed is left alone
+	users := []m.User{}
+	for _, user := range candidates {
+		q, dataFields := constructPurgeQuery(user.ID, deletedBefore)
+		res, e := repo.Conn(ctx, r.db).ExecContext(ctx, q, dataFields...)
+		if e != nil {
+			return nil, errors.Wrap(e, "repository.User.Purge")
+		}
+		count, e := res.RowsAffected()
+		if e != nil {
+			return nil, errors.Wrap(e, "repository.User.Purge")
+		}
+		if count > 0 {
+			users = append(users, user)
+		}
+	}
+
+	return users, nil
+}
+
+func (r *userMySQLRepository) DeleteAll(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	stmt, e := repo.Conn(ctx, r.db).PrepareContext(ctx, fmt.Sprintf("DELETE FROM %s ", new(m.User).TableName()))
 	if e != nil {
 		return errors.Wrap(e, "repository.User.DeleteAll")
 	}
 	defer stmt.Close()
-	if res, e := stmt.Exec(); e != nil {
+	if res, e := stmt.ExecContext(ctx); e != nil {
 		return errors.Wrap(e, "repository.User.DeleteAll")
 	} else {
 		count, e := res.RowsAffected()
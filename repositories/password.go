@@ -2,19 +2,173 @@ package repositories
 
 import (
 	"crypto/md5"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
 	"fmt"
-	"io"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Hasher turns passwords into self-describing hashes, the algorithm and its
+// parameters are part of the stored string.
+type Hasher interface {
+	Hash(password string) (string, error)
+	// Verify reports whether password matches encoded, ok is false when
+	// encoded was not produced by this kind of hasher.
+	Verify(password, encoded string) (match, ok bool)
+	// NeedsRehash reports whether encoded was not produced by this hasher with
+	// its current parameters.
+	NeedsRehash(encoded string) bool
+}
+
+var (
+	hasherMu sync.RWMutex
+	hasher   Hasher = BcryptHasher{Cost: bcrypt.DefaultCost}
+	// verifiers recognize every format that may still be stored, md5 is only
+	// kept so that old hashes verify once before they are upgraded.
+	verifiers = []Hasher{BcryptHasher{}, Argon2idHasher{}, md5Hasher{}}
 )
 
+// SetHasher sets the hasher used for new passwords.
+func SetHasher(h Hasher) {
+	hasherMu.Lock()
+	defer hasherMu.Unlock()
+	hasher = h
+}
+
+func currentHasher() Hasher {
+	hasherMu.RLock()
+	defer hasherMu.RUnlock()
+	return hasher
+}
+
 func IsPasswordMatch(password, userPass string) bool {
-	ePassword := EncryptPassword(password)
-	return ePassword == userPass
+	for _, v := range verifiers {
+		if match, ok := v.Verify(password, userPass); ok {
+			return match
+		}
+	}
+	return false
+}
+
+func EncryptPassword(password string) (string, error) {
+	return currentHasher().Hash(password)
+}
+
+// NeedsRehash reports whether a stored hash should be replaced by a hash of
+// the current hasher, e.g. after a successful login.
+func NeedsRehash(userPass string) bool {
+	return currentHasher().NeedsRehash(userPass)
+}
+
+type BcryptHasher struct {
+	Cost int
+}
+
+func (h BcryptHasher) Hash(password string) (string, error) {
+	b, e := bcrypt.GenerateFromPassword([]byte(password), h.Cost)
+	return string(b), e
+}
+
+func (h BcryptHasher) Verify(password, encoded string) (bool, bool) {
+	if !strings.HasPrefix(encoded, "$2") {
+		return false, false
+	}
+	return bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password)) == nil, true
 }
 
-func EncryptPassword(password string) string {
-	tPass := md5.New()
-	io.WriteString(tPass, password)
-	ePassword := fmt.Sprintf("%x", tPass.Sum(nil))
+func (h BcryptHasher) NeedsRehash(encoded string) bool {
+	cost, e := bcrypt.Cost([]byte(encoded))
+	return e != nil || cost != h.Cost
+}
+
+// Argon2idHasher stores hashes in the PHC string format,
+// $argon2id$v=19$m=<memory>,t=<time>,p=<threads>$<salt>$<key>
+type Argon2idHasher struct {
+	Time    uint32
+	Memory  uint32 // KiB
+	Threads uint8
+	KeyLen  uint32
+	SaltLen uint32
+}
+
+type argon2idParams struct {
+	memory, time uint32
+	threads      uint8
+	salt, key    []byte
+}
+
+func (h Argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, h.SaltLen)
+	if _, e := rand.Read(salt); e != nil {
+		return "", e
+	}
+	key := argon2.IDKey([]byte(password), salt, h.Time, h.Memory, h.Threads, h.KeyLen)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, h.Memory, h.Time, h.Threads,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+func (h Argon2idHasher) Verify(password, encoded string) (bool, bool) {
+	if !strings.HasPrefix(encoded, "$argon2id$") {
+		return false, false
+	}
+	p, e := parseArgon2id(encoded)
+	if e != nil {
+		return false, true
+	}
+	key := argon2.IDKey([]byte(password), p.salt, p.time, p.memory, p.threads, uint32(len(p.key)))
+	return subtle.ConstantTimeCompare(key, p.key) == 1, true
+}
+
+func (h Argon2idHasher) NeedsRehash(encoded string) bool {
+	p, e := parseArgon2id(encoded)
+	return e != nil || p.memory != h.Memory || p.time != h.Time || p.threads != h.Threads ||
+		uint32(len(p.salt)) != h.SaltLen || uint32(len(p.key)) != h.KeyLen
+}
+
+func parseArgon2id(encoded string) (p argon2idParams, e error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return p, fmt.Errorf("invalid argon2id hash")
+	}
+	var version int
+	if _, e = fmt.Sscanf(parts[2], "v=%d", &version); e != nil {
+		return
+	}
+	if version != argon2.Version {
+		return p, fmt.Errorf("unsupported argon2id version %d", version)
+	}
+	if _, e = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.memory, &p.time, &p.threads); e != nil {
+		return
+	}
+	if p.salt, e = base64.RawStdEncoding.DecodeString(parts[4]); e != nil {
+		return
+	}
+	p.key, e = base64.RawStdEncoding.DecodeString(parts[5])
+	return
+}
+
+// md5Hasher is the legacy unsalted MD5 hex format, it only verifies.
+type md5Hasher struct{}
+
+func (md5Hasher) Hash(password string) (string, error) {
+	sum := md5.Sum([]byte(password))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func (h md5Hasher) Verify(password, encoded string) (bool, bool) {
+	if _, e := hex.DecodeString(encoded); e != nil || len(encoded) != md5.Size*2 {
+		return false, false
+	}
+	ePassword, _ := h.Hash(password)
+	return subtle.ConstantTimeCompare([]byte(ePassword), []byte(encoded)) == 1, true
+}
 
-	return ePassword
+func (md5Hasher) NeedsRehash(string) bool {
+	return true
 }
@@ -0,0 +1,85 @@
+package repositories
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPasswordHasher(t *testing.T) {
+	testTable := []struct {
+		name   string
+		hasher Hasher
+		prefix string
+	}{
+		{
+			name:   "bcrypt",
+			hasher: BcryptHasher{Cost: 4},
+			prefix: "$2a$04$",
+		},
+		{
+			name:   "argon2id",
+			hasher: Argon2idHasher{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32, SaltLen: 16},
+			prefix: "$argon2id$v=19$m=1024,t=1,p=1$",
+		},
+	}
+
+	for _, _tt := range testTable {
+		tt := _tt
+		t.Run(tt.name, func(t *testing.T) {
+			hash, e := tt.hasher.Hash("Password.1")
+			if e != nil {
+				t.Fatalf("unable to hash password: %s", e.Error())
+			}
+			if !strings.HasPrefix(hash, tt.prefix) {
+				t.Errorf("hash should describe itself with %s, got %s", tt.prefix, hash)
+			}
+			if other, _ := tt.hasher.Hash("Password.1"); other == hash {
+				t.Errorf("hashes of the same password should use different salts")
+			}
+			if !IsPasswordMatch("Password.1", hash) {
+				t.Errorf("password should match its hash")
+			}
+			if IsPasswordMatch("Password.2", hash) {
+				t.Errorf("another password should not match")
+			}
+			if tt.hasher.NeedsRehash(hash) {
+				t.Errorf("hash of the current hasher should not need a rehash")
+			}
+		})
+	}
+}
+
+func TestPasswordNeedsRehash(t *testing.T) {
+	defer SetHasher(currentHasher())
+	SetHasher(BcryptHasher{Cost: 5})
+
+	legacy, _ := md5Hasher{}.Hash("Password.1")
+	if legacy != "a8b6bb3b34339f5a6d439a0ef7fc7878" {
+		t.Errorf("legacy hash is not correct, got %s", legacy)
+	}
+	if !IsPasswordMatch("Password.1", legacy) {
+		t.Errorf("legacy md5 hash should still verify")
+	}
+	oldCost, _ := BcryptHasher{Cost: 4}.Hash("Password.1")
+	current, _ := EncryptPassword("Password.1")
+	argon, _ := Argon2idHasher{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32, SaltLen: 16}.Hash("Password.1")
+
+	testTable := []struct {
+		name     string
+		hash     string
+		expected bool
+	}{
+		{name: "legacy_md5", hash: legacy, expected: true},
+		{name: "other_cost", hash: oldCost, expected: true},
+		{name: "other_algorithm", hash: argon, expected: true},
+		{name: "current", hash: current, expected: false},
+	}
+	for _, tt := range testTable {
+		if actual := NeedsRehash(tt.hash); actual != tt.expected {
+			t.Errorf("%s: want needs rehash %v, got %v", tt.name, tt.expected, actual)
+		}
+	}
+	if IsPasswordMatch("Password.1", "not a hash") {
+		t.Errorf("unknown formats should never match")
+	}
+}
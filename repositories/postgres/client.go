@@ -0,0 +1,31 @@
+package postgres
+
+import (
+	"database/sql"
+
+	repo "github.com/rinosukmandityo/user-profile/repositories"
+
+	"github.com/lib/pq"
+)
+
+const uniqueViolation = "23505"
+
+// NewClient opens the connection pool shared by all PostgreSQL repositories.
+func NewClient(URL string, pool repo.PoolConfig) (*sql.DB, error) {
+	db, e := sql.Open("postgres", URL)
+	if e != nil {
+		return nil, e
+	}
+	pool.Apply(db)
+	if e = db.Ping(); e != nil {
+		db.Close()
+		return nil, e
+	}
+
+	return db, nil
+}
+
+func isDuplicateEntry(e error) bool {
+	pqErr, ok := e.(*pq.Error)
+	return ok && pqErr.Code == uniqueViolation
+}
@@ -0,0 +1,137 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/rinosukmandityo/user-profile/helper"
+	m "github.com/rinosukmandityo/user-profile/models"
+	repo "github.com/rinosukmandityo/user-profile/repositories"
+
+	"database/sql"
+	_ "github.com/lib/pq"
+	"github.com/pkg/errors"
+)
+
+type loginAttemptPostgresRepository struct {
+	db      *sql.DB
+	timeout time.Duration
+}
+
+func NewLoginAttemptRepository(db *sql.DB, timeout int) (repo.LoginAttemptRepository, error) {
+	repo := &loginAttemptPostgresRepository{
+		db:      db,
+		timeout: time.Duration(timeout) * time.Second,
+	}
+	return repo, nil
+}
+
+func scanLoginAttempt(row interface{ Scan(...interface{}) error }, res *m.LoginAttempt) error {
+	if e := row.Scan(&res.ID, &res.Failures, &res.LastFailed, &res.LockedUntil); e != nil {
+		return e
+	}
+	res.LastFailed = res.LastFailed.UTC()
+	res.LockedUntil = res.LockedUntil.UTC()
+	return nil
+}
+
+func (r *loginAttemptPostgresRepository) GetBy(ctx context.Context, id string) (m.LoginAttempt, error) {
+	res := m.LoginAttempt{}
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	q, dataFields := constructGetBy(res.TableName(), map[string]interface{}{"ID": id})
+	if e := scanLoginAttempt(repo.Conn(ctx, r.db).QueryRowContext(ctx, q, dataFields...), &res); e != nil {
+		if e == sql.ErrNoRows {
+			return m.LoginAttempt{}, errors.Wrap(helper.ErrAttemptNotFound, "repository.LoginAttempt.GetBy")
+		}
+		return m.LoginAttempt{}, errors.Wrap(e, "repository.LoginAttempt.GetBy")
+	}
+
+	return res, nil
+}
+
+func (r *loginAttemptPostgresRepository) Save(ctx context.Context, data *m.LoginAttempt) error {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	q, dataFields := constructUpsertQuery(data.TableName(), data.SplitByField(), []string{"Failures", "LastFailed", "LockedUntil"})
+	if _, e := repo.Conn(ctx, r.db).ExecContext(ctx, q, dataFields...); e != nil {
+		return errors.Wrap(e, "repository.LoginAttempt.Save")
+	}
+
+	return nil
+}
+
+func (r *loginAttemptPostgresRepository) Increment(ctx context.Context, id string, now, resetBefore time.Time) (m.LoginAttempt, error) {
+	res := m.LoginAttempt{}
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	q, dataFields := constructLoginAttemptIncrement(res.TableName(), id, now, resetBefore)
+	if e := scanLoginAttempt(repo.Conn(ctx, r.db).QueryRowContext(ctx, q, dataFields...), &res); e != nil {
+		return m.LoginAttempt{}, errors.Wrap(e, "repository.LoginAttempt.Increment")
+	}
+
+	return res, nil
+}
+
+func (r *loginAttemptPostgresRepository) Lock(ctx context.Context, id string, lockedUntil time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	q, dataFields := constructLoginAttemptLock(new(m.LoginAttempt).TableName(), id, lockedUntil)
+	if _, e := repo.Conn(ctx, r.db).ExecContext(ctx, q, dataFields...); e != nil {
+		return errors.Wrap(e, "repository.LoginAttempt.Lock")
+	}
+
+	return nil
+}
+
+func (r *loginAttemptPostgresRepository) Delete(ctx context.Context, id string) error {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	q, dataFields := constructDelete(new(m.LoginAttempt).TableName(), map[string]interface{}{"ID": id})
+	if _, e := repo.Conn(ctx, r.db).ExecContext(ctx, q, dataFields...); e != nil {
+		return errors.Wrap(e, "repository.LoginAttempt.Delete")
+	}
+
+	return nil
+}
+
+func (r *loginAttemptPostgresRepository) Purge(ctx context.Context, lockedBefore time.Time) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	q, dataFields := constructLoginAttemptPurge(new(m.LoginAttempt).TableName(), lockedBefore)
+	res, e := repo.Conn(ctx, r.db).ExecContext(ctx, q, dataFields...)
+	if e != nil {
+		return 0, errors.Wrap(e, "repository.LoginAttempt.Purge")
+	}
+	count, e := res.RowsAffected()
+	if e != nil {
+		return 0, errors.Wrap(e, "repository.LoginAttempt.Purge")
+	}
+
+	return count, nil
+}
+
+func (r *loginAttemptPostgresRepository) DeleteAll(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	res, e := repo.Conn(ctx, r.db).ExecContext(ctx, constructDeleteAll(new(m.LoginAttempt).TableName()))
+	if e != nil {
+		return errors.Wrap(e, "repository.LoginAttempt.DeleteAll")
+	}
+	count, e := res.RowsAffected()
+	if e != nil {
+		return errors.Wrap(e, "repository.LoginAttempt.DeleteAll")
+	}
+	if count == 0 {
+		return errors.Wrap(helper.ErrAttemptNotFound, "repository.LoginAttempt.DeleteAll")
+	}
+
+	return nil
+}
@@ -0,0 +1,188 @@
+package postgres
+
+import (
+	"database/sql"
+
+	"github.com/rinosukmandityo/user-profile/repositories/migration"
+)
+
+// migrations must only be appended to, an applied migration is never edited.
+// Versions are kept in line with the MySQL migrations.
+var migrations = []migration.Migration{
+	{
+		Version: 1,
+		Name:    "create_tables",
+		Up: []string{
+			`CREATE TABLE IF NOT EXISTS users (
+				"ID" VARCHAR(30) NOT NULL UNIQUE,
+				"Name" VARCHAR(30),
+				"Email" VARCHAR(50),
+				"Password" VARCHAR(50),
+				"Telephone" VARCHAR(30),
+				"Address" VARCHAR(50),
+				"IsActive" BOOLEAN,
+				"IsGoogleAuth" BOOLEAN
+			)`,
+			`CREATE TABLE IF NOT EXISTS sessions (
+				"ID" VARCHAR(30) NOT NULL UNIQUE,
+				"UserID" VARCHAR(30),
+				"Email" VARCHAR(50),
+				"Created" TIMESTAMPTZ,
+				"Expired" TIMESTAMPTZ DEFAULT 'epoch'
+			)`,
+			`CREATE TABLE IF NOT EXISTS tokens (
+				"ID" VARCHAR(30) NOT NULL UNIQUE,
+				"UserID" VARCHAR(30),
+				"Created" TIMESTAMPTZ,
+				"Expired" TIMESTAMPTZ DEFAULT 'epoch',
+				"IsClaimed" BOOLEAN
+			)`,
+		},
+		Down: []string{"DROP TABLE tokens", "DROP TABLE sessions", "DROP TABLE users"},
+	},
+	{
+		Version: 2,
+		Name:    "widen_user_columns",
+		Up: []string{
+			`ALTER TABLE users ALTER COLUMN "Name" TYPE VARCHAR(100)`,
+			`ALTER TABLE users ALTER COLUMN "Address" TYPE VARCHAR(255)`,
+		},
+		Down: []string{
+			`ALTER TABLE users ALTER COLUMN "Name" TYPE VARCHAR(30)`,
+			`ALTER TABLE users ALTER COLUMN "Address" TYPE VARCHAR(50)`,
+		},
+	},
+	{
+		Version: 3,
+		Name:    "add_lookup_indexes",
+		Up: []string{
+			`CREATE INDEX idx_users_email ON users ("Email")`,
+			`CREATE INDEX idx_sessions_userid ON sessions ("UserID")`,
+			`CREATE INDEX idx_sessions_email ON sessions ("Email")`,
+			`CREATE INDEX idx_tokens_userid_expired ON tokens ("UserID", "Expired")`,
+		},
+		Down: []string{
+			"DROP INDEX idx_tokens_userid_expired",
+			"DROP INDEX idx_sessions_email",
+			"DROP INDEX idx_sessions_userid",
+			"DROP INDEX idx_users_email",
+		},
+	},
+	{
+		Version: 4,
+		Name:    "soft_delete_users",
+		Up: []string{
+			`ALTER TABLE users ADD COLUMN "IsDeleted" BOOLEAN NOT NULL DEFAULT FALSE`,
+			`ALTER TABLE users ADD COLUMN "Deleted" TIMESTAMPTZ`,
+			`CREATE INDEX idx_users_deleted ON users ("IsDeleted", "Deleted")`,
+		},
+		Down: []string{
+			"DROP INDEX idx_users_deleted",
+			`ALTER TABLE users DROP COLUMN "Deleted"`,
+			`ALTER TABLE users DROP COLUMN "IsDeleted"`,
+		},
+	},
+	{
+		// bcrypt and argon2id hashes don't fit the old MD5 sized column
+		Version: 5,
+		Name:    "widen_password_column",
+		Up:      []string{`ALTER TABLE users ALTER COLUMN "Password" TYPE VARCHAR(255)`},
+		Down:    []string{`ALTER TABLE users ALTER COLUMN "Password" TYPE VARCHAR(50)`},
+	},
+	{
+		Version: 6,
+		Name:    "create_login_attempts",
+		Up: []string{
+			`CREATE TABLE IF NOT EXISTS login_attempts (
+				"ID" VARCHAR(255) NOT NULL UNIQUE,
+				"Failures" INTEGER NOT NULL DEFAULT 0,
+				"LastFailed" TIMESTAMPTZ,
+				"LockedUntil" TIMESTAMPTZ
+			)`,
+			`CREATE INDEX idx_login_attempts_lockeduntil ON login_attempts ("LockedUntil")`,
+		},
+		Down: []string{"DROP TABLE login_attempts"},
+	},
+	{
+		Version: 7,
+		Name:    "add_two_factor",
+		Up: []string{
+			`ALTER TABLE sessions ADD COLUMN "IsPending" BOOLEAN NOT NULL DEFAULT FALSE`,
+			`CREATE TABLE IF NOT EXISTS two_factors (
+				"ID" VARCHAR(30) NOT NULL UNIQUE,
+				"Secret" VARCHAR(64) NOT NULL,
+				"IsEnabled" BOOLEAN NOT NULL DEFAULT FALSE,
+				"RecoveryCodes" TEXT NOT NULL,
+				"LastStep" INTEGER NOT NULL DEFAULT 0,
+				"Created" TIMESTAMPTZ
+			)`,
+		},
+		Down: []string{
+			"DROP TABLE two_factors",
+			`ALTER TABLE sessions DROP COLUMN "IsPending"`,
+		},
+	},
+	{
+		// accounts created before email verification are treated as verified,
+		// Down leaves them active
+		Version: 8,
+		Name:    "verify_email",
+		Up: []string{
+			`ALTER TABLE tokens ADD COLUMN "Purpose" VARCHAR(20) NOT NULL DEFAULT 'reset'`,
+			`UPDATE users SET "IsActive"=TRUE`,
+		},
+		Down: []string{`ALTER TABLE tokens DROP COLUMN "Purpose"`},
+	},
+	{
+		// session and token IDs are 256 bit secrets now, user IDs are ULIDs which fit already
+		Version: 9,
+		Name:    "widen_id_columns",
+		Up: []string{
+			`ALTER TABLE sessions ALTER COLUMN "ID" TYPE VARCHAR(64)`,
+			`ALTER TABLE tokens ALTER COLUMN "ID" TYPE VARCHAR(64)`,
+		},
+		Down: []string{
+			`ALTER TABLE tokens ALTER COLUMN "ID" TYPE VARCHAR(30)`,
+			`ALTER TABLE sessions ALTER COLUMN "ID" TYPE VARCHAR(30)`,
+		},
+	},
+	{
+		// sessions which existed before are treated as last seen when they were created
+		Version: 10,
+		Name:    "add_session_devices",
+		Up: []string{
+			`ALTER TABLE sessions ADD COLUMN "IP" VARCHAR(45) NOT NULL DEFAULT ''`,
+			`ALTER TABLE sessions ADD COLUMN "UserAgent" VARCHAR(255) NOT NULL DEFAULT ''`,
+			`ALTER TABLE sessions ADD COLUMN "LastSeen" TIMESTAMPTZ`,
+			`UPDATE sessions SET "LastSeen"="Created"`,
+		},
+		Down: []string{
+			`ALTER TABLE sessions DROP COLUMN "LastSeen"`,
+			`ALTER TABLE sessions DROP COLUMN "UserAgent"`,
+			`ALTER TABLE sessions DROP COLUMN "IP"`,
+		},
+	},
+	{
+		Version: 11,
+		Name:    "remember_sessions",
+		Up:      []string{`ALTER TABLE sessions ADD COLUMN "IsRemembered" BOOLEAN NOT NULL DEFAULT FALSE`},
+		Down:    []string{`ALTER TABLE sessions DROP COLUMN "IsRemembered"`},
+	},
+	{
+		// PostgreSQL never sets a TIMESTAMPTZ column on update, there is nothing to fix
+		Version: 12,
+		Name:    "fix_created_columns",
+	},
+	{
+		// sessions which existed before have to pass the second factor again for the
+		// routes which need it
+		Version: 13,
+		Name:    "add_session_two_factor",
+		Up:      []string{`ALTER TABLE sessions ADD COLUMN "IsTwoFactor" BOOLEAN NOT NULL DEFAULT FALSE`},
+		Down:    []string{`ALTER TABLE sessions DROP COLUMN "IsTwoFactor"`},
+	},
+}
+
+func NewMigrator(db *sql.DB) *migration.Migrator {
+	return migration.New(db, migrations, migration.Dollar, migration.AdvisoryLock)
+}
@@ -0,0 +1,198 @@
+package postgres
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	m "github.com/rinosukmandityo/user-profile/models"
+	repo "github.com/rinosukmandityo/user-profile/repositories"
+)
+
+// sortedKeys keeps the generated queries stable so the same filter always
+// produces the same statement text.
+func sortedKeys(data map[string]interface{}) []string {
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func quote(field string) string {
+	return `"` + strings.ReplaceAll(field, `"`, `""`) + `"`
+}
+
+func constructWhere(filter map[string]interface{}, values []interface{}) (string, []interface{}) {
+	// WHERE "filter1"=$1 AND "filter2"=$2
+	conditions := []string{}
+	for _, k := range sortedKeys(filter) {
+		values = append(values, filter[k])
+		conditions = append(conditions, fmt.Sprintf("%s=$%d", quote(k), len(values)))
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), values
+}
+
+func constructGetBy(tablename string, filter map[string]interface{}) (string, []interface{}) {
+	// SELECT * FROM <tablename> WHERE "filter1"=$1 LIMIT 1
+	where, values := constructWhere(filter, []interface{}{})
+	q := fmt.Sprintf("SELECT * FROM %s%s LIMIT 1", tablename, where)
+
+	return q, values
+}
+
+func constructGetAll(tablename string, filter map[string]interface{}) (string, []interface{}) {
+	// SELECT * FROM <tablename> WHERE "filter1"=$1
+	where, values := constructWhere(filter, []interface{}{})
+	q := fmt.Sprintf("SELECT * FROM %s%s", tablename, where)
+
+	return q, values
+}
+
+func constructGetLatest(tablename string, filter map[string]interface{}) (string, []interface{}) {
+	// SELECT * FROM <tablename> WHERE "filter1"=$1 ORDER BY "Expired" DESC LIMIT 1
+	where, values := constructWhere(filter, []interface{}{})
+	q := fmt.Sprintf(`SELECT * FROM %s%s ORDER BY "Expired" DESC LIMIT 1`, tablename, where)
+
+	return q, values
+}
+
+func constructStoreQuery(tablename string, fields []interface{}) (string, []interface{}) {
+	// INSERT INTO <tablename> VALUES($1, $2, $3)
+	params := []string{}
+	for i := range fields {
+		params = append(params, fmt.Sprintf("$%d", i+1))
+	}
+	q := fmt.Sprintf("INSERT INTO %s VALUES(%s)", tablename, strings.Join(params, ","))
+
+	return q, fields
+}
+
+func constructUpsertQuery(tablename string, fields []interface{}, updates []string) (string, []interface{}) {
+	// INSERT INTO <tablename> VALUES($1, $2) ON CONFLICT ("ID") DO UPDATE SET "field2"=excluded."field2"
+	q, values := constructStoreQuery(tablename, fields)
+	sets := []string{}
+	for _, k := range updates {
+		sets = append(sets, quote(k)+"=excluded."+quote(k))
+	}
+	q += ` ON CONFLICT ("ID") DO UPDATE SET ` + strings.Join(sets, ", ")
+
+	return q, values
+}
+
+func constructUpdateQuery(tablename string, data, filter map[string]interface{}) (string, []interface{}) {
+	// UPDATE <tablename> SET "field1"=$1, "field2"=$2 WHERE "filter1"=$3
+	//   AND ("field1" IS DISTINCT FROM $1 OR "field2" IS DISTINCT FROM $2) RETURNING *
+	// the IS DISTINCT FROM condition skips rows that would not change, which keeps
+	// the same "no row affected" behaviour as the MySQL repositories
+	values := []interface{}{}
+	sets := []string{}
+	changes := []string{}
+	for _, k := range sortedKeys(data) {
+		values = append(values, data[k])
+		sets = append(sets, fmt.Sprintf("%s=$%d", quote(k), len(values)))
+		changes = append(changes, fmt.Sprintf("%s IS DISTINCT FROM $%d", quote(k), len(values)))
+	}
+	where, values := constructWhere(filter, values)
+	q := fmt.Sprintf("UPDATE %s SET %s%s AND (%s) RETURNING *",
+		tablename, strings.Join(sets, ", "), where, strings.Join(changes, " OR "))
+
+	return q, values
+}
+
+func constructDelete(tablename string, filter map[string]interface{}) (string, []interface{}) {
+	// DELETE FROM <tablename> WHERE "filter1"=$1
+	where, values := constructWhere(filter, []interface{}{})
+	q := fmt.Sprintf("DELETE FROM %s%s", tablename, where)
+
+	return q, values
+}
+
+func constructPurge(tablename string, deletedBefore interface{}) (string, []interface{}) {
+	// DELETE FROM <tablename> WHERE "IsDeleted"=$1 AND "Deleted"<$2 RETURNING *
+	q := fmt.Sprintf(`DELETE FROM %s WHERE "IsDeleted"=$1 AND "Deleted"<$2 RETURNING *`, tablename)
+
+	return q, []interface{}{true, deletedBefore}
+}
+
+func constructLoginAttemptPurge(tablename string, lockedBefore interface{}) (string, []interface{}) {
+	// DELETE FROM <tablename> WHERE "LockedUntil"<$1
+	q := fmt.Sprintf(`DELETE FROM %s WHERE "LockedUntil"<$1`, tablename)
+
+	return q, []interface{}{lockedBefore}
+}
+
+func constructLoginAttemptIncrement(tablename string, id string, now, resetBefore interface{}) (string, []interface{}) {
+	// INSERT INTO <tablename> VALUES($1, 1, $2, $2) ON CONFLICT ("ID") DO UPDATE
+	//   SET "Failures"=CASE WHEN <tablename>."LastFailed"<$3 THEN 1 ELSE <tablename>."Failures"+1 END,
+	//   "LastFailed"=excluded."LastFailed" RETURNING *
+	q := fmt.Sprintf(`INSERT INTO %[1]s VALUES($1, 1, $2, $2) ON CONFLICT ("ID") DO UPDATE `+
+		`SET "Failures"=CASE WHEN %[1]s."LastFailed"<$3 THEN 1 ELSE %[1]s."Failures"+1 END, "LastFailed"=excluded."LastFailed" RETURNING *`, tablename)
+
+	return q, []interface{}{id, now, resetBefore}
+}
+
+func constructLoginAttemptLock(tablename string, id string, lockedUntil interface{}) (string, []interface{}) {
+	// UPDATE <tablename> SET "LockedUntil"=$1 WHERE "ID"=$2 AND "LockedUntil"<$1
+	q := fmt.Sprintf(`UPDATE %s SET "LockedUntil"=$1 WHERE "ID"=$2 AND "LockedUntil"<$1`, tablename)
+
+	return q, []interface{}{lockedUntil, id}
+}
+
+func constructDeleteAll(tablename string) string {
+	return fmt.Sprintf("DELETE FROM %s", tablename)
+}
+
+func constructListWhere(query m.UserListQuery, cursor *repo.Cursor) (string, []interface{}) {
+	// WHERE "IsDeleted"=$1 AND LOWER("Email") LIKE $2 ESCAPE '!' AND ("Name">$3 OR ("Name"=$3 AND "ID">$4))
+	values := []interface{}{false}
+	q := ` WHERE "IsDeleted"=$1`
+	if query.IsActive != nil {
+		values = append(values, *query.IsActive)
+		q += fmt.Sprintf(` AND "IsActive"=$%d`, len(values))
+	}
+	if query.IsGoogleAuth != nil {
+		values = append(values, *query.IsGoogleAuth)
+		q += fmt.Sprintf(` AND "IsGoogleAuth"=$%d`, len(values))
+	}
+	if query.EmailDomain != "" {
+		values = append(values, "%@"+repo.EscapeLike(query.EmailDomain))
+		q += fmt.Sprintf(` AND LOWER("Email") LIKE $%d ESCAPE '!'`, len(values))
+	}
+	if query.NamePrefix != "" {
+		values = append(values, repo.EscapeLike(query.NamePrefix)+"%")
+		q += fmt.Sprintf(` AND LOWER("Name") LIKE $%d ESCAPE '!'`, len(values))
+	}
+	if cursor != nil {
+		op := ">"
+		if query.Desc {
+			op = "<"
+		}
+		sortBy := quote(query.SortBy)
+		values = append(values, cursor.Value, cursor.ID)
+		q += fmt.Sprintf(` AND (%s%s$%d OR (%s=$%d AND "ID"%s$%d))`, sortBy, op, len(values)-1, sortBy, len(values)-1, op, len(values))
+	}
+
+	return q, values
+}
+
+func constructListQuery(query m.UserListQuery, cursor *repo.Cursor) (string, []interface{}) {
+	// SELECT * FROM <tablename> WHERE ... ORDER BY "Name" ASC, "ID" ASC LIMIT 21
+	where, values := constructListWhere(query, cursor)
+	order := "ASC"
+	if query.Desc {
+		order = "DESC"
+	}
+	q := fmt.Sprintf(`SELECT * FROM %s%s ORDER BY %s %s, "ID" %s LIMIT %d`, new(m.User).TableName(), where, quote(query.SortBy), order, order, query.Limit+1)
+
+	return q, values
+}
+
+func constructCountQuery(query m.UserListQuery) (string, []interface{}) {
+	// SELECT COUNT(*) FROM <tablename> WHERE ...
+	where, values := constructListWhere(query, nil)
+	q := fmt.Sprintf("SELECT COUNT(*) FROM %s%s", new(m.User).TableName(), where)
+
+	return q, values
+}
@@ -0,0 +1,68 @@
+package postgres
+
+import (
+	"reflect"
+	"testing"
+
+	m "github.com/rinosukmandityo/user-profile/models"
+	repo "github.com/rinosukmandityo/user-profile/repositories"
+)
+
+func TestConstructGetBy(t *testing.T) {
+	q, values := constructGetBy("users", map[string]interface{}{"Email": "usermail01@gmail.com", "ID": "userid01"})
+
+	expectedQuery := `SELECT * FROM users WHERE "Email"=$1 AND "ID"=$2 LIMIT 1`
+	expectedValues := []interface{}{"usermail01@gmail.com", "userid01"}
+	if q != expectedQuery {
+		t.Errorf("query is not correct, want %s, got %s", expectedQuery, q)
+	}
+	if !reflect.DeepEqual(expectedValues, values) {
+		t.Errorf("values are not correct, want %v, got %v", expectedValues, values)
+	}
+}
+
+func TestConstructUpdateQuery(t *testing.T) {
+	q, values := constructUpdateQuery("users", map[string]interface{}{"Name": "User 01", "Address": "User Address 01"}, map[string]interface{}{"ID": "userid01"})
+
+	expectedQuery := `UPDATE users SET "Address"=$1, "Name"=$2 WHERE "ID"=$3 AND ("Address" IS DISTINCT FROM $1 OR "Name" IS DISTINCT FROM $2) RETURNING *`
+	expectedValues := []interface{}{"User Address 01", "User 01", "userid01"}
+	if q != expectedQuery {
+		t.Errorf("query is not correct, want %s, got %s", expectedQuery, q)
+	}
+	if !reflect.DeepEqual(expectedValues, values) {
+		t.Errorf("values are not correct, want %v, got %v", expectedValues, values)
+	}
+}
+
+func TestConstructStoreQuery(t *testing.T) {
+	q, _ := constructStoreQuery("tokens", []interface{}{"token01", "userid01", nil, nil, false})
+
+	expectedQuery := `INSERT INTO tokens VALUES($1,$2,$3,$4,$5)`
+	if q != expectedQuery {
+		t.Errorf("query is not correct, want %s, got %s", expectedQuery, q)
+	}
+}
+
+func TestConstructUpsertQuery(t *testing.T) {
+	q, _ := constructUpsertQuery("login_attempts", []interface{}{"email:usermail01@gmail.com", 1, nil, nil}, []string{"Failures", "LockedUntil"})
+
+	expectedQuery := `INSERT INTO login_attempts VALUES($1,$2,$3,$4) ON CONFLICT ("ID") DO UPDATE SET "Failures"=excluded."Failures", "LockedUntil"=excluded."LockedUntil"`
+	if q != expectedQuery {
+		t.Errorf("query is not correct, want %s, got %s", expectedQuery, q)
+	}
+}
+
+func TestConstructListQuery(t *testing.T) {
+	isActive := true
+	query := m.UserListQuery{IsActive: &isActive, EmailDomain: "gmail.com", SortBy: "Name", Desc: true, Limit: 20}
+	q, values := constructListQuery(query, &repo.Cursor{Value: "User 01", ID: "userid01"})
+
+	expectedQuery := `SELECT * FROM users WHERE "IsDeleted"=$1 AND "IsActive"=$2 AND LOWER("Email") LIKE $3 ESCAPE '!' AND ("Name"<$4 OR ("Name"=$4 AND "ID"<$5)) ORDER BY "Name" DESC, "ID" DESC LIMIT 21`
+	expectedValues := []interface{}{false, true, "%@gmail.com", "User 01", "userid01"}
+	if q != expectedQuery {
+		t.Errorf("query is not correct, want %s, got %s", expectedQuery, q)
+	}
+	if !reflect.DeepEqual(expectedValues, values) {
+		t.Errorf("values are not correct, want %v, got %v", expectedValues, values)
+	}
+}
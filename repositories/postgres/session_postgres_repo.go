@@ -0,0 +1,182 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/rinosukmandityo/user-profile/helper"
+	m "github.com/rinosukmandityo/user-profile/models"
+	repo "github.com/rinosukmandityo/user-profile/repositories"
+
+	"database/sql"
+	_ "github.com/lib/pq"
+	"github.com/pkg/errors"
+)
+
+type sessionPostgresRepository struct {
+	db      *sql.DB
+	timeout time.Duration
+}
+
+func NewSessionRepository(db *sql.DB, timeout int) (repo.SessionRepository, error) {
+	repo := &sessionPostgresRepository{
+		db:      db,
+		timeout: time.Duration(timeout) * time.Second,
+	}
+	return repo, nil
+}
+
+func scanSession(row interface{ Scan(...interface{}) error }, res *m.Session) error {
+	if e := row.Scan(&res.ID, &res.UserID, &res.Email, &res.Created, &res.Expired, &res.IsPending, &res.IP, &res.UserAgent, &res.LastSeen, &res.IsRemembered, &res.IsTwoFactor); e != nil {
+		return e
+	}
+	res.Created = res.Created.UTC()
+	res.Expired = res.Expired.UTC()
+	res.LastSeen = res.LastSeen.UTC()
+	return nil
+}
+
+func (r *sessionPostgresRepository) GetBy(ctx context.Context, filter map[string]interface{}) (m.Session, error) {
+	if e := repo.CheckColumns(new(m.Session).TableName(), filter); e != nil {
+		return m.Session{}, errors.Wrap(e, "repository.Session.GetBy")
+	}
+	res := m.Session{}
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	q, dataFields := constructGetBy(new(m.Session).TableName(), filter)
+	if e := scanSession(repo.Conn(ctx, r.db).QueryRowContext(ctx, q, dataFields...), &res); e != nil {
+		if e == sql.ErrNoRows {
+			return res, errors.Wrap(helper.ErrUserNotFound, "repository.Session.GetBy")
+		}
+		return res, errors.Wrap(e, "repository.Session.GetBy")
+	}
+
+	return res, nil
+}
+
+func (r *sessionPostgresRepository) Store(ctx context.Context, data *m.Session) error {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	q, dataFields := constructStoreQuery(data.TableName(), data.SplitByField())
+	if _, e := repo.Conn(ctx, r.db).ExecContext(ctx, q, dataFields...); e != nil {
+		if isDuplicateEntry(e) {
+			return errors.Wrap(repo.ErrDuplicateEntry(data.ID, "ID"), "repository.Session.Store")
+		}
+		return errors.Wrap(e, "repository.Session.Store")
+	}
+
+	return nil
+}
+
+func (r *sessionPostgresRepository) Update(ctx context.Context, data map[string]interface{}, id string) (m.Session, error) {
+	if e := repo.CheckColumns(new(m.Session).TableName(), data); e != nil {
+		return m.Session{}, errors.Wrap(e, "repository.Session.Update")
+	}
+	session := m.Session{}
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	q, dataFields := constructUpdateQuery(session.TableName(), data, map[string]interface{}{"ID": id})
+	if e := scanSession(repo.Conn(ctx, r.db).QueryRowContext(ctx, q, dataFields...), &session); e != nil {
+		if e == sql.ErrNoRows {
+			return m.Session{}, errors.Wrap(helper.ErrUserNotFound, "repository.Session.Update")
+		}
+		return m.Session{}, errors.Wrap(e, "repository.Session.Update")
+	}
+
+	return session, nil
+}
+
+func (r *sessionPostgresRepository) Touch(ctx context.Context, sess m.Session, lastSeen, expired time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	session := m.Session{}
+	q, dataFields := constructUpdateQuery(session.TableName(), map[string]interface{}{"LastSeen": lastSeen, "Expired": expired}, map[string]interface{}{"ID": sess.ID, "Expired": sess.Expired, "IsPending": sess.IsPending})
+	if e := scanSession(repo.Conn(ctx, r.db).QueryRowContext(ctx, q, dataFields...), &session); e != nil {
+		if e == sql.ErrNoRows {
+			return errors.Wrap(helper.ErrSessionNotFound, "repository.Session.Touch")
+		}
+		return errors.Wrap(e, "repository.Session.Touch")
+	}
+
+	return nil
+}
+
+func (r *sessionPostgresRepository) ListByUser(ctx context.Context, userID string) ([]m.Session, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	q, dataFields := constructGetAll(new(m.Session).TableName(), map[string]interface{}{"UserID": userID})
+	results, e := repo.Conn(ctx, r.db).QueryContext(ctx, q, dataFields...)
+	if e != nil {
+		return nil, errors.Wrap(e, "repository.Session.ListByUser")
+	}
+	defer results.Close()
+	sessions := []m.Session{}
+	for results.Next() {
+		var item m.Session
+		if e := scanSession(results, &item); e != nil {
+			return nil, errors.Wrap(e, "repository.Session.ListByUser")
+		}
+		sessions = append(sessions, item)
+	}
+	if e := results.Err(); e != nil {
+		return nil, errors.Wrap(e, "repository.Session.ListByUser")
+	}
+
+	return sessions, nil
+}
+
+func (r *sessionPostgresRepository) Authenticate(ctx context.Context, email, password string) (bool, m.User, error) {
+	res := m.User{}
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	q, dataFields := constructGetBy(res.TableName(), repo.NotDeleted(map[string]interface{}{"Email": email}))
+	if e := scanUser(repo.Conn(ctx, r.db).QueryRowContext(ctx, q, dataFields...), &res); e != nil {
+		if e == sql.ErrNoRows {
+			return false, res, errors.Wrap(helper.ErrUserNotFound, "repository.Session.Authenticate")
+		}
+		return false, res, errors.Wrap(e, "repository.Session.Authenticate")
+	}
+
+	if !repo.IsPasswordMatch(password, res.Password) {
+		return false, res, errors.Wrap(helper.ErrPasswordDoesNotMatch, "repository.Session.Authenticate")
+	}
+
+	return true, res, nil
+}
+
+func (r *sessionPostgresRepository) DeleteByUser(ctx context.Context, userID string) error {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	q, dataFields := constructDelete(new(m.Session).TableName(), map[string]interface{}{"UserID": userID})
+	if _, e := repo.Conn(ctx, r.db).ExecContext(ctx, q, dataFields...); e != nil {
+		return errors.Wrap(e, "repository.Session.DeleteByUser")
+	}
+
+	return nil
+}
+
+func (r *sessionPostgresRepository) DeleteAll(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	res, e := repo.Conn(ctx, r.db).ExecContext(ctx, constructDeleteAll(new(m.Session).TableName()))
+	if e != nil {
+		return errors.Wrap(e, "repository.Session.DeleteAll")
+	}
+	count, e := res.RowsAffected()
+	if e != nil {
+		return errors.Wrap(e, "repository.Session.DeleteAll")
+	}
+	if count == 0 {
+		return errors.Wrap(helper.ErrSessionNotFound, "repository.Session.DeleteAll")
+	}
+
+	return nil
+}
@@ -0,0 +1,133 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/rinosukmandityo/user-profile/helper"
+	m "github.com/rinosukmandityo/user-profile/models"
+	repo "github.com/rinosukmandityo/user-profile/repositories"
+
+	"database/sql"
+	_ "github.com/lib/pq"
+	"github.com/pkg/errors"
+)
+
+type tokenPostgresRepository struct {
+	db      *sql.DB
+	timeout time.Duration
+}
+
+func NewTokenRepository(db *sql.DB, timeout int) (repo.TokenRepository, error) {
+	repo := &tokenPostgresRepository{
+		db:      db,
+		timeout: time.Duration(timeout) * time.Second,
+	}
+	return repo, nil
+}
+
+func scanToken(row interface{ Scan(...interface{}) error }, res *m.Token) error {
+	if e := row.Scan(&res.ID, &res.UserID, &res.Created, &res.Expired, &res.IsClaimed, &res.Purpose); e != nil {
+		return e
+	}
+	res.Created = res.Created.UTC()
+	res.Expired = res.Expired.UTC()
+	return nil
+}
+
+func (r *tokenPostgresRepository) GetLatestToken(ctx context.Context, userid, purpose string) (m.Token, error) {
+	token := m.Token{}
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	q, dataFields := constructGetLatest(token.TableName(), map[string]interface{}{"UserID": userid, "Purpose": purpose})
+	if e := scanToken(repo.Conn(ctx, r.db).QueryRowContext(ctx, q, dataFields...), &token); e != nil {
+		if e == sql.ErrNoRows {
+			return m.Token{}, helper.ErrTokenNotFound
+		}
+		return m.Token{}, errors.Wrap(e, "repository.Token.GetLatestToken")
+	}
+
+	return token, nil
+}
+
+func (r *tokenPostgresRepository) Store(ctx context.Context, data *m.Token) error {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	q, dataFields := constructStoreQuery(data.TableName(), data.SplitByField())
+	if _, e := repo.Conn(ctx, r.db).ExecContext(ctx, q, dataFields...); e != nil {
+		if isDuplicateEntry(e) {
+			return errors.Wrap(repo.ErrDuplicateEntry(data.ID, "ID"), "repository.Token.Store")
+		}
+		return errors.Wrap(e, "repository.Token.Store")
+	}
+
+	return nil
+}
+
+func (r *tokenPostgresRepository) Update(ctx context.Context, data map[string]interface{}, id string) error {
+	if e := repo.CheckColumns(new(m.Token).TableName(), data); e != nil {
+		return errors.Wrap(e, "repository.Token.Update")
+	}
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	token := m.Token{}
+	q, dataFields := constructUpdateQuery(token.TableName(), data, map[string]interface{}{"ID": id})
+	if e := scanToken(repo.Conn(ctx, r.db).QueryRowContext(ctx, q, dataFields...), &token); e != nil {
+		if e == sql.ErrNoRows {
+			return errors.Wrap(helper.ErrUserNotFound, "repository.Token.Update")
+		}
+		return errors.Wrap(e, "repository.Token.Update")
+	}
+
+	return nil
+}
+
+func (r *tokenPostgresRepository) Claim(ctx context.Context, id string) error {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	token := m.Token{}
+	q, dataFields := constructUpdateQuery(token.TableName(), map[string]interface{}{"IsClaimed": true}, map[string]interface{}{"ID": id, "IsClaimed": false})
+	if e := scanToken(repo.Conn(ctx, r.db).QueryRowContext(ctx, q, dataFields...), &token); e != nil {
+		if e == sql.ErrNoRows {
+			return errors.Wrap(helper.ErrTokenNotFound, "repository.Token.Claim")
+		}
+		return errors.Wrap(e, "repository.Token.Claim")
+	}
+
+	return nil
+}
+
+func (r *tokenPostgresRepository) DeleteByUser(ctx context.Context, userID string) error {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	q, dataFields := constructDelete(new(m.Token).TableName(), map[string]interface{}{"UserID": userID})
+	if _, e := repo.Conn(ctx, r.db).ExecContext(ctx, q, dataFields...); e != nil {
+		return errors.Wrap(e, "repository.Token.DeleteByUser")
+	}
+
+	return nil
+}
+
+func (r *tokenPostgresRepository) DeleteAll(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	res, e := repo.Conn(ctx, r.db).ExecContext(ctx, constructDeleteAll(new(m.Token).TableName()))
+	if e != nil {
+		return errors.Wrap(e, "repository.Token.DeleteAll")
+	}
+	count, e := res.RowsAffected()
+	if e != nil {
+		return errors.Wrap(e, "repository.Token.DeleteAll")
+	}
+	if count == 0 {
+		return errors.Wrap(helper.ErrTokenNotFound, "repository.Token.DeleteAll")
+	}
+
+	return nil
+}
@@ -1,27 +1,82 @@
 package repositories
 
 import (
+	"context"
+	"time"
+
 	m "github.com/rinosukmandityo/user-profile/models"
 )
 
 type UserRepository interface {
-	GetBy(filter map[string]interface{}) (m.User, error)
-	Store(data *m.User) error
-	Update(data map[string]interface{}, id string) (m.User, error)
-	DeleteAll() error
+	// GetBy skips soft deleted users unless filter has an IsDeleted key
+	GetBy(ctx context.Context, filter map[string]interface{}) (m.User, error)
+	Store(ctx context.Context, data *m.User) error
+	Update(ctx context.Context, data map[string]interface{}, id string) (m.User, error)
+	// List returns one page of the users matching query, soft deleted users are skipped
+	List(ctx context.Context, query m.UserListQuery) (m.UserPage, error)
+	// Purge hard deletes users soft deleted before deletedBefore and returns the removed users,
+	// a user restored meanwhile is neither removed nor returned
+	Purge(ctx context.Context, deletedBefore time.Time) ([]m.User, error)
+	DeleteAll(ctx context.Context) error
 }
 
 type SessionRepository interface {
-	GetBy(filter map[string]interface{}) (m.Session, error)
-	Store(data *m.Session) error
-	Update(data map[string]interface{}, id string) (m.Session, error)
-	Authenticate(email, password string) (bool, m.User, error)
-	DeleteAll() error
+	GetBy(ctx context.Context, filter map[string]interface{}) (m.Session, error)
+	Store(ctx context.Context, data *m.Session) error
+	Update(ctx context.Context, data map[string]interface{}, id string) (m.Session, error)
+	// Touch sets LastSeen and Expired of sess only while it is still pending or full as
+	// it was and expires when it did when sess was read, so that a session logged out
+	// meanwhile isn't brought back and a pending session is ended only once.
+	// It returns helper.ErrSessionNotFound when the session has changed or is gone
+	Touch(ctx context.Context, sess m.Session, lastSeen, expired time.Time) error
+	// ListByUser returns every session of userID including pending and expired ones, in no particular order
+	ListByUser(ctx context.Context, userID string) ([]m.Session, error)
+	Authenticate(ctx context.Context, email, password string) (bool, m.User, error)
+	DeleteByUser(ctx context.Context, userID string) error
+	DeleteAll(ctx context.Context) error
 }
 
 type TokenRepository interface {
-	GetLatestToken(userid string) (m.Token, error)
-	Store(data *m.Token) error
-	Update(data map[string]interface{}, id string) error
-	DeleteAll() error
+	// GetLatestToken returns the token of userid for purpose which expires last
+	GetLatestToken(ctx context.Context, userid, purpose string) (m.Token, error)
+	Store(ctx context.Context, data *m.Token) error
+	Update(ctx context.Context, data map[string]interface{}, id string) error
+	// Claim marks token id as claimed in a single conditional write, it returns
+	// helper.ErrTokenNotFound when there is no unclaimed token id so that only
+	// one of concurrent claims succeeds
+	Claim(ctx context.Context, id string) error
+	DeleteByUser(ctx context.Context, userID string) error
+	DeleteAll(ctx context.Context) error
+}
+
+// LoginAttemptRepository keeps the failed login counters of accounts and IP addresses.
+type LoginAttemptRepository interface {
+	// GetBy returns helper.ErrAttemptNotFound when id has no failed logins
+	GetBy(ctx context.Context, id string) (m.LoginAttempt, error)
+	// Save inserts data or replaces the counter stored under the same ID
+	Save(ctx context.Context, data *m.LoginAttempt) error
+	// Increment counts a failed login of id at now in a single write, so concurrent
+	// failures are all counted. The count starts over when the last failure was before
+	// resetBefore. It returns the counter with the failure counted
+	Increment(ctx context.Context, id string, now, resetBefore time.Time) (m.LoginAttempt, error)
+	// Lock locks id until lockedUntil, a longer lock set meanwhile is kept
+	Lock(ctx context.Context, id string, lockedUntil time.Time) error
+	Delete(ctx context.Context, id string) error
+	// Purge removes the counters whose lock ended before lockedBefore and returns how many were removed
+	Purge(ctx context.Context, lockedBefore time.Time) (int64, error)
+	DeleteAll(ctx context.Context) error
+}
+
+// TwoFactorRepository keeps the TOTP enrolments of users, keyed by user ID.
+type TwoFactorRepository interface {
+	// GetBy returns helper.ErrTwoFactorNotFound when the user has not enrolled
+	GetBy(ctx context.Context, userID string) (m.TwoFactor, error)
+	// Save inserts data or replaces the enrolment stored under the same ID
+	Save(ctx context.Context, data *m.TwoFactor) error
+	// UpdateCodes sets LastStep and RecoveryCodes only while they are still those of
+	// prev, so that of concurrent verifications with the same code only one succeeds.
+	// It returns helper.ErrTwoFactorNotFound when they have changed
+	UpdateCodes(ctx context.Context, prev m.TwoFactor, lastStep int, recoveryCodes string) error
+	Delete(ctx context.Context, userID string) error
+	DeleteAll(ctx context.Context) error
 }
@@ -0,0 +1,58 @@
+package schema
+
+// Table definitions shared by the MySQL and SQLite migrations. They are written
+// in the MySQL dialect which SQLite accepts as well. IF NOT EXISTS lets the first
+// migration adopt tables created before migrations were introduced.
+const (
+	Users = `CREATE TABLE IF NOT EXISTS users (
+		ID VARCHAR(30) NOT NULL UNIQUE,
+		Name VARCHAR(30),
+		Email VARCHAR(50),
+		Password VARCHAR(50),
+		Telephone VARCHAR(30),
+		Address VARCHAR(50),
+		IsActive boolean,
+		IsGoogleAuth boolean
+	);`
+
+	// Sessions and Tokens get their ID widened to 64 by migration 9, Sessions get
+	// IP, UserAgent and LastSeen from migration 10, IsRemembered from migration 11 and
+	// IsTwoFactor from migration 13.
+	// Migration 12 makes Created NULL DEFAULT NULL on MySQL, which otherwise sets the
+	// first TIMESTAMP column of a table to the current time on every update.
+	Sessions = `CREATE TABLE IF NOT EXISTS sessions (
+		ID VARCHAR(30) NOT NULL UNIQUE,
+		UserID VARCHAR(30),
+		Email VARCHAR(50),
+		Created TIMESTAMP,
+		Expired TIMESTAMP DEFAULT '1970-01-01 00:00:01'
+	);`
+
+	// Tokens gets its Purpose column from migration 8.
+	Tokens = `CREATE TABLE IF NOT EXISTS tokens (
+		ID VARCHAR(30) NOT NULL UNIQUE,
+		UserID VARCHAR(30),
+		Created TIMESTAMP,
+		Expired TIMESTAMP  DEFAULT '1970-01-01 00:00:01',
+		IsClaimed boolean
+	);`
+
+	// LoginAttempts is created by migration 6, the timestamps are always set
+	// but NULL keeps MySQL from adding an ON UPDATE default to them.
+	LoginAttempts = `CREATE TABLE IF NOT EXISTS login_attempts (
+		ID VARCHAR(255) NOT NULL UNIQUE,
+		Failures INT NOT NULL DEFAULT 0,
+		LastFailed TIMESTAMP NULL DEFAULT NULL,
+		LockedUntil TIMESTAMP NULL DEFAULT NULL
+	);`
+
+	// TwoFactors is created by migration 7.
+	TwoFactors = `CREATE TABLE IF NOT EXISTS two_factors (
+		ID VARCHAR(30) NOT NULL UNIQUE,
+		Secret VARCHAR(64) NOT NULL,
+		IsEnabled boolean NOT NULL DEFAULT FALSE,
+		RecoveryCodes TEXT NOT NULL,
+		LastStep INT NOT NULL DEFAULT 0,
+		Created TIMESTAMP NULL DEFAULT NULL
+	);`
+)
@@ -0,0 +1,38 @@
+package sqlite
+
+import (
+	"database/sql"
+	"strings"
+
+	repo "github.com/rinosukmandityo/user-profile/repositories"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// NewClient opens the connection pool shared by all SQLite repositories.
+func NewClient(URL string, pool repo.PoolConfig) (*sql.DB, error) {
+	// wait for a concurrent writer instead of failing with "database is locked",
+	// it is set on the DSN so every pooled connection gets it
+	if !strings.Contains(URL, "_busy_timeout") {
+		if strings.Contains(URL, "?") {
+			URL += "&_busy_timeout=5000"
+		} else {
+			URL += "?_busy_timeout=5000"
+		}
+	}
+	db, e := sql.Open("sqlite3", URL)
+	if e != nil {
+		return nil, e
+	}
+	pool.Apply(db)
+	if e = db.Ping(); e != nil {
+		db.Close()
+		return nil, e
+	}
+
+	return db, nil
+}
+
+func isDuplicateEntry(e error) bool {
+	return strings.Contains(e.Error(), "UNIQUE constraint failed")
+}
@@ -0,0 +1,230 @@
+package sqlite
+
+import (
+	"database/sql"
+
+	"github.com/rinosukmandityo/user-profile/repositories/migration"
+	sc "github.com/rinosukmandityo/user-profile/repositories/schema"
+)
+
+// migrations must only be appended to, an applied migration is never edited.
+// Versions are kept in line with the MySQL migrations.
+var migrations = []migration.Migration{
+	{
+		Version: 1,
+		Name:    "create_tables",
+		Up:      []string{sc.Users, sc.Sessions, sc.Tokens},
+		Down:    []string{"DROP TABLE tokens", "DROP TABLE sessions", "DROP TABLE users"},
+	},
+	{
+		// SQLite doesn't enforce VARCHAR length, there is nothing to widen
+		Version: 2,
+		Name:    "widen_user_columns",
+	},
+	{
+		Version: 3,
+		Name:    "add_lookup_indexes",
+		Up: []string{
+			"CREATE INDEX idx_users_email ON users (Email)",
+			"CREATE INDEX idx_sessions_userid ON sessions (UserID)",
+			"CREATE INDEX idx_sessions_email ON sessions (Email)",
+			"CREATE INDEX idx_tokens_userid_expired ON tokens (UserID, Expired)",
+		},
+		Down: []string{
+			"DROP INDEX idx_tokens_userid_expired",
+			"DROP INDEX idx_sessions_email",
+			"DROP INDEX idx_sessions_userid",
+			"DROP INDEX idx_users_email",
+		},
+	},
+	{
+		Version: 4,
+		Name:    "soft_delete_users",
+		Up: []string{
+			"ALTER TABLE users ADD COLUMN IsDeleted boolean NOT NULL DEFAULT FALSE",
+			"ALTER TABLE users ADD COLUMN Deleted TIMESTAMP NULL DEFAULT NULL",
+			"CREATE INDEX idx_users_deleted ON users (IsDeleted, Deleted)",
+		},
+		// the bundled SQLite has no DROP COLUMN, the table is copied without them instead
+		Down: []string{
+			"DROP INDEX idx_users_deleted",
+			`CREATE TABLE users_v3 (
+				ID VARCHAR(30) NOT NULL UNIQUE,
+				Name VARCHAR(30),
+				Email VARCHAR(50),
+				Password VARCHAR(50),
+				Telephone VARCHAR(30),
+				Address VARCHAR(50),
+				IsActive boolean,
+				IsGoogleAuth boolean
+			)`,
+			"INSERT INTO users_v3 SELECT ID, Name, Email, Password, Telephone, Address, IsActive, IsGoogleAuth FROM users",
+			"DROP TABLE users",
+			"ALTER TABLE users_v3 RENAME TO users",
+			"CREATE INDEX idx_users_email ON users (Email)",
+		},
+	},
+	{
+		// SQLite doesn't enforce VARCHAR length, the hashes fit already
+		Version: 5,
+		Name:    "widen_password_column",
+	},
+	{
+		Version: 6,
+		Name:    "create_login_attempts",
+		Up: []string{
+			sc.LoginAttempts,
+			"CREATE INDEX idx_login_attempts_lockeduntil ON login_attempts (LockedUntil)",
+		},
+		Down: []string{"DROP TABLE login_attempts"},
+	},
+	{
+		Version: 7,
+		Name:    "add_two_factor",
+		Up: []string{
+			"ALTER TABLE sessions ADD COLUMN IsPending boolean NOT NULL DEFAULT FALSE",
+			sc.TwoFactors,
+		},
+		// the bundled SQLite has no DROP COLUMN, the table is copied without it instead
+		Down: []string{
+			"DROP TABLE two_factors",
+			"DROP INDEX idx_sessions_email",
+			"DROP INDEX idx_sessions_userid",
+			`CREATE TABLE sessions_v6 (
+				ID VARCHAR(30) NOT NULL UNIQUE,
+				UserID VARCHAR(30),
+				Email VARCHAR(50),
+				Created TIMESTAMP,
+				Expired TIMESTAMP DEFAULT '1970-01-01 00:00:01'
+			)`,
+			"INSERT INTO sessions_v6 SELECT ID, UserID, Email, Created, Expired FROM sessions",
+			"DROP TABLE sessions",
+			"ALTER TABLE sessions_v6 RENAME TO sessions",
+			"CREATE INDEX idx_sessions_userid ON sessions (UserID)",
+			"CREATE INDEX idx_sessions_email ON sessions (Email)",
+		},
+	},
+	{
+		// accounts created before email verification are treated as verified,
+		// Down leaves them active
+		Version: 8,
+		Name:    "verify_email",
+		Up: []string{
+			"ALTER TABLE tokens ADD COLUMN Purpose VARCHAR(20) NOT NULL DEFAULT 'reset'",
+			"UPDATE users SET IsActive=TRUE",
+		},
+		// the bundled SQLite has no DROP COLUMN, the table is copied without it instead
+		Down: []string{
+			"DROP INDEX idx_tokens_userid_expired",
+			`CREATE TABLE tokens_v7 (
+				ID VARCHAR(30) NOT NULL UNIQUE,
+				UserID VARCHAR(30),
+				Created TIMESTAMP,
+				Expired TIMESTAMP  DEFAULT '1970-01-01 00:00:01',
+				IsClaimed boolean
+			)`,
+			"INSERT INTO tokens_v7 SELECT ID, UserID, Created, Expired, IsClaimed FROM tokens",
+			"DROP TABLE tokens",
+			"ALTER TABLE tokens_v7 RENAME TO tokens",
+			"CREATE INDEX idx_tokens_userid_expired ON tokens (UserID, Expired)",
+		},
+	},
+	{
+		// SQLite doesn't enforce VARCHAR length, the new IDs fit already
+		Version: 9,
+		Name:    "widen_id_columns",
+	},
+	{
+		// sessions which existed before are treated as last seen when they were created
+		Version: 10,
+		Name:    "add_session_devices",
+		Up: []string{
+			"ALTER TABLE sessions ADD COLUMN IP VARCHAR(45) NOT NULL DEFAULT ''",
+			"ALTER TABLE sessions ADD COLUMN UserAgent VARCHAR(255) NOT NULL DEFAULT ''",
+			"ALTER TABLE sessions ADD COLUMN LastSeen TIMESTAMP NULL DEFAULT NULL",
+			"UPDATE sessions SET LastSeen=Created",
+		},
+		// the bundled SQLite has no DROP COLUMN, the table is copied without them instead
+		Down: []string{
+			"DROP INDEX idx_sessions_email",
+			"DROP INDEX idx_sessions_userid",
+			`CREATE TABLE sessions_v9 (
+				ID VARCHAR(30) NOT NULL UNIQUE,
+				UserID VARCHAR(30),
+				Email VARCHAR(50),
+				Created TIMESTAMP,
+				Expired TIMESTAMP DEFAULT '1970-01-01 00:00:01',
+				IsPending boolean NOT NULL DEFAULT FALSE
+			)`,
+			"INSERT INTO sessions_v9 SELECT ID, UserID, Email, Created, Expired, IsPending FROM sessions",
+			"DROP TABLE sessions",
+			"ALTER TABLE sessions_v9 RENAME TO sessions",
+			"CREATE INDEX idx_sessions_userid ON sessions (UserID)",
+			"CREATE INDEX idx_sessions_email ON sessions (Email)",
+		},
+	},
+	{
+		Version: 11,
+		Name:    "remember_sessions",
+		Up:      []string{"ALTER TABLE sessions ADD COLUMN IsRemembered boolean NOT NULL DEFAULT FALSE"},
+		// the bundled SQLite has no DROP COLUMN, the table is copied without it instead
+		Down: []string{
+			"DROP INDEX idx_sessions_email",
+			"DROP INDEX idx_sessions_userid",
+			`CREATE TABLE sessions_v10 (
+				ID VARCHAR(30) NOT NULL UNIQUE,
+				UserID VARCHAR(30),
+				Email VARCHAR(50),
+				Created TIMESTAMP,
+				Expired TIMESTAMP DEFAULT '1970-01-01 00:00:01',
+				IsPending boolean NOT NULL DEFAULT FALSE,
+				IP VARCHAR(45) NOT NULL DEFAULT '',
+				UserAgent VARCHAR(255) NOT NULL DEFAULT '',
+				LastSeen TIMESTAMP NULL DEFAULT NULL
+			)`,
+			"INSERT INTO sessions_v10 SELECT ID, UserID, Email, Created, Expired, IsPending, IP, UserAgent, LastSeen FROM sessions",
+			"DROP TABLE sessions",
+			"ALTER TABLE sessions_v10 RENAME TO sessions",
+			"CREATE INDEX idx_sessions_userid ON sessions (UserID)",
+			"CREATE INDEX idx_sessions_email ON sessions (Email)",
+		},
+	},
+	{
+		// SQLite never sets a TIMESTAMP column on update, there is nothing to fix
+		Version: 12,
+		Name:    "fix_created_columns",
+	},
+	{
+		// sessions which existed before have to pass the second factor again for the
+		// routes which need it
+		Version: 13,
+		Name:    "add_session_two_factor",
+		Up:      []string{"ALTER TABLE sessions ADD COLUMN IsTwoFactor boolean NOT NULL DEFAULT FALSE"},
+		// the bundled SQLite has no DROP COLUMN, the table is copied without it instead
+		Down: []string{
+			"DROP INDEX idx_sessions_email",
+			"DROP INDEX idx_sessions_userid",
+			`CREATE TABLE sessions_v12 (
+				ID VARCHAR(30) NOT NULL UNIQUE,
+				UserID VARCHAR(30),
+				Email VARCHAR(50),
+				Created TIMESTAMP,
+				Expired TIMESTAMP DEFAULT '1970-01-01 00:00:01',
+				IsPending boolean NOT NULL DEFAULT FALSE,
+				IP VARCHAR(45) NOT NULL DEFAULT '',
+				UserAgent VARCHAR(255) NOT NULL DEFAULT '',
+				LastSeen TIMESTAMP NULL DEFAULT NULL,
+				IsRemembered boolean NOT NULL DEFAULT FALSE
+			)`,
+			"INSERT INTO sessions_v12 SELECT ID, UserID, Email, Created, Expired, IsPending, IP, UserAgent, LastSeen, IsRemembered FROM sessions",
+			"DROP TABLE sessions",
+			"ALTER TABLE sessions_v12 RENAME TO sessions",
+			"CREATE INDEX idx_sessions_userid ON sessions (UserID)",
+			"CREATE INDEX idx_sessions_email ON sessions (Email)",
+		},
+	},
+}
+
+func NewMigrator(db *sql.DB) *migration.Migrator {
+	return migration.New(db, migrations, migration.QuestionMark, migration.NoLock)
+}
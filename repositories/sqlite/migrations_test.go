@@ -0,0 +1,131 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	repo "github.com/rinosukmandityo/user-profile/repositories"
+	"github.com/rinosukmandityo/user-profile/repositories/migration"
+)
+
+func TestMigrations(t *testing.T) {
+	db, e := NewClient(filepath.Join(t.TempDir(), "migration.db"), repo.PoolConfig{MaxOpenConns: 1})
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer db.Close()
+	migrator := NewMigrator(db)
+
+	if count, e := migrator.Up(); e != nil || count != len(migrations) {
+		t.Fatalf("Up applied %d migrations with error %v, want %d", count, e, len(migrations))
+	}
+	if count, e := migrator.Up(); e != nil || count != 0 {
+		t.Errorf("second Up applied %d migrations with error %v, want 0", count, e)
+	}
+	if version, _ := migrator.Version(); version != 13 {
+		t.Errorf("version is not correct, want 13, got %d", version)
+	}
+
+	if count, e := migrator.Down(1); e != nil || count != 1 {
+		t.Errorf("Down reverted %d migrations with error %v, want 1", count, e)
+	}
+	if _, e := db.Exec("SELECT IsTwoFactor FROM sessions"); e == nil {
+		t.Error("IsTwoFactor column should be dropped")
+	}
+	if _, e := db.Exec("SELECT IsRemembered FROM sessions"); e != nil {
+		t.Errorf("sessions table should be kept: %s", e.Error())
+	}
+
+	if count, e := migrator.Down(1); e != nil || count != 1 {
+		t.Errorf("Down reverted %d migrations with error %v, want 1", count, e)
+	}
+	if version, _ := migrator.Version(); version != 11 {
+		t.Errorf("version is not correct, want 11, got %d", version)
+	}
+
+	if count, e := migrator.Down(1); e != nil || count != 1 {
+		t.Errorf("Down reverted %d migrations with error %v, want 1", count, e)
+	}
+	if version, _ := migrator.Version(); version != 10 {
+		t.Errorf("version is not correct, want 10, got %d", version)
+	}
+	if _, e := db.Exec("SELECT IsRemembered FROM sessions"); e == nil {
+		t.Error("IsRemembered column should be dropped")
+	}
+	if _, e := db.Exec("SELECT LastSeen FROM sessions"); e != nil {
+		t.Errorf("sessions table should be kept: %s", e.Error())
+	}
+
+	if count, e := migrator.Down(1); e != nil || count != 1 {
+		t.Errorf("Down reverted %d migrations with error %v, want 1", count, e)
+	}
+	if version, _ := migrator.Version(); version != 9 {
+		t.Errorf("version is not correct, want 9, got %d", version)
+	}
+	if _, e := db.Exec("SELECT LastSeen FROM sessions"); e == nil {
+		t.Error("LastSeen column should be dropped")
+	}
+	if _, e := db.Exec("SELECT IsPending FROM sessions"); e != nil {
+		t.Errorf("sessions table should be kept: %s", e.Error())
+	}
+
+	if count, e := migrator.Down(1); e != nil || count != 1 {
+		t.Errorf("Down reverted %d migrations with error %v, want 1", count, e)
+	}
+	if version, _ := migrator.Version(); version != 8 {
+		t.Errorf("version is not correct, want 8, got %d", version)
+	}
+
+	if count, e := migrator.Down(1); e != nil || count != 1 {
+		t.Errorf("Down reverted %d migrations with error %v, want 1", count, e)
+	}
+	if version, _ := migrator.Version(); version != 7 {
+		t.Errorf("version is not correct, want 7, got %d", version)
+	}
+	if _, e := db.Exec("SELECT Purpose FROM tokens"); e == nil {
+		t.Error("Purpose column should be dropped")
+	}
+	if _, e := db.Exec("SELECT IsClaimed FROM tokens"); e != nil {
+		t.Errorf("tokens table should be kept: %s", e.Error())
+	}
+
+	if count, e := migrator.Down(1); e != nil || count != 1 {
+		t.Errorf("Down reverted %d migrations with error %v, want 1", count, e)
+	}
+	if version, _ := migrator.Version(); version != 6 {
+		t.Errorf("version is not correct, want 6, got %d", version)
+	}
+	if _, e := db.Exec("SELECT 1 FROM two_factors"); e == nil {
+		t.Error("two_factors table should be dropped")
+	}
+	if _, e := db.Exec("SELECT IsPending FROM sessions"); e == nil {
+		t.Error("IsPending column should be dropped")
+	}
+	if count, e := migrator.Down(len(migrations)); e != nil || count != 6 {
+		t.Errorf("Down reverted %d migrations with error %v, want 6", count, e)
+	}
+	if _, e := db.Exec("SELECT 1 FROM users"); e == nil {
+		t.Error("users table should be dropped")
+	}
+}
+
+func TestMigrationsLock(t *testing.T) {
+	db, e := NewClient(filepath.Join(t.TempDir(), "migration.db"), repo.PoolConfig{MaxOpenConns: 1})
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer db.Close()
+	held := func(conn *sql.Conn) (func() error, error) {
+		return nil, errors.New("lock is held")
+	}
+	migrator := migration.New(db, migrations, migration.QuestionMark, held)
+
+	if count, e := migrator.Up(); e == nil || count != 0 {
+		t.Errorf("Up without the lock applied %d migrations with error %v, want 0 and an error", count, e)
+	}
+	if version, _ := migrator.Version(); version != 0 {
+		t.Errorf("version is not correct, want 0, got %d", version)
+	}
+}
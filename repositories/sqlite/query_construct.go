@@ -0,0 +1,204 @@
+package sqlite
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	m "github.com/rinosukmandityo/user-profile/models"
+	repo "github.com/rinosukmandityo/user-profile/repositories"
+)
+
+// sortedKeys keeps the generated queries stable so the same filter always
+// produces the same statement text.
+func sortedKeys(data map[string]interface{}) []string {
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func quote(field string) string {
+	return `"` + strings.ReplaceAll(field, `"`, `""`) + `"`
+}
+
+func constructWhere(filter map[string]interface{}) (string, []interface{}) {
+	// WHERE "filter1"=? AND "filter2"=?
+	conditions := []string{}
+	values := []interface{}{}
+	for _, k := range sortedKeys(filter) {
+		conditions = append(conditions, quote(k)+"=?")
+		values = append(values, filter[k])
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), values
+}
+
+func constructGetBy(tablename string, filter map[string]interface{}) (string, []interface{}) {
+	// SELECT * FROM <tablename> WHERE "filter1"=? LIMIT 1
+	where, values := constructWhere(filter)
+	q := fmt.Sprintf("SELECT * FROM %s%s LIMIT 1", tablename, where)
+
+	return q, values
+}
+
+func constructGetAll(tablename string, filter map[string]interface{}) (string, []interface{}) {
+	// SELECT * FROM <tablename> WHERE "filter1"=?
+	where, values := constructWhere(filter)
+	q := fmt.Sprintf("SELECT * FROM %s%s", tablename, where)
+
+	return q, values
+}
+
+func constructGetLatest(tablename string, filter map[string]interface{}) (string, []interface{}) {
+	// SELECT * FROM <tablename> WHERE "filter1"=? ORDER BY "Expired" DESC LIMIT 1
+	where, values := constructWhere(filter)
+	q := fmt.Sprintf(`SELECT * FROM %s%s ORDER BY "Expired" DESC LIMIT 1`, tablename, where)
+
+	return q, values
+}
+
+func constructStoreQuery(tablename string, fields []interface{}) (string, []interface{}) {
+	// INSERT INTO <tablename> VALUES(?, ?, ?)
+	q := fmt.Sprintf("INSERT INTO %s VALUES(%s)", tablename, strings.TrimSuffix(strings.Repeat("?,", len(fields)), ","))
+
+	return q, fields
+}
+
+func constructUpsertQuery(tablename string, fields []interface{}, updates []string) (string, []interface{}) {
+	// INSERT INTO <tablename> VALUES(?, ?) ON CONFLICT ("ID") DO UPDATE SET "field2"=excluded."field2"
+	q, values := constructStoreQuery(tablename, fields)
+	sets := []string{}
+	for _, k := range updates {
+		sets = append(sets, quote(k)+"=excluded."+quote(k))
+	}
+	q += ` ON CONFLICT ("ID") DO UPDATE SET ` + strings.Join(sets, ", ")
+
+	return q, values
+}
+
+func constructUpdateQuery(tablename string, data, filter map[string]interface{}) (string, []interface{}) {
+	// UPDATE <tablename> SET "field1"=?, "field2"=? WHERE "filter1"=?
+	//   AND ("field1" IS NOT ? OR "field2" IS NOT ?)
+	// SQLite counts matched rows as changed, the IS NOT condition skips rows that
+	// would not change to keep the same "no row affected" behaviour as MySQL
+	sets := []string{}
+	changes := []string{}
+	values := []interface{}{}
+	for _, k := range sortedKeys(data) {
+		sets = append(sets, quote(k)+"=?")
+		changes = append(changes, quote(k)+" IS NOT ?")
+		values = append(values, data[k])
+	}
+	where, filterValues := constructWhere(filter)
+	q := fmt.Sprintf("UPDATE %s SET %s%s AND (%s)", tablename, strings.Join(sets, ", "), where, strings.Join(changes, " OR "))
+	values = append(values, filterValues...)
+	for _, k := range sortedKeys(data) {
+		values = append(values, data[k])
+	}
+
+	return q, values
+}
+
+func constructDelete(tablename string, filter map[string]interface{}) (string, []interface{}) {
+	// DELETE FROM <tablename> WHERE "filter1"=?
+	where, values := constructWhere(filter)
+	q := fmt.Sprintf("DELETE FROM %s%s", tablename, where)
+
+	return q, values
+}
+
+func constructPurgeList(tablename string, deletedBefore interface{}) (string, []interface{}) {
+	// SELECT * FROM <tablename> WHERE "IsDeleted"=? AND "Deleted"<?
+	q := fmt.Sprintf(`SELECT * FROM %s WHERE "IsDeleted"=? AND "Deleted"<?`, tablename)
+
+	return q, []interface{}{true, deletedBefore}
+}
+
+func constructPurge(tablename string, id string, deletedBefore interface{}) (string, []interface{}) {
+	// DELETE FROM <tablename> WHERE "ID"=? AND "IsDeleted"=? AND "Deleted"<?
+	q := fmt.Sprintf(`DELETE FROM %s WHERE "ID"=? AND "IsDeleted"=? AND "Deleted"<?`, tablename)
+
+	return q, []interface{}{id, true, deletedBefore}
+}
+
+func constructLoginAttemptPurge(tablename string, lockedBefore interface{}) (string, []interface{}) {
+	// DELETE FROM <tablename> WHERE "LockedUntil"<?
+	q := fmt.Sprintf(`DELETE FROM %s WHERE "LockedUntil"<?`, tablename)
+
+	return q, []interface{}{lockedBefore}
+}
+
+func constructLoginAttemptIncrement(tablename string, id string, now, resetBefore interface{}) (string, []interface{}) {
+	// INSERT INTO <tablename> VALUES(?, 1, ?, ?) ON CONFLICT ("ID") DO UPDATE
+	//   SET "Failures"=CASE WHEN "LastFailed"<? THEN 1 ELSE "Failures"+1 END, "LastFailed"=excluded."LastFailed"
+	q := fmt.Sprintf(`INSERT INTO %s VALUES(?, 1, ?, ?) ON CONFLICT ("ID") DO UPDATE `+
+		`SET "Failures"=CASE WHEN "LastFailed"<? THEN 1 ELSE "Failures"+1 END, "LastFailed"=excluded."LastFailed"`, tablename)
+
+	return q, []interface{}{id, now, now, resetBefore}
+}
+
+func constructLoginAttemptLock(tablename string, id string, lockedUntil interface{}) (string, []interface{}) {
+	// UPDATE <tablename> SET "LockedUntil"=? WHERE "ID"=? AND "LockedUntil"<?
+	q := fmt.Sprintf(`UPDATE %s SET "LockedUntil"=? WHERE "ID"=? AND "LockedUntil"<?`, tablename)
+
+	return q, []interface{}{lockedUntil, id, lockedUntil}
+}
+
+func constructDeleteAll(tablename string) string {
+	return fmt.Sprintf("DELETE FROM %s", tablename)
+}
+
+func constructListWhere(query m.UserListQuery, cursor *repo.Cursor) (string, []interface{}) {
+	// WHERE "IsDeleted"=? AND LOWER("Email") LIKE ? ESCAPE '!' AND ("Name">? OR ("Name"=? AND "ID">?))
+	q := ` WHERE "IsDeleted"=?`
+	values := []interface{}{false}
+	if query.IsActive != nil {
+		q += ` AND "IsActive"=?`
+		values = append(values, *query.IsActive)
+	}
+	if query.IsGoogleAuth != nil {
+		q += ` AND "IsGoogleAuth"=?`
+		values = append(values, *query.IsGoogleAuth)
+	}
+	if query.EmailDomain != "" {
+		q += ` AND LOWER("Email") LIKE ? ESCAPE '!'`
+		values = append(values, "%@"+repo.EscapeLike(query.EmailDomain))
+	}
+	if query.NamePrefix != "" {
+		q += ` AND LOWER("Name") LIKE ? ESCAPE '!'`
+		values = append(values, repo.EscapeLike(query.NamePrefix)+"%")
+	}
+	if cursor != nil {
+		op := ">"
+		if query.Desc {
+			op = "<"
+		}
+		sortBy := quote(query.SortBy)
+		q += fmt.Sprintf(` AND (%s%s? OR (%s=? AND "ID"%s?))`, sortBy, op, sortBy, op)
+		values = append(values, cursor.Value, cursor.Value, cursor.ID)
+	}
+
+	return q, values
+}
+
+func constructListQuery(query m.UserListQuery, cursor *repo.Cursor) (string, []interface{}) {
+	// SELECT * FROM <tablename> WHERE ... ORDER BY "Name" ASC, "ID" ASC LIMIT 21
+	where, values := constructListWhere(query, cursor)
+	order := "ASC"
+	if query.Desc {
+		order = "DESC"
+	}
+	q := fmt.Sprintf(`SELECT * FROM %s%s ORDER BY %s %s, "ID" %s LIMIT %d`, new(m.User).TableName(), where, quote(query.SortBy), order, order, query.Limit+1)
+
+	return q, values
+}
+
+func constructCountQuery(query m.UserListQuery) (string, []interface{}) {
+	// SELECT COUNT(*) FROM <tablename> WHERE ...
+	where, values := constructListWhere(query, nil)
+	q := fmt.Sprintf("SELECT COUNT(*) FROM %s%s", new(m.User).TableName(), where)
+
+	return q, values
+}
@@ -0,0 +1,19 @@
+package sqlite
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestConstructUpdateQuery(t *testing.T) {
+	q, values := constructUpdateQuery("users", map[string]interface{}{"Name": "User 01", "Address": "User Address 01"}, map[string]interface{}{"ID": "userid01"})
+
+	expectedQuery := `UPDATE users SET "Address"=?, "Name"=? WHERE "ID"=? AND ("Address" IS NOT ? OR "Name" IS NOT ?)`
+	expectedValues := []interface{}{"User Address 01", "User 01", "userid01", "User Address 01", "User 01"}
+	if q != expectedQuery {
+		t.Errorf("query is not correct, want %s, got %s", expectedQuery, q)
+	}
+	if !reflect.DeepEqual(expectedValues, values) {
+		t.Errorf("values are not correct, want %v, got %v", expectedValues, values)
+	}
+}
@@ -0,0 +1,116 @@
+package sqlite
+
+import (
+	"context"
+	"time"
+
+	"github.com/rinosukmandityo/user-profile/helper"
+	m "github.com/rinosukmandityo/user-profile/models"
+	repo "github.com/rinosukmandityo/user-profile/repositories"
+
+	"database/sql"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/pkg/errors"
+)
+
+type twoFactorSQLiteRepository struct {
+	db      *sql.DB
+	timeout time.Duration
+}
+
+func NewTwoFactorRepository(db *sql.DB, timeout int) (repo.TwoFactorRepository, error) {
+	repo := &twoFactorSQLiteRepository{
+		db:      db,
+		timeout: time.Duration(timeout) * time.Second,
+	}
+	return repo, nil
+}
+
+func scanTwoFactor(row interface{ Scan(...interface{}) error }, res *m.TwoFactor) error {
+	if e := row.Scan(&res.ID, &res.Secret, &res.IsEnabled, &res.RecoveryCodes, &res.LastStep, &res.Created); e != nil {
+		return e
+	}
+	res.Created = res.Created.UTC()
+	return nil
+}
+
+func (r *twoFactorSQLiteRepository) GetBy(ctx context.Context, userID string) (m.TwoFactor, error) {
+	res := m.TwoFactor{}
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	q, dataFields := constructGetBy(res.TableName(), map[string]interface{}{"ID": userID})
+	if e := scanTwoFactor(repo.Conn(ctx, r.db).QueryRowContext(ctx, q, dataFields...), &res); e != nil {
+		if e == sql.ErrNoRows {
+			return m.TwoFactor{}, errors.Wrap(helper.ErrTwoFactorNotFound, "repository.TwoFactor.GetBy")
+		}
+		return m.TwoFactor{}, errors.Wrap(e, "repository.TwoFactor.GetBy")
+	}
+
+	return res, nil
+}
+
+func (r *twoFactorSQLiteRepository) Save(ctx context.Context, data *m.TwoFactor) error {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	q, dataFields := constructUpsertQuery(data.TableName(), data.SplitByField(), []string{"Secret", "IsEnabled", "RecoveryCodes", "LastStep", "Created"})
+	if _, e := repo.Conn(ctx, r.db).ExecContext(ctx, q, dataFields...); e != nil {
+		return errors.Wrap(e, "repository.TwoFactor.Save")
+	}
+
+	return nil
+}
+
+func (r *twoFactorSQLiteRepository) UpdateCodes(ctx context.Context, prev m.TwoFactor, lastStep int, recoveryCodes string) error {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	data := map[string]interface{}{"LastStep": lastStep, "RecoveryCodes": recoveryCodes}
+	filter := map[string]interface{}{"ID": prev.ID, "LastStep": prev.LastStep, "RecoveryCodes": prev.RecoveryCodes}
+	q, dataFields := constructUpdateQuery(prev.TableName(), data, filter)
+	res, e := repo.Conn(ctx, r.db).ExecContext(ctx, q, dataFields...)
+	if e != nil {
+		return errors.Wrap(e, "repository.TwoFactor.UpdateCodes")
+	}
+	count, e := res.RowsAffected()
+	if e != nil {
+		return errors.Wrap(e, "repository.TwoFactor.UpdateCodes")
+	}
+	if count == 0 {
+		return errors.Wrap(helper.ErrTwoFactorNotFound, "repository.TwoFactor.UpdateCodes")
+	}
+
+	return nil
+}
+
+func (r *twoFactorSQLiteRepository) Delete(ctx context.Context, userID string) error {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	q, dataFields := constructDelete(new(m.TwoFactor).TableName(), map[string]interface{}{"ID": userID})
+	if _, e := repo.Conn(ctx, r.db).ExecContext(ctx, q, dataFields...); e != nil {
+		return errors.Wrap(e, "repository.TwoFactor.Delete")
+	}
+
+	return nil
+}
+
+func (r *twoFactorSQLiteRepository) DeleteAll(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	res, e := repo.Conn(ctx, r.db).ExecContext(ctx, constructDeleteAll(new(m.TwoFactor).TableName()))
+	if e != nil {
+		return errors.Wrap(e, "repository.TwoFactor.DeleteAll")
+	}
+	count, e := res.RowsAffected()
+	if e != nil {
+		return errors.Wrap(e, "repository.TwoFactor.DeleteAll")
+	}
+	if count == 0 {
+		return errors.Wrap(helper.ErrTwoFactorNotFound, "repository.TwoFactor.DeleteAll")
+	}
+
+	return nil
+}
@@ -0,0 +1,198 @@
+package sqlite
+
+import (
+	"context"
+	"time"
+
+	"github.com/rinosukmandityo/user-profile/helper"
+	m "github.com/rinosukmandityo/user-profile/models"
+	repo "github.com/rinosukmandityo/user-profile/repositories"
+
+	"database/sql"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/pkg/errors"
+)
+
+type userSQLiteRepository struct {
+	db      *sql.DB
+	timeout time.Duration
+}
+
+func NewUserRepository(db *sql.DB, timeout int) (repo.UserRepository, error) {
+	repo := &userSQLiteRepository{
+		db:      db,
+		timeout: time.Duration(timeout) * time.Second,
+	}
+	return repo, nil
+}
+
+func scanUser(row interface{ Scan(...interface{}) error }, res *m.User) error {
+	return row.Scan(&res.ID, &res.Name, &res.Email, &res.Password, &res.Telephone, &res.Address, &res.IsActive, &res.IsGoogleAuth, &res.IsDeleted, &res.Deleted)
+}
+
+func (r *userSQLiteRepository) GetBy(ctx context.Context, filter map[string]interface{}) (m.User, error) {
+	return r.getBy(ctx, repo.NotDeleted(filter))
+}
+
+// getBy doesn't skip soft deleted users, Update uses it to read back any row it changed.
+func (r *userSQLiteRepository) getBy(ctx context.Context, filter map[string]interface{}) (m.User, error) {
+	if e := repo.CheckColumns(new(m.User).TableName(), filter); e != nil {
+		return m.User{}, errors.Wrap(e, "repository.User.GetBy")
+	}
+	res := m.User{}
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	q, dataFields := constructGetBy(new(m.User).TableName(), filter)
+	if e := scanUser(repo.Conn(ctx, r.db).QueryRowContext(ctx, q, dataFields...), &res); e != nil {
+		if e == sql.ErrNoRows {
+			return res, errors.Wrap(helper.ErrUserNotFound, "repository.User.GetBy")
+		}
+		return res, errors.Wrap(e, "repository.User.GetBy")
+	}
+
+	return res, nil
+}
+
+func (r *userSQLiteRepository) Store(ctx context.Context, data *m.User) error {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	q, dataFields := constructStoreQuery(data.TableName(), []interface{}{
+		data.ID, data.Name, data.Email, data.Password, data.Telephone, data.Address, data.IsActive, data.IsGoogleAuth, data.IsDeleted, data.Deleted,
+	})
+	if _, e := repo.Conn(ctx, r.db).ExecContext(ctx, q, dataFields...); e != nil {
+		if isDuplicateEntry(e) {
+			return errors.Wrap(repo.ErrDuplicateEntry(data.ID, "ID"), "repository.User.Store")
+		}
+		return errors.Wrap(e, "repository.User.Store")
+	}
+
+	return nil
+}
+
+func (r *userSQLiteRepository) Update(ctx context.Context, data map[string]interface{}, id string) (m.User, error) {
+	if e := repo.CheckColumns(new(m.User).TableName(), data); e != nil {
+		return m.User{}, errors.Wrap(e, "repository.User.Update")
+	}
+	user := m.User{}
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	q, dataFields := constructUpdateQuery(user.TableName(), data, map[string]interface{}{"ID": id})
+	res, e := repo.Conn(ctx, r.db).ExecContext(ctx, q, dataFields...)
+	if e != nil {
+		return user, errors.Wrap(e, "repository.User.Update")
+	}
+	count, e := res.RowsAffected()
+	if e != nil {
+		return user, errors.Wrap(e, "repository.User.Update")
+	}
+	if count == 0 {
+		return user, errors.Wrap(helper.ErrUserNotFound, "repository.User.Update")
+	}
+	user, e = r.getBy(ctx, map[string]interface{}{"ID": id})
+	if e != nil {
+		return user, errors.Wrap(e, "repository.User.Update")
+	}
+
+	return user, nil
+}
+
+func (r *userSQLiteRepository) List(ctx context.Context, query m.UserListQuery) (m.UserPage, error) {
+	query, cursor, e := repo.PrepareList(query)
+	if e != nil {
+		return m.UserPage{}, errors.Wrap(e, "repository.User.List")
+	}
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	total := 0
+	q, dataFields := constructCountQuery(query)
+	if e := repo.Conn(ctx, r.db).QueryRowContext(ctx, q, dataFields...).Scan(&total); e != nil {
+		return m.UserPage{}, errors.Wrap(e, "repository.User.List")
+	}
+
+	q, dataFields = constructListQuery(query, cursor)
+	results, e := repo.Conn(ctx, r.db).QueryContext(ctx, q, dataFields...)
+	if e != nil {
+		return m.UserPage{}, errors.Wrap(e, "repository.User.List")
+	}
+	defer results.Close()
+	users := []m.User{}
+	for results.Next() {
+		var item m.User
+		if e := scanUser(results, &item); e != nil {
+			return m.UserPage{}, errors.Wrap(e, "repository.User.List")
+		}
+		users = append(users, item)
+	}
+	if e := results.Err(); e != nil {
+		return m.UserPage{}, errors.Wrap(e, "repository.User.List")
+	}
+
+	return repo.NewPage(users, query, total), nil
+}
+
+func (r *userSQLiteRepository) Purge(ctx context.Context, deletedBefore time.Time) ([]m.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	q, dataFields := constructPurgeList(new(m.User).TableName(), deletedBefore)
+	results, e := repo.Conn(ctx, r.db).QueryContext(ctx, q, dataFields...)
+	if e != nil {
+		return nil, errors.Wrap(e, "repository.User.Purge")
+	}
+	defer results.Close()
+	candidates := []m.User{}
+	for results.Next() {
+		var item m.User
+		if e := scanUser(results, &item); e != nil {
+			return nil, errors.Wrap(e, "repository.User.Purge")
+		}
+		candidates = append(candidates, item)
+	}
+	if e := results.Err(); e != nil {
+		return nil, errors.Wrap(e, "repository.User.Purge")
+	}
+	results.Close()
+
+	// every user is deleted only while it is still deleted, so a user restored
+	// since it was listed is left alone
+	users := []m.User{}
+	for _, user := range candidates {
+		q, dataFields := constructPurge(new(m.User).TableName(), user.ID, deletedBefore)
+		res, e := repo.Conn(ctx, r.db).ExecContext(ctx, q, dataFields...)
+		if e != nil {
+			return nil, errors.Wrap(e, "repository.User.Purge")
+		}
+		count, e := res.RowsAffected()
+		if e != nil {
+			return nil, errors.Wrap(e, "repository.User.Purge")
+		}
+		if count > 0 {
+			users = append(users, user)
+		}
+	}
+
+	return users, nil
+}
+
+func (r *userSQLiteRepository) DeleteAll(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	res, e := repo.Conn(ctx, r.db).ExecContext(ctx, constructDeleteAll(new(m.User).TableName()))
+	if e != nil {
+		return errors.Wrap(e, "repository.User.DeleteAll")
+	}
+	count, e := res.RowsAffected()
+	if e != nil {
+		return errors.Wrap(e, "repository.User.DeleteAll")
+	}
+	if count == 0 {
+		return errors.Wrap(helper.ErrUserNotFound, "repository.User.DeleteAll")
+	}
+
+	return nil
+}
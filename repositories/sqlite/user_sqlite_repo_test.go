@@ -0,0 +1,29 @@
+package sqlite
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	repo "github.com/rinosukmandityo/user-profile/repositories"
+
+	"github.com/pkg/errors"
+)
+
+func TestGetByCancelledContext(t *testing.T) {
+	db, e := NewClient(filepath.Join(t.TempDir(), "context.db"), repo.PoolConfig{MaxOpenConns: 1})
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer db.Close()
+	if _, e = NewMigrator(db).Up(); e != nil {
+		t.Fatal(e)
+	}
+	userRepo, _ := NewUserRepository(db, 10)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, e = userRepo.GetBy(ctx, map[string]interface{}{"ID": "userid01"}); errors.Cause(e) != context.Canceled {
+		t.Errorf("error is not correct, want %v, got %v", context.Canceled, e)
+	}
+}
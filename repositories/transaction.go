@@ -0,0 +1,59 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/pkg/errors"
+)
+
+// Transactor runs fn as one unit of work. Repository calls made with the
+// context passed to fn join the transaction, it is committed when fn returns
+// nil and rolled back otherwise. Nested calls join the outer transaction.
+type Transactor interface {
+	WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// DBTX is implemented by both *sql.DB and *sql.Tx so SQL repositories can run
+// the same queries inside and outside a transaction.
+type DBTX interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+type txKey struct{}
+
+type sqlTransactor struct {
+	db *sql.DB
+}
+
+func NewSQLTransactor(db *sql.DB) Transactor {
+	return &sqlTransactor{db}
+}
+
+func (t *sqlTransactor) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return fn(ctx)
+	}
+	tx, e := t.db.BeginTx(ctx, nil)
+	if e != nil {
+		return errors.Wrap(e, "repository.Transaction.Begin")
+	}
+	if e = fn(context.WithValue(ctx, txKey{}, tx)); e != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return errors.Wrapf(e, "repository.Transaction.Rollback: %s", rbErr.Error())
+		}
+		return e
+	}
+	return errors.Wrap(tx.Commit(), "repository.Transaction.Commit")
+}
+
+// Conn returns the transaction joined by ctx, or db when there is none.
+func Conn(ctx context.Context, db *sql.DB) DBTX {
+	if tx, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return tx
+	}
+	return db
+}
@@ -0,0 +1,11 @@
+package services
+
+import (
+	"context"
+)
+
+type AccountService interface {
+	Delete(ctx context.Context, id string) error
+	Restore(ctx context.Context, email, password string) error
+	Purge(ctx context.Context) (int64, error)
+}
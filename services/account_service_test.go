@@ -0,0 +1,155 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rinosukmandityo/user-profile/helper"
+	m "github.com/rinosukmandityo/user-profile/models"
+	"github.com/rinosukmandityo/user-profile/services/logic"
+
+	"github.com/pkg/errors"
+)
+
+func TestDeleteAndRestoreAccount(t *testing.T) {
+	testData := m.User{
+		Name:     "User 01",
+		Password: "Password.1",
+		ID:       "userid01",
+		Email:    "usermail01@gmail.com",
+		Address:  "User Address 01",
+	}
+
+	defer func() {
+		if err := cleanupUserData(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	user := testData
+	if e := userService.Store(context.Background(), &user); e != nil {
+		t.Fatal(e)
+	}
+	tSession, e := sessionSvc.CreateNewSession(context.Background(), user, m.Client{})
+	if e != nil {
+		t.Fatal(e)
+	}
+	if _, _, e := tokenService.ResetPasswordByMail(context.Background(), user.Email, time.Hour); e != nil {
+		t.Fatal(e)
+	}
+
+	if e := accountSvc.Delete(context.Background(), user.ID); e != nil {
+		t.Fatalf("unable to delete account: %s", e.Error())
+	}
+	if _, e := userService.GetById(context.Background(), user.ID); errors.Cause(e) != helper.ErrUserNotFound {
+		t.Errorf("deleted user should not be found, got error %v", e)
+	}
+	if _, _, e := sessionSvc.Authenticate(context.Background(), user.Email, testData.Password); errors.Cause(e) != helper.ErrUserNotFound {
+		t.Errorf("deleted user should not authenticate, got error %v", e)
+	}
+	if _, e := sessionSvc.GetBy(context.Background(), map[string]interface{}{"ID": tSession.ID}); e == nil {
+		t.Errorf("session of a deleted user should be revoked")
+	}
+	if _, e := tokenRepo.GetLatestToken(context.Background(), user.ID, m.TokenResetPassword); e != helper.ErrTokenNotFound {
+		t.Errorf("token of a deleted user should be revoked, got error %v", e)
+	}
+	if e := userService.Store(context.Background(), &m.User{Name: "User 02", Password: "Password.1", Email: testData.Email}); errors.Cause(e) != helper.ErrEmailDuplicate {
+		t.Errorf("email of a deleted user should stay reserved, got error %v", e)
+	}
+
+	if e := accountSvc.Restore(context.Background(), user.Email, "wrong password"); errors.Cause(e) != helper.ErrPasswordDoesNotMatch {
+		t.Errorf("restore should check the password, got error %v", e)
+	}
+	if e := accountSvc.Restore(context.Background(), user.Email, testData.Password); e != nil {
+		t.Fatalf("unable to restore account: %s", e.Error())
+	}
+	actualData, e := userService.GetById(context.Background(), user.ID)
+	if e != nil {
+		t.Fatalf("restored user should be found: %s", e.Error())
+	}
+	if actualData.IsDeleted || actualData.Deleted != nil {
+		t.Errorf("restored user should not be flagged as deleted, got %+v", actualData)
+	}
+	if e := accountSvc.Restore(context.Background(), user.Email, testData.Password); errors.Cause(e) != helper.ErrUserNotFound {
+		t.Errorf("active user cannot be restored, got error %v", e)
+	}
+}
+
+func TestPurgeAccount(t *testing.T) {
+	testData := []m.User{{
+		Name:     "User 01",
+		Password: "Password.1",
+		ID:       "userid01",
+		Email:    "usermail01@gmail.com",
+	}, {
+		Name:     "User 02",
+		Password: "Password.1",
+		ID:       "userid02",
+		Email:    "usermail02@gmail.com",
+	}}
+
+	defer func() {
+		if err := cleanupUserData(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	defer func() {
+		if err := cleanupTwoFactorData(); err != nil {
+			t.Fatal(err)
+		}
+		if err := cleanupAttemptData(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	now := time.Now().UTC().Truncate(time.Second)
+	for _, data := range testData {
+		user := data
+		if e := userService.Store(context.Background(), &user); e != nil {
+			t.Fatal(e)
+		}
+		// the enrolment and failed logins of a purged account go with it
+		if e := twoFactorRepo.Save(context.Background(), &m.TwoFactor{ID: user.ID, Secret: "secret", IsEnabled: true, Created: now}); e != nil {
+			t.Fatal(e)
+		}
+		if e := attemptRepo.Save(context.Background(), &m.LoginAttempt{ID: "email:" + user.Email, Failures: 1, LastFailed: now, LockedUntil: now}); e != nil {
+			t.Fatal(e)
+		}
+	}
+	if e := accountSvc.Delete(context.Background(), testData[0].ID); e != nil {
+		t.Fatal(e)
+	}
+
+	// within the grace period nothing is purged
+	if count, e := accountSvc.Purge(context.Background()); e != nil || count != 0 {
+		t.Errorf("want 0 purged user, got %d, error %v", count, e)
+	}
+
+	expired := logic.NewAccountService(userRepo, sessionRepo, tokenRepo, twoFactorRepo, attemptRepo, transactor, -time.Minute)
+	if e := expired.Restore(context.Background(), testData[0].Email, testData[0].Password); errors.Cause(e) != helper.ErrRestoreExpired {
+		t.Errorf("restore after the grace period should fail, got error %v", e)
+	}
+	if count, e := expired.Purge(context.Background()); e != nil || count != 1 {
+		t.Errorf("want 1 purged user, got %d, error %v", count, e)
+	}
+	if _, e := userRepo.GetBy(context.Background(), map[string]interface{}{"ID": testData[0].ID, "IsDeleted": true}); errors.Cause(e) != helper.ErrUserNotFound {
+		t.Errorf("purged user should be removed, got error %v", e)
+	}
+	if _, e := userService.GetById(context.Background(), testData[1].ID); e != nil {
+		t.Errorf("active user should not be purged: %s", e.Error())
+	}
+	if _, e := twoFactorRepo.GetBy(context.Background(), testData[0].ID); errors.Cause(e) != helper.ErrTwoFactorNotFound {
+		t.Errorf("two factor enrolment of the purged user should be removed, got error %v", e)
+	}
+	if _, e := attemptRepo.GetBy(context.Background(), "email:"+testData[0].Email); errors.Cause(e) != helper.ErrAttemptNotFound {
+		t.Errorf("failed logins of the purged user should be removed, got error %v", e)
+	}
+	if _, e := twoFactorRepo.GetBy(context.Background(), testData[1].ID); e != nil {
+		t.Errorf("two factor enrolment of the active user should be kept: %s", e.Error())
+	}
+	if _, e := attemptRepo.GetBy(context.Background(), "email:"+testData[1].Email); e != nil {
+		t.Errorf("failed logins of the active user should be kept: %s", e.Error())
+	}
+}
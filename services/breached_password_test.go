@@ -0,0 +1,113 @@
+package services_test
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/rinosukmandityo/user-profile/helper"
+	m "github.com/rinosukmandityo/user-profile/models"
+	"github.com/rinosukmandityo/user-profile/services/logic"
+
+	"github.com/pkg/errors"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	if e := ioutil.WriteFile(path, []byte(content), 0644); e != nil {
+		t.Fatal(e)
+	}
+}
+
+func TestLoadBreachedPasswords(t *testing.T) {
+	rangeDir := t.TempDir()
+	// SHA-1 of Password.1 and Summer.2020, the padding entry of Welcome.123 has a count of 0
+	writeFile(t, filepath.Join(rangeDir, "AED7E.txt"), "5AAC63B817A978217A113994B99A5340D59:3\r\n0018A45C4D1DEF81644B54AB7F969B88D65:1\r\n")
+	writeFile(t, filepath.Join(rangeDir, "A5FBA"), "005BE934DCB8B37A0CDE83BD8435C18A8B7:12\n")
+	writeFile(t, filepath.Join(rangeDir, "1CF5A"), "B6FBE6C7A3E1DFFCD353A683BF1A0F75E9B:0\n")
+	writeFile(t, filepath.Join(rangeDir, "README"), "not a range file\n")
+
+	fullFile := filepath.Join(t.TempDir(), "pwned.txt")
+	writeFile(t, fullFile, "AED7E5AAC63B817A978217A113994B99A5340D59:3\na5fba005be934dcb8b37a0cde83bd8435c18a8b7\n")
+
+	invalidFile := filepath.Join(t.TempDir(), "invalid.txt")
+	writeFile(t, invalidFile, "AED7E5AAC63B817A978217A113994B99A5340D59:3\n5AAC63B817A978217A113994B99A5340D59:3\n")
+
+	testTable := []struct {
+		name        string
+		path        string
+		expectedLen int
+	}{
+		{
+			name:        "range_directory",
+			path:        rangeDir,
+			expectedLen: 3,
+		},
+		{
+			name:        "full_hash_file",
+			path:        fullFile,
+			expectedLen: 2,
+		},
+	}
+
+	for _, _tt := range testTable {
+		tt := _tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			breached, e := logic.LoadBreachedPasswords(tt.path)
+			if e != nil {
+				t.Fatalf("failed to load breached passwords: %s", e.Error())
+			}
+			if breached.Len() != tt.expectedLen {
+				t.Errorf("number of hashes is not correct, want %d, got %d", tt.expectedLen, breached.Len())
+			}
+			for _, password := range []string{"Password.1", "Summer.2020"} {
+				if !breached.Contains(password) {
+					t.Errorf("%s should be breached", password)
+				}
+			}
+			for _, password := range []string{"Welcome.123", "Password.2"} {
+				if breached.Contains(password) {
+					t.Errorf("%s should not be breached", password)
+				}
+			}
+		})
+	}
+
+	if _, e := logic.LoadBreachedPasswords(invalidFile); e == nil {
+		t.Error("a truncated hash should be rejected")
+	}
+}
+
+func TestStoreBreachedPassword(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "AED7E"), "5AAC63B817A978217A113994B99A5340D59:3\n")
+	breached, e := logic.LoadBreachedPasswords(dir)
+	if e != nil {
+		t.Fatal(e)
+	}
+	policy := logic.DefaultPasswordPolicy()
+	policy.Breached = breached
+	userSvc := logic.NewUserService(userRepo, transactor, policy)
+
+	defer func() {
+		if err := cleanupUserData(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	data := m.User{
+		Name:     "User 01",
+		Password: "Password.1",
+		ID:       "userid01",
+		Email:    "usermail01@gmail.com",
+	}
+	if e := userSvc.Store(context.Background(), &data); errors.Cause(e) != helper.ErrPasswordBreached {
+		t.Errorf("breached password should be rejected, got %v", e)
+	}
+
+	data.Password = "Password.2"
+	if e := userSvc.Store(context.Background(), &data); e != nil {
+		t.Errorf("failed to save data: %s", e.Error())
+	}
+}
@@ -0,0 +1,17 @@
+package services
+
+import (
+	"context"
+	"time"
+)
+
+type LockoutService interface {
+	// Check returns helper.ErrTooManyAttempts and the time logins are accepted
+	// again when the account or the IP address is locked
+	Check(ctx context.Context, email, ip string) (time.Time, error)
+	// Failure counts a failed login and returns the time until which the next one is refused
+	Failure(ctx context.Context, email, ip string) (time.Time, error)
+	// Success forgets the failed logins of the account, the IP address keeps its count
+	Success(ctx context.Context, email string) error
+	Purge(ctx context.Context) (int64, error)
+}
@@ -0,0 +1,202 @@
+package services_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rinosukmandityo/user-profile/helper"
+	m "github.com/rinosukmandityo/user-profile/models"
+	"github.com/rinosukmandityo/user-profile/services/logic"
+)
+
+func TestLockoutBackoff(t *testing.T) {
+	defer func() {
+		if e := cleanupAttemptData(); e != nil {
+			t.Fatal(e)
+		}
+	}()
+	ctx := context.Background()
+	email, ip := "usermail01@gmail.com", "10.0.0.1"
+	policy := logic.DefaultLockoutPolicy()
+
+	testTable := []struct {
+		name          string
+		expectedDelay time.Duration
+	}{
+		{name: "first_failure_is_free", expectedDelay: 0},
+		{name: "second_failure_is_free", expectedDelay: 0},
+		{name: "third_failure_backs_off", expectedDelay: policy.BaseDelay},
+		{name: "fourth_failure_doubles", expectedDelay: 2 * policy.BaseDelay},
+		{name: "fifth_failure_locks", expectedDelay: policy.Duration},
+	}
+
+	for _, tt := range testTable {
+		before := time.Now().UTC()
+		lockedUntil, e := lockoutSvc.Failure(ctx, email, ip)
+		if e != nil {
+			t.Fatalf("%s: failed to count failure: %s", tt.name, e.Error())
+		}
+		if delay := lockedUntil.Sub(before); delay < tt.expectedDelay || delay > tt.expectedDelay+time.Second {
+			t.Errorf("%s: delay is not correct, want %s, got %s", tt.name, tt.expectedDelay, delay)
+		}
+	}
+
+	// email is not case sensitive and every IP address is locked out of a locked account
+	if _, e := lockoutSvc.Check(ctx, "UserMail01@gmail.com", "10.0.0.2"); e != helper.ErrTooManyAttempts {
+		t.Errorf("account should be locked, got %v", e)
+	}
+	if _, e := lockoutSvc.Check(ctx, "usermail02@gmail.com", "10.0.0.2"); e != nil {
+		t.Errorf("other accounts should not be locked, got %v", e)
+	}
+
+	if e := lockoutSvc.Success(ctx, email); e != nil {
+		t.Fatal(e)
+	}
+	if _, e := lockoutSvc.Check(ctx, email, "10.0.0.2"); e != nil {
+		t.Errorf("account should be unlocked after a successful login, got %v", e)
+	}
+}
+
+func TestLockoutPerIP(t *testing.T) {
+	defer func() {
+		if e := cleanupAttemptData(); e != nil {
+			t.Fatal(e)
+		}
+	}()
+	ctx := context.Background()
+	policy := logic.DefaultLockoutPolicy()
+	policy.MaxIPFailures = 4
+	svc := logic.NewLockoutService(attemptRepo, policy)
+
+	// every failure is on another account so only the IP address reaches its limit
+	emails := []string{"usermail01@gmail.com", "usermail02@gmail.com", "usermail03@gmail.com", "usermail04@gmail.com"}
+	for _, email := range emails {
+		if _, e := svc.Failure(ctx, email, "10.0.0.1"); e != nil {
+			t.Fatal(e)
+		}
+	}
+	if _, e := svc.Check(ctx, "usermail05@gmail.com", "10.0.0.1"); e != helper.ErrTooManyAttempts {
+		t.Errorf("IP address should be locked, got %v", e)
+	}
+	if _, e := svc.Check(ctx, "usermail05@gmail.com", "10.0.0.2"); e != nil {
+		t.Errorf("other IP addresses should not be locked, got %v", e)
+	}
+}
+
+func TestLockoutConcurrentFailures(t *testing.T) {
+	defer func() {
+		if e := cleanupAttemptData(); e != nil {
+			t.Fatal(e)
+		}
+	}()
+	ctx := context.Background()
+	policy := logic.DefaultLockoutPolicy()
+	policy.MaxFailures = 100
+	svc := logic.NewLockoutService(attemptRepo, policy)
+
+	// a burst of guesses is counted as every one of them
+	const failures = 20
+	var wg sync.WaitGroup
+	for i := 0; i < failures; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, e := svc.Failure(ctx, "usermail01@gmail.com", ""); e != nil {
+				t.Error(e)
+			}
+		}()
+	}
+	wg.Wait()
+	attempt, e := attemptRepo.GetBy(ctx, "email:usermail01@gmail.com")
+	if e != nil {
+		t.Fatal(e)
+	}
+	if attempt.Failures != failures {
+		t.Errorf("failures are not counted, want %d, got %d", failures, attempt.Failures)
+	}
+}
+
+func TestLockoutExpires(t *testing.T) {
+	defer func() {
+		if e := cleanupAttemptData(); e != nil {
+			t.Fatal(e)
+		}
+	}()
+	ctx := context.Background()
+	policy := logic.DefaultLockoutPolicy()
+	policy.MaxFailures = 1
+	policy.Duration = 100 * time.Millisecond
+	policy.ResetAfter = 100 * time.Millisecond
+	svc := logic.NewLockoutService(attemptRepo, policy)
+
+	if _, e := svc.Failure(ctx, "usermail01@gmail.com", ""); e != nil {
+		t.Fatal(e)
+	}
+	if _, e := svc.Check(ctx, "usermail01@gmail.com", ""); e != helper.ErrTooManyAttempts {
+		t.Errorf("account should be locked, got %v", e)
+	}
+
+	time.Sleep(2 * policy.Duration)
+	if _, e := svc.Check(ctx, "usermail01@gmail.com", ""); e != nil {
+		t.Errorf("lockout should have expired, got %v", e)
+	}
+
+	time.Sleep(policy.ResetAfter)
+	if count, e := svc.Purge(ctx); e != nil || count != 1 {
+		t.Errorf("Purge removed %d counters with error %v, want 1", count, e)
+	}
+}
+
+func TestPasswordResetUnlocksAccount(t *testing.T) {
+	userData := []m.User{{
+		Name:     "User 01",
+		Password: "Password.1",
+		ID:       "userid01",
+		Email:    "usermail01@gmail.com",
+	}}
+
+	defer func() {
+		if e := cleanupUserData(); e != nil {
+			t.Fatal(e)
+		}
+		if e := cleanupTokenData(); e != nil {
+			t.Fatal(e)
+		}
+		if e := cleanupAttemptData(); e != nil {
+			t.Fatal(e)
+		}
+	}()
+
+	if e := seedUserData(userData); e != nil {
+		t.Fatal(e)
+	}
+	tokenData := m.Token{
+		ID:      "token01",
+		UserID:  userData[0].ID,
+		Created: time.Now().UTC(),
+		Expired: time.Now().UTC().Add(time.Minute * 30),
+		Purpose: m.TokenResetPassword,
+	}
+	if e := tokenRepo.Store(context.Background(), &tokenData); e != nil {
+		t.Fatal(e)
+	}
+
+	ctx := context.Background()
+	for i := 0; i < logic.DefaultLockoutPolicy().MaxFailures; i++ {
+		if _, e := lockoutSvc.Failure(ctx, userData[0].Email, ""); e != nil {
+			t.Fatal(e)
+		}
+	}
+	if _, e := lockoutSvc.Check(ctx, userData[0].Email, ""); e != helper.ErrTooManyAttempts {
+		t.Fatalf("account should be locked, got %v", e)
+	}
+
+	if e := tokenService.ChangePasswordToken(ctx, userData[0].ID, "New.Password.2", tokenData.ID); e != nil {
+		t.Fatalf("failed to change password with token: %s", e.Error())
+	}
+	if _, e := lockoutSvc.Check(ctx, userData[0].Email, ""); e != nil {
+		t.Errorf("account should be unlocked after a password reset, got %v", e)
+	}
+}
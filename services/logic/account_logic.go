@@ -0,0 +1,94 @@
+package logic
+
+import (
+	"context"
+	"time"
+
+	"github.com/rinosukmandityo/user-profile/helper"
+	repo "github.com/rinosukmandityo/user-profile/repositories"
+	svc "github.com/rinosukmandityo/user-profile/services"
+
+	"github.com/pkg/errors"
+)
+
+type accountService struct {
+	userRepo      repo.UserRepository
+	sessionRepo   repo.SessionRepository
+	tokenRepo     repo.TokenRepository
+	twoFactorRepo repo.TwoFactorRepository
+	attemptRepo   repo.LoginAttemptRepository
+	transactor    repo.Transactor
+	gracePeriod   time.Duration
+}
+
+// NewAccountService returns a service which soft deletes accounts, a deleted
+// account can be restored until gracePeriod has passed and Purge removes it.
+func NewAccountService(userRepo repo.UserRepository, sessionRepo repo.SessionRepository, tokenRepo repo.TokenRepository, twoFactorRepo repo.TwoFactorRepository, attemptRepo repo.LoginAttemptRepository, transactor repo.Transactor, gracePeriod time.Duration) svc.AccountService {
+	return &accountService{
+		userRepo, sessionRepo, tokenRepo, twoFactorRepo, attemptRepo, transactor, gracePeriod,
+	}
+}
+
+func (u *accountService) Delete(ctx context.Context, id string) error {
+	now := time.Now().UTC()
+	e := u.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		if _, e := u.userRepo.Update(ctx, map[string]interface{}{"IsDeleted": true, "Deleted": &now}, id); e != nil {
+			return e
+		}
+		// a deleted account must not stay signed in or keep a working reset link
+		if e := u.sessionRepo.DeleteByUser(ctx, id); e != nil {
+			return e
+		}
+		return u.tokenRepo.DeleteByUser(ctx, id)
+	})
+	if e != nil {
+		return errors.Wrap(e, "service.Account.Delete")
+	}
+	return nil
+}
+
+func (u *accountService) Restore(ctx context.Context, email, password string) error {
+	user, e := u.userRepo.GetBy(ctx, map[string]interface{}{"Email": email, "IsDeleted": true})
+	if e != nil {
+		if errors.Cause(e) == helper.ErrUserNotFound {
+			verifyDummy(password)
+		}
+		return errors.Wrap(e, "service.Account.Restore")
+	}
+	if !repo.IsPasswordMatch(password, user.Password) {
+		return errors.Wrap(helper.ErrPasswordDoesNotMatch, "service.Account.Restore")
+	}
+	if user.Deleted == nil || time.Since(*user.Deleted) > u.gracePeriod {
+		return errors.Wrap(helper.ErrRestoreExpired, "service.Account.Restore")
+	}
+	if _, e := u.userRepo.Update(ctx, map[string]interface{}{"IsDeleted": false, "Deleted": (*time.Time)(nil)}, user.ID); e != nil {
+		return errors.Wrap(e, "service.Account.Restore")
+	}
+	return nil
+}
+
+// Purge removes the accounts deleted longer than gracePeriod ago together with
+// their two factor enrolment and failed login counter.
+func (u *accountService) Purge(ctx context.Context) (int64, error) {
+	var count int64
+	e := u.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		users, e := u.userRepo.Purge(ctx, time.Now().UTC().Add(-u.gracePeriod))
+		if e != nil {
+			return e
+		}
+		for _, user := range users {
+			if e := u.twoFactorRepo.Delete(ctx, user.ID); e != nil {
+				return e
+			}
+			if e := u.attemptRepo.Delete(ctx, accountAttemptID(user.Email)); e != nil {
+				return e
+			}
+		}
+		count = int64(len(users))
+		return nil
+	})
+	if e != nil {
+		return 0, errors.Wrap(e, "service.Account.Purge")
+	}
+	return count, nil
+}
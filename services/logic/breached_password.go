@@ -0,0 +1,110 @@
+package logic
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	rangePrefixLength = 5
+	sha1HexLength     = sha1.Size * 2
+)
+
+// BreachedPasswords is an in-memory index of known-compromised passwords, it keeps
+// only the first 8 bytes of every SHA-1 hash in a sorted slice. A false positive
+// needs a 64-bit collision, which is negligible even for the full HIBP corpus.
+type BreachedPasswords struct {
+	hashes []uint64
+}
+
+// LoadBreachedPasswords reads SHA-1 hashes in the HIBP range format, lines of
+// <suffix>:<count>. path is either a directory of range files named after their
+// 5 character prefix, or a single file whose lines hold the full hash.
+// Padding entries with a count of 0 are skipped.
+func LoadBreachedPasswords(path string) (*BreachedPasswords, error) {
+	info, e := os.Stat(path)
+	if e != nil {
+		return nil, e
+	}
+	files := []string{path}
+	if info.IsDir() {
+		if files, e = filepath.Glob(filepath.Join(path, "*")); e != nil {
+			return nil, e
+		}
+	}
+
+	b := &BreachedPasswords{}
+	for _, file := range files {
+		prefix := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+		if !info.IsDir() {
+			prefix = ""
+		} else if len(prefix) != rangePrefixLength {
+			continue
+		}
+		if e := b.loadFile(file, prefix); e != nil {
+			return nil, e
+		}
+	}
+
+	sort.Slice(b.hashes, func(i, j int) bool { return b.hashes[i] < b.hashes[j] })
+	unique := b.hashes[:0]
+	for i, hash := range b.hashes {
+		if i == 0 || hash != b.hashes[i-1] {
+			unique = append(unique, hash)
+		}
+	}
+	b.hashes = unique
+	return b, nil
+}
+
+func (b *BreachedPasswords) loadFile(file, prefix string) error {
+	f, e := os.Open(file)
+	if e != nil {
+		return e
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		entry := strings.TrimSpace(scanner.Text())
+		if entry == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, ":", 2)
+		if len(parts) == 2 && strings.TrimSpace(parts[1]) == "0" {
+			continue
+		}
+		hash, e := hex.DecodeString(prefix + parts[0])
+		if e != nil || len(prefix)+len(parts[0]) != sha1HexLength {
+			return fmt.Errorf("%s:%d: invalid SHA-1 hash", file, line)
+		}
+		b.hashes = append(b.hashes, binary.BigEndian.Uint64(hash))
+	}
+	return scanner.Err()
+}
+
+// Len returns the number of distinct hashes in the index.
+func (b *BreachedPasswords) Len() int {
+	if b == nil {
+		return 0
+	}
+	return len(b.hashes)
+}
+
+// Contains reports whether password is in the index, a nil index contains nothing.
+func (b *BreachedPasswords) Contains(password string) bool {
+	if b == nil {
+		return false
+	}
+	sum := sha1.Sum([]byte(password))
+	hash := binary.BigEndian.Uint64(sum[:])
+	i := sort.Search(len(b.hashes), func(i int) bool { return b.hashes[i] >= hash })
+	return i < len(b.hashes) && b.hashes[i] == hash
+}
@@ -0,0 +1,143 @@
+package logic
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/rinosukmandityo/user-profile/helper"
+	repo "github.com/rinosukmandityo/user-profile/repositories"
+	svc "github.com/rinosukmandityo/user-profile/services"
+
+	"github.com/pkg/errors"
+)
+
+// LockoutPolicy allows the first half of MaxFailures for free, every failed login
+// after that doubles the delay starting from BaseDelay until MaxFailures locks
+// for Duration. The IP address limit is higher because many users can share
+// one address. Failures are forgotten once ResetAfter has passed since the last one.
+type LockoutPolicy struct {
+	MaxFailures   int
+	MaxIPFailures int
+	BaseDelay     time.Duration
+	Duration      time.Duration
+	ResetAfter    time.Duration
+}
+
+func DefaultLockoutPolicy() LockoutPolicy {
+	return LockoutPolicy{
+		MaxFailures:   5,
+		MaxIPFailures: 20,
+		BaseDelay:     time.Second,
+		Duration:      15 * time.Minute,
+		ResetAfter:    24 * time.Hour,
+	}
+}
+
+// delay is how long logins are refused after the failures-th failure.
+func (p LockoutPolicy) delay(failures, maxFailures int) time.Duration {
+	free := maxFailures / 2
+	if failures <= free {
+		return 0
+	}
+	if failures >= maxFailures || failures-free > 30 {
+		return p.Duration
+	}
+	if d := p.BaseDelay << uint(failures-free-1); d < p.Duration {
+		return d
+	}
+	return p.Duration
+}
+
+// accountAttemptID is the key of the failed logins of an account, unknown
+// emails are counted as well so a lockout doesn't tell which accounts exist.
+func accountAttemptID(email string) string {
+	return "email:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipAttemptID(ip string) string {
+	return "ip:" + ip
+}
+
+type lockoutService struct {
+	attemptRepo repo.LoginAttemptRepository
+	policy      LockoutPolicy
+}
+
+func NewLockoutService(attemptRepo repo.LoginAttemptRepository, policy LockoutPolicy) svc.LockoutService {
+	return &lockoutService{
+		attemptRepo, policy,
+	}
+}
+
+// limits returns the tracked keys with their failure limit, an unknown IP address is not tracked.
+func (u *lockoutService) limits(email, ip string) map[string]int {
+	limits := map[string]int{accountAttemptID(email): u.policy.MaxFailures}
+	if ip != "" {
+		limits[ipAttemptID(ip)] = u.policy.MaxIPFailures
+	}
+	return limits
+}
+
+func (u *lockoutService) Check(ctx context.Context, email, ip string) (time.Time, error) {
+	now := time.Now().UTC()
+	lockedUntil := time.Time{}
+	for id := range u.limits(email, ip) {
+		attempt, e := u.attemptRepo.GetBy(ctx, id)
+		if errors.Cause(e) == helper.ErrAttemptNotFound {
+			continue
+		}
+		if e != nil {
+			return lockedUntil, errors.Wrap(e, "service.Lockout.Check")
+		}
+		if attempt.LockedUntil.After(now) && attempt.LockedUntil.After(lockedUntil) {
+			lockedUntil = attempt.LockedUntil
+		}
+	}
+	if !lockedUntil.IsZero() {
+		return lockedUntil, helper.ErrTooManyAttempts
+	}
+	return lockedUntil, nil
+}
+
+// Failure counts the failure in the database rather than writing back a count read
+// before, so a burst of concurrent failed logins is counted in full.
+func (u *lockoutService) Failure(ctx context.Context, email, ip string) (time.Time, error) {
+	now := time.Now().UTC()
+	lockedUntil := now
+	for id, maxFailures := range u.limits(email, ip) {
+		attempt, e := u.attemptRepo.Increment(ctx, id, now, now.Add(-u.policy.ResetAfter))
+		if e != nil {
+			return lockedUntil, errors.Wrap(e, "service.Lockout.Failure")
+		}
+		until := now.Add(u.policy.delay(attempt.Failures, maxFailures))
+		if until.After(attempt.LockedUntil) {
+			if e := u.attemptRepo.Lock(ctx, id, until); e != nil {
+				return lockedUntil, errors.Wrap(e, "service.Lockout.Failure")
+			}
+		} else {
+			until = attempt.LockedUntil
+		}
+		if until.After(lockedUntil) {
+			lockedUntil = until
+		}
+	}
+	return lockedUntil, nil
+}
+
+func (u *lockoutService) Success(ctx context.Context, email string) error {
+	if e := u.attemptRepo.Delete(ctx, accountAttemptID(email)); e != nil {
+		return errors.Wrap(e, "service.Lockout.Success")
+	}
+	return nil
+}
+
+// Purge removes the counters whose lock ended more than ResetAfter ago, they
+// would be reset by the next failure anyway.
+func (u *lockoutService) Purge(ctx context.Context) (int64, error) {
+	count, e := u.attemptRepo.Purge(ctx, time.Now().UTC().Add(-u.policy.ResetAfter))
+	if e != nil {
+		return 0, errors.Wrap(e, "service.Lockout.Purge")
+	}
+	return count, nil
+}
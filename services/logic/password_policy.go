@@ -0,0 +1,102 @@
+package logic
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/rinosukmandityo/user-profile/helper"
+	m "github.com/rinosukmandityo/user-profile/models"
+)
+
+// minPersonalLength keeps short name parts like initials from banning
+// half of the possible passwords.
+const minPersonalLength = 3
+
+// PasswordPolicy is checked wherever a password is set, MinLength is counted in
+// characters and MaxLength in bytes, which is what the hashers limit.
+type PasswordPolicy struct {
+	MinLength     int
+	MaxLength     int
+	RequireLower  bool
+	RequireUpper  bool
+	RequireDigit  bool
+	RequireSymbol bool
+	// Breached rejects known-compromised passwords, nil skips the check.
+	Breached *BreachedPasswords
+}
+
+// DefaultPasswordPolicy stops at 72 bytes because bcrypt ignores anything after them.
+func DefaultPasswordPolicy() PasswordPolicy {
+	return PasswordPolicy{
+		MinLength:    8,
+		MaxLength:    72,
+		RequireLower: true,
+		RequireUpper: true,
+		RequireDigit: true,
+	}
+}
+
+// Validate returns a *helper.PasswordPolicyError listing every broken rule,
+// helper.ErrPasswordBreached for a compromised password, or nil when the
+// password of user is allowed.
+func (p PasswordPolicy) Validate(password string, user m.User) error {
+	violations := []string{}
+	if utf8.RuneCountInString(password) < p.MinLength {
+		violations = append(violations, fmt.Sprintf(helper.ErrPasswordTooShort, p.MinLength))
+	}
+	if p.MaxLength > 0 && len(password) > p.MaxLength {
+		violations = append(violations, fmt.Sprintf(helper.ErrPasswordTooLong, p.MaxLength))
+	}
+
+	var hasLower, hasUpper, hasDigit, hasSymbol bool
+	for _, c := range password {
+		switch {
+		case unicode.IsLower(c):
+			hasLower = true
+		case unicode.IsUpper(c):
+			hasUpper = true
+		case unicode.IsDigit(c):
+			hasDigit = true
+		case unicode.IsPunct(c) || unicode.IsSymbol(c) || unicode.IsSpace(c):
+			hasSymbol = true
+		}
+	}
+	if p.RequireLower && !hasLower {
+		violations = append(violations, helper.ErrPasswordNoLower)
+	}
+	if p.RequireUpper && !hasUpper {
+		violations = append(violations, helper.ErrPasswordNoUpper)
+	}
+	if p.RequireDigit && !hasDigit {
+		violations = append(violations, helper.ErrPasswordNoDigit)
+	}
+	if p.RequireSymbol && !hasSymbol {
+		violations = append(violations, helper.ErrPasswordNoSymbol)
+	}
+
+	lower := strings.ToLower(password)
+	email := strings.ToLower(user.Email)
+	if containsPersonal(lower, email) || containsPersonal(lower, strings.Split(email, "@")[0]) {
+		violations = append(violations, helper.ErrPasswordHasEmail)
+	}
+	for _, name := range append([]string{user.Name}, strings.Fields(user.Name)...) {
+		if containsPersonal(lower, strings.ToLower(name)) {
+			violations = append(violations, helper.ErrPasswordHasName)
+			break
+		}
+	}
+
+	if len(violations) > 0 {
+		return &helper.PasswordPolicyError{Violations: violations}
+	}
+	if p.Breached.Contains(password) {
+		return helper.ErrPasswordBreached
+	}
+	return nil
+}
+
+func containsPersonal(password, personal string) bool {
+	return utf8.RuneCountInString(personal) >= minPersonalLength && strings.Contains(password, personal)
+}
@@ -1,72 +1,308 @@
 package logic
 
 import (
-	"fmt"
+	"context"
+	"log"
+	"sort"
+	"sync"
 	"time"
 
+	"github.com/rinosukmandityo/user-profile/helper"
+	"github.com/rinosukmandityo/user-profile/helper/ids"
 	m "github.com/rinosukmandityo/user-profile/models"
 	repo "github.com/rinosukmandityo/user-profile/repositories"
 	svc "github.com/rinosukmandityo/user-profile/services"
+
+	"github.com/pkg/errors"
 )
 
 var (
-	_expiredduration = time.Duration(time.Minute * 30)
+	// _pendingduration is how long the second factor can be entered after the password
+	_pendingduration = time.Duration(time.Minute * 5)
+
+	dummyHashOnce sync.Once
+	dummyHash     string
 )
 
-type sessionService struct {
-	sessionRepo repo.SessionRepository
+// verifyDummy spends the time of a password check on an unknown email, so the
+// response time doesn't tell whether an account exists.
+func verifyDummy(password string) {
+	dummyHashOnce.Do(func() {
+		dummyHash, _ = repo.EncryptPassword("dummy password")
+	})
+	repo.IsPasswordMatch(password, dummyHash)
 }
 
-func NewSessionService(sessionRepo repo.SessionRepository) svc.SessionService {
-	return &sessionService{
-		sessionRepo,
+// SessionPolicy ends a session after IdleTimeout without requests and, however
+// active it is, Lifetime after the login. A remembered session lasts
+// RememberLifetime without an idle timeout. Requests extend a session at most once
+// per TouchInterval, so that not every request writes to the database.
+type SessionPolicy struct {
+	IdleTimeout      time.Duration
+	Lifetime         time.Duration
+	RememberLifetime time.Duration
+	TouchInterval    time.Duration
+}
+
+func DefaultSessionPolicy() SessionPolicy {
+	return SessionPolicy{
+		IdleTimeout:      30 * time.Minute,
+		Lifetime:         12 * time.Hour,
+		RememberLifetime: 30 * 24 * time.Hour,
+		TouchInterval:    time.Minute,
 	}
 }
 
-func (u *sessionService) Authenticate(email, password string) (found bool, user m.User, e error) {
-	return u.sessionRepo.Authenticate(email, password)
+// deadline is when sess ends however active it is.
+func (p SessionPolicy) deadline(sess m.Session) time.Time {
+	if sess.IsRemembered {
+		return sess.Created.Add(p.RememberLifetime)
+	}
+	return sess.Created.Add(p.Lifetime)
 }
 
-func (u *sessionService) GetBy(filter map[string]interface{}) (m.Session, error) {
-	return u.sessionRepo.GetBy(filter)
+// expiry is when sess ends if there is no request after now.
+func (p SessionPolicy) expiry(sess m.Session, now time.Time) time.Time {
+	idle := p.IdleTimeout
+	if sess.IsRemembered {
+		idle = p.RememberLifetime
+	}
+	if deadline := p.deadline(sess); now.Add(idle).After(deadline) {
+		return deadline
+	}
+	return now.Add(idle)
 }
 
-func (u *sessionService) CreateNewSession(user m.User) (tSession m.Session, e error) {
-	tSession, _ = u.sessionRepo.GetBy(map[string]interface{}{"Email": user.Email})
+type sessionService struct {
+	sessionRepo repo.SessionRepository
+	userRepo    repo.UserRepository
+	transactor  repo.Transactor
+	policy      SessionPolicy
+}
 
-	if tSession.ID == "" || tSession.Expired.Before(time.Now().UTC()) {
-		tSession.ID = fmt.Sprintf("%d", time.Now().UTC().UnixNano())
-		tSession.UserID = user.ID
-		tSession.Email = user.Email
-		tSession.Created = time.Now().UTC()
-		tSession.Expired = time.Now().UTC().Add(_expiredduration)
+func NewSessionService(sessionRepo repo.SessionRepository, userRepo repo.UserRepository, transactor repo.Transactor, policy SessionPolicy) svc.SessionService {
+	return &sessionService{
+		sessionRepo, userRepo, transactor, policy,
+	}
+}
 
-		e = u.sessionRepo.Store(&tSession)
-	} else {
-		tSession.Expired = time.Now().UTC().Add(_expiredduration)
+// Authenticate upgrades the stored hash when it was made by an older hasher,
+// the plain password is only known right after it has been verified.
+func (u *sessionService) Authenticate(ctx context.Context, email, password string) (found bool, user m.User, e error) {
+	found, user, e = u.sessionRepo.Authenticate(ctx, email, password)
+	if errors.Cause(e) == helper.ErrUserNotFound {
+		verifyDummy(password)
+	}
+	if e != nil || !found || !repo.NeedsRehash(user.Password) {
+		return
+	}
+	hash, rehashErr := repo.EncryptPassword(password)
+	if rehashErr == nil {
+		_, rehashErr = u.userRepo.Update(ctx, map[string]interface{}{"Password": hash}, user.ID)
+	}
+	if rehashErr != nil {
+		// the login itself succeeded, the hash is upgraded on a later login
+		log.Printf("Error on rehash password of user %s: %s\n", user.ID, rehashErr.Error())
+		return
+	}
+	user.Password = hash
+	return
+}
+
+func (u *sessionService) GetBy(ctx context.Context, filter map[string]interface{}) (m.Session, error) {
+	return u.sessionRepo.GetBy(ctx, filter)
+}
 
-		_, e = u.sessionRepo.Update(map[string]interface{}{"Expired": tSession.Expired}, tSession.ID)
+func (u *sessionService) CreateNewSession(ctx context.Context, user m.User, client m.Client) (m.Session, error) {
+	tSession, e := u.newSession(ctx, user, client, false)
+	if e != nil {
+		return tSession, errors.Wrap(e, "service.Session.CreateNewSession")
 	}
+	return tSession, nil
+}
 
-	return
+// newSession stores a full session, twoFactor tells whether its login passed the second factor.
+func (u *sessionService) newSession(ctx context.Context, user m.User, client m.Client, twoFactor bool) (m.Session, error) {
+	// the session ID is the credential of the session cookie, it must not be guessable
+	id, e := ids.NewSecret()
+	if e != nil {
+		return m.Session{}, e
+	}
+	now := time.Now().UTC()
+	tSession := m.Session{
+		ID:           id,
+		UserID:       user.ID,
+		Email:        user.Email,
+		Created:      now,
+		IP:           client.IP,
+		UserAgent:    client.UserAgent,
+		LastSeen:     now,
+		IsRemembered: client.Remember,
+		IsTwoFactor:  twoFactor,
+	}
+	tSession.Expired = u.policy.expiry(tSession, now)
+	if e := u.sessionRepo.Store(ctx, &tSession); e != nil {
+		return tSession, e
+	}
+	return tSession, nil
 }
 
-func (u *sessionService) IsSessionActive(id string) (stat bool, sess m.Session, e error) {
+func (u *sessionService) IsSessionActive(ctx context.Context, id string) (stat bool, sess m.Session, e error) {
 	sess = m.Session{}
 
-	sess, e = u.sessionRepo.GetBy(map[string]interface{}{"ID": id})
+	sess, e = u.sessionRepo.GetBy(ctx, map[string]interface{}{"ID": id})
 	if e != nil {
 		return
 	}
-	if sess.Expired.Before(time.Now().UTC()) {
+	now := time.Now().UTC()
+	if sess.IsPending || sess.Expired.Before(now) || u.policy.deadline(sess).Before(now) {
 		return
 	}
+	if now.Sub(sess.LastSeen) >= u.policy.TouchInterval {
+		// activity slides the idle timeout, the session is still valid if the write fails
+		// unless it failed because the session was logged out after it was read
+		expired := u.policy.expiry(sess, now)
+		if touchErr := u.sessionRepo.Touch(ctx, sess, now, expired); errors.Cause(touchErr) == helper.ErrSessionNotFound {
+			return
+		} else if touchErr != nil {
+			log.Printf("Error on extending session: %s\n", touchErr.Error())
+		} else {
+			sess.LastSeen = now
+			sess.Expired = expired
+		}
+	}
 	stat = true
 	return
 }
 
-func (u *sessionService) Logout(data m.Session) error {
-	_, e := u.sessionRepo.Update(map[string]interface{}{"Expired": time.Now().UTC()}, data.ID)
+func (u *sessionService) CreatePendingSession(ctx context.Context, user m.User, client m.Client) (m.Session, error) {
+	id, e := ids.NewSecret()
+	if e != nil {
+		return m.Session{}, errors.Wrap(e, "service.Session.CreatePendingSession")
+	}
+	now := time.Now().UTC()
+	tSession := m.Session{
+		ID:        id,
+		UserID:    user.ID,
+		Email:     user.Email,
+		Created:   now,
+		Expired:   now.Add(_pendingduration),
+		IsPending: true,
+		IP:        client.IP,
+		UserAgent: client.UserAgent,
+		LastSeen:  now,
+		// the full session is remembered once the second factor is verified
+		IsRemembered: client.Remember,
+	}
+	if e := u.sessionRepo.Store(ctx, &tSession); e != nil {
+		return tSession, errors.Wrap(e, "service.Session.CreatePendingSession")
+	}
+	return tSession, nil
+}
+
+func (u *sessionService) GetPendingSession(ctx context.Context, id string) (m.Session, error) {
+	sess, e := u.sessionRepo.GetBy(ctx, map[string]interface{}{"ID": id})
+	if errors.Cause(e) == helper.ErrUserNotFound || e == nil && (!sess.IsPending || sess.Expired.Before(time.Now().UTC())) {
+		return m.Session{}, errors.Wrap(helper.ErrSessionNotFound, "service.Session.GetPendingSession")
+	}
+	if e != nil {
+		return m.Session{}, errors.Wrap(e, "service.Session.GetPendingSession")
+	}
+	return sess, nil
+}
+
+// CompletePendingSession ends pending with a conditional write in the transaction which
+// starts the full session, so a pending session read by two requests is completed once.
+func (u *sessionService) CompletePendingSession(ctx context.Context, pending m.Session) (m.Session, error) {
+	if !pending.IsPending {
+		return m.Session{}, errors.Wrap(helper.ErrSessionNotFound, "service.Session.CompletePendingSession")
+	}
+	var tSession m.Session
+	e := u.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		now := time.Now().UTC()
+		if e := u.sessionRepo.Touch(ctx, pending, now, now); e != nil {
+			return e
+		}
+		client := m.Client{IP: pending.IP, UserAgent: pending.UserAgent, Remember: pending.IsRemembered}
+		var e error
+		tSession, e = u.newSession(ctx, m.User{ID: pending.UserID, Email: pending.Email}, client, true)
+		return e
+	})
+	if e != nil {
+		return m.Session{}, errors.Wrap(e, "service.Session.CompletePendingSession")
+	}
+	return tSession, nil
+}
+
+func (u *sessionService) Rotate(ctx context.Context, old m.Session, user m.User) (m.Session, error) {
+	id, e := ids.NewSecret()
+	if e != nil {
+		return m.Session{}, errors.Wrap(e, "service.Session.Rotate")
+	}
+	tSession := old
+	tSession.ID = id
+	tSession.UserID = user.ID
+	tSession.Email = user.Email
+	tSession.LastSeen = time.Now().UTC()
+	// the old ID stops working in the same transaction the new one starts in,
+	// both are never live at once
+	e = u.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		if e := u.sessionRepo.Store(ctx, &tSession); e != nil {
+			return e
+		}
+		return u.Logout(ctx, old)
+	})
+	if e != nil {
+		return m.Session{}, errors.Wrap(e, "service.Session.Rotate")
+	}
+	return tSession, nil
+}
+
+func (u *sessionService) ListSessions(ctx context.Context, userID string) ([]m.Session, error) {
+	sessions, e := u.sessionRepo.ListByUser(ctx, userID)
+	if e != nil {
+		return nil, errors.Wrap(e, "service.Session.ListSessions")
+	}
+	now := time.Now().UTC()
+	active := []m.Session{}
+	for _, sess := range sessions {
+		if !sess.IsPending && !sess.Expired.Before(now) {
+			active = append(active, sess)
+		}
+	}
+	sort.Slice(active, func(i, j int) bool {
+		return active[i].LastSeen.After(active[j].LastSeen)
+	})
+	return active, nil
+}
+
+func (u *sessionService) Logout(ctx context.Context, data m.Session) error {
+	_, e := u.sessionRepo.Update(ctx, map[string]interface{}{"Expired": time.Now().UTC()}, data.ID)
 	return e
 
 }
+
+// LogoutAll ends every session of userID which hasn't expired yet, pending
+// sessions included so that a login waiting for its second factor can't be completed.
+func (u *sessionService) LogoutAll(ctx context.Context, userID string) error {
+	e := u.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		sessions, e := u.sessionRepo.ListByUser(ctx, userID)
+		if e != nil {
+			return e
+		}
+		now := time.Now().UTC()
+		for _, sess := range sessions {
+			if sess.Expired.Before(now) {
+				continue
+			}
+			if e := u.Logout(ctx, sess); e != nil {
+				return e
+			}
+		}
+		return nil
+	})
+	if e != nil {
+		return errors.Wrap(e, "service.Session.LogoutAll")
+	}
+	return nil
+}
@@ -1,56 +1,78 @@
 package logic
 
 import (
+	"context"
 	"fmt"
 	"github.com/pkg/errors"
 	"time"
 
 	"github.com/rinosukmandityo/user-profile/helper"
+	"github.com/rinosukmandityo/user-profile/helper/ids"
 	m "github.com/rinosukmandityo/user-profile/models"
 	repo "github.com/rinosukmandityo/user-profile/repositories"
 	svc "github.com/rinosukmandityo/user-profile/services"
 )
 
 type tokenService struct {
-	tokenRepo repo.TokenRepository
-	userRepo  repo.UserRepository
+	tokenRepo   repo.TokenRepository
+	userRepo    repo.UserRepository
+	sessionRepo repo.SessionRepository
+	attemptRepo repo.LoginAttemptRepository
+	transactor  repo.Transactor
+	policy      PasswordPolicy
 }
 
-func NewTokenService(tokenRepo repo.TokenRepository, userRepo repo.UserRepository) svc.TokenService {
+func NewTokenService(tokenRepo repo.TokenRepository, userRepo repo.UserRepository, sessionRepo repo.SessionRepository, attemptRepo repo.LoginAttemptRepository, transactor repo.Transactor, policy PasswordPolicy) svc.TokenService {
 	return &tokenService{
-		tokenRepo, userRepo,
+		tokenRepo, userRepo, sessionRepo, attemptRepo, transactor, policy,
 	}
 }
 
-func (u *tokenService) ResetPasswordByMail(email string, expired time.Duration) (user m.User, tokenID string, e error) {
-	user, e = u.userRepo.GetBy(map[string]interface{}{"Email": email})
+func (u *tokenService) ResetPasswordByMail(ctx context.Context, email string, expired time.Duration) (user m.User, tokenID string, e error) {
+	user, e = u.userRepo.GetBy(ctx, map[string]interface{}{"Email": email})
 	if e != nil {
 		return
 	}
-
-	tToken, e := u.tokenRepo.GetLatestToken(user.ID)
-	if time.Now().UTC().After(tToken.Expired) {
-		e = fmt.Errorf(helper.ErrTokenExpired)
-	} else if tToken.IsClaimed {
-		e = fmt.Errorf(helper.ErrTokenClaimed)
+	tokenID, e = u.latestToken(ctx, user.ID, m.TokenResetPassword, expired)
+	if e != nil {
+		e = fmt.Errorf("Reset password failed to create token: %s", e.Error())
 	}
 
-	tokenID = tToken.ID
-	if tokenID != "" && e == nil {
+	return
+}
+
+func (u *tokenService) VerificationByMail(ctx context.Context, email string, expired time.Duration) (user m.User, tokenID string, e error) {
+	user, e = u.userRepo.GetBy(ctx, map[string]interface{}{"Email": email})
+	if e != nil {
 		return
 	}
-	// token expired or never been created
-	tToken, e = u.CreateNewToken(user.ID, expired)
+	if user.IsActive {
+		return user, "", errors.Wrap(helper.ErrEmailVerified, "service.Token.VerificationByMail")
+	}
+	tokenID, e = u.latestToken(ctx, user.ID, m.TokenVerifyEmail, expired)
 	if e != nil {
-		e = fmt.Errorf("Reset password failed to create token: %s", e.Error())
+		e = errors.Wrap(e, "service.Token.VerificationByMail")
 	}
-	tokenID = tToken.ID
 
 	return
 }
 
-func (u *tokenService) ChangePassword(userID, passwd string) (e error) {
-	if _, e = u.userRepo.Update(map[string]interface{}{"Password": passwd}, userID); e != nil {
+// latestToken returns the token of userID for purpose which can still be used,
+// a new one is created when it has expired, has been claimed or never been created.
+func (u *tokenService) latestToken(ctx context.Context, userID, purpose string, expired time.Duration) (string, error) {
+	tToken, e := u.tokenRepo.GetLatestToken(ctx, userID, purpose)
+	if e == nil && !tToken.IsClaimed && time.Now().UTC().Before(tToken.Expired) {
+		return tToken.ID, nil
+	}
+	tToken, e = u.CreateNewToken(ctx, userID, purpose, expired)
+	if e != nil {
+		return "", e
+	}
+	return tToken.ID, nil
+}
+
+func (u *tokenService) ChangePassword(ctx context.Context, userID, passwd string) (e error) {
+	if _, e = u.userRepo.Update(ctx, map[string]interface{}{"Password": passwd}, userID); e != nil {
 		if errors.Cause(e).Error() == helper.ErrUserNotFound.Error() {
 			e = nil
 		}
@@ -60,29 +82,45 @@ func (u *tokenService) ChangePassword(userID, passwd string) (e error) {
 	return
 }
 
-func (u *tokenService) ChangePasswordToken(userID, passwd, tokenID string) (e error) {
-	gToken, e := u.tokenRepo.GetLatestToken(userID)
-	if e != nil {
-		return
-	}
-	if gToken.ID != tokenID {
-		return helper.ErrTokenNotMatch
-	}
-	if e = u.ChangePassword(userID, repo.EncryptPassword(passwd)); e == nil {
-		e = u.Claim(gToken)
-	}
-
-	return
+// ChangePasswordToken changes the password and claims the token in one transaction,
+// a token is never left reusable after the password has been changed. A reset
+// also lifts the lockout of the account and revokes every session of it, whoever
+// knew the old password is logged out and has to log in with the new one.
+func (u *tokenService) ChangePasswordToken(ctx context.Context, userID, passwd, tokenID string) error {
+	return u.transactor.WithinTransaction(ctx, func(ctx context.Context) (e error) {
+		// a reset link which is used up or expired can't be replayed
+		if e = u.claimToken(ctx, userID, tokenID, m.TokenResetPassword); e != nil {
+			return
+		}
+		user, e := u.userRepo.GetBy(ctx, map[string]interface{}{"ID": userID})
+		if e != nil {
+			return
+		}
+		if e = u.policy.Validate(passwd, user); e != nil {
+			return
+		}
+		password, e := repo.EncryptPassword(passwd)
+		if e != nil {
+			return
+		}
+		if e = u.ChangePassword(ctx, userID, password); e != nil {
+			return
+		}
+		if e = u.attemptRepo.Delete(ctx, accountAttemptID(user.Email)); e != nil {
+			return
+		}
+		return u.sessionRepo.DeleteByUser(ctx, userID)
+	})
 }
 
-func (u *tokenService) IsTokenValid(userid, tokenID string) (isValid bool, e error) {
-	if _, e = u.userRepo.GetBy(map[string]interface{}{"ID": userid}); e != nil {
+func (u *tokenService) IsTokenValid(ctx context.Context, userid, tokenID string) (isValid bool, e error) {
+	if _, e = u.userRepo.GetBy(ctx, map[string]interface{}{"ID": userid}); e != nil {
 		if errors.Cause(e).Error() == helper.ErrUserNotFound.Error() {
 			e = helper.ErrUserNotFound
 		}
 		return
 	}
-	tToken, e := u.tokenRepo.GetLatestToken(userid)
+	tToken, e := u.tokenRepo.GetLatestToken(ctx, userid, m.TokenResetPassword)
 	if e != nil {
 		if errors.Cause(e).Error() == helper.ErrTokenNotFound.Error() {
 			e = helper.ErrTokenNotFound
@@ -106,18 +144,89 @@ func (u *tokenService) IsTokenValid(userid, tokenID string) (isValid bool, e err
 	return true, nil
 }
 
-func (u *tokenService) CreateNewToken(userid string, validity time.Duration) (token m.Token, e error) {
+// VerifyEmail activates the user and claims the token in one transaction.
+func (u *tokenService) VerifyEmail(ctx context.Context, userID, tokenID string) error {
+	e := u.transactor.WithinTransaction(ctx, func(ctx context.Context) (e error) {
+		if e = u.claimToken(ctx, userID, tokenID, m.TokenVerifyEmail); e != nil {
+			return
+		}
+		_, e = u.userRepo.Update(ctx, map[string]interface{}{"IsActive": true}, userID)
+		return
+	})
+	if e != nil {
+		return errors.Wrap(e, "service.Token.VerifyEmail")
+	}
+	return nil
+}
+
+func (u *tokenService) MagicLinkByMail(ctx context.Context, email string, expired time.Duration) (user m.User, tokenID string, e error) {
+	user, e = u.userRepo.GetBy(ctx, map[string]interface{}{"Email": email})
+	if e != nil {
+		return
+	}
+	if !user.IsActive {
+		return user, "", errors.Wrap(helper.ErrEmailNotVerified, "service.Token.MagicLinkByMail")
+	}
+	tokenID, e = u.latestToken(ctx, user.ID, m.TokenMagicLink, expired)
+	if e != nil {
+		e = errors.Wrap(e, "service.Token.MagicLinkByMail")
+	}
+
+	return
+}
+
+func (u *tokenService) ClaimMagicLink(ctx context.Context, userID, tokenID string) (user m.User, e error) {
+	e = u.transactor.WithinTransaction(ctx, func(ctx context.Context) (e error) {
+		if e = u.claimToken(ctx, userID, tokenID, m.TokenMagicLink); e != nil {
+			return
+		}
+		user, e = u.userRepo.GetBy(ctx, map[string]interface{}{"ID": userID})
+		return
+	})
+	if e != nil {
+		return m.User{}, errors.Wrap(e, "service.Token.ClaimMagicLink")
+	}
+	return user, nil
+}
+
+// claimToken checks the token the same way IsTokenValid does and claims it,
+// it must run inside the transaction of the change the token grants. The claim
+// only succeeds while the token is unclaimed, so of two concurrent claims one fails.
+func (u *tokenService) claimToken(ctx context.Context, userID, tokenID, purpose string) error {
+	tToken, e := u.tokenRepo.GetLatestToken(ctx, userID, purpose)
+	if e != nil {
+		return e
+	}
+	if tToken.ID != tokenID {
+		return helper.ErrTokenNotFound
+	}
+	if time.Now().UTC().After(tToken.Expired) {
+		return fmt.Errorf(helper.ErrTokenExpired)
+	} else if tToken.IsClaimed {
+		return fmt.Errorf(helper.ErrTokenClaimed)
+	}
+	if e := u.tokenRepo.Claim(ctx, tToken.ID); e != nil {
+		if errors.Cause(e) == helper.ErrTokenNotFound {
+			return fmt.Errorf(helper.ErrTokenClaimed)
+		}
+		return e
+	}
+	return nil
+}
+
+func (u *tokenService) CreateNewToken(ctx context.Context, userid, purpose string, validity time.Duration) (token m.Token, e error) {
+	id, e := ids.NewSecret()
+	if e != nil {
+		return
+	}
 	token = m.Token{
-		ID:        fmt.Sprintf("%d", time.Now().UTC().UnixNano()),
+		ID:        id,
 		UserID:    userid,
 		Created:   time.Now().UTC(),
 		Expired:   time.Now().UTC().Add(validity),
 		IsClaimed: false,
+		Purpose:   purpose,
 	}
-	e = u.tokenRepo.Store(&token)
+	e = u.tokenRepo.Store(ctx, &token)
 	return
 }
-
-func (u *tokenService) Claim(token m.Token) error {
-	return u.tokenRepo.Update(map[string]interface{}{"IsClaimed": true}, token.ID)
-}
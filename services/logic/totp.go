@@ -0,0 +1,110 @@
+package logic
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	totpDigits = 6
+	totpPeriod = 30
+	// totpSkew is how many time steps before and after now are accepted, for clock drift
+	totpSkew = 1
+
+	recoveryCodeCount = 10
+)
+
+var secretEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateTOTP returns the code of secret at t as described in RFC 6238, with
+// the HMAC-SHA1, 6 digits and 30 seconds period every authenticator app supports.
+func GenerateTOTP(secret string, t time.Time) (string, error) {
+	key, e := secretEncoding.DecodeString(strings.ToUpper(secret))
+	if e != nil {
+		return "", e
+	}
+	return hotp(key, totpStep(t)), nil
+}
+
+func totpStep(t time.Time) int64 {
+	return t.Unix() / totpPeriod
+}
+
+// hotp is the RFC 4226 code of counter.
+func hotp(key []byte, counter int64) string {
+	msg := make([]byte, 8)
+	binary.BigEndian.PutUint64(msg, uint64(counter))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg)
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", totpDigits, value%1000000)
+}
+
+// matchTOTP returns the time step code belongs to, steps up to lastStep were
+// used already and are never accepted again.
+func matchTOTP(secret, code string, lastStep int64, now time.Time) (int64, bool) {
+	key, e := secretEncoding.DecodeString(secret)
+	if e != nil || len(code) != totpDigits {
+		return 0, false
+	}
+	step := totpStep(now)
+	for s := step - totpSkew; s <= step+totpSkew; s++ {
+		if s > lastStep && hmac.Equal([]byte(hotp(key, s)), []byte(code)) {
+			return s, true
+		}
+	}
+	return 0, false
+}
+
+// newTOTPSecret returns 160 random bits, the key size RFC 4226 recommends.
+func newTOTPSecret() (string, error) {
+	key := make([]byte, 20)
+	if _, e := rand.Read(key); e != nil {
+		return "", e
+	}
+	return secretEncoding.EncodeToString(key), nil
+}
+
+// totpURI is the otpauth URI authenticator apps read from a QR code.
+func totpURI(issuer, account, secret string) string {
+	q := url.Values{}
+	q.Set("secret", secret)
+	q.Set("issuer", issuer)
+	q.Set("algorithm", "SHA1")
+	q.Set("digits", fmt.Sprint(totpDigits))
+	q.Set("period", fmt.Sprint(totpPeriod))
+	return "otpauth://totp/" + url.PathEscape(issuer+":"+account) + "?" + q.Encode()
+}
+
+// newRecoveryCodes returns recoveryCodeCount codes like ABCDE-FGHIJ and the
+// hashes to store, a code is only ever shown once.
+func newRecoveryCodes() (codes, hashes []string, e error) {
+	for i := 0; i < recoveryCodeCount; i++ {
+		b := make([]byte, 7)
+		if _, e = rand.Read(b); e != nil {
+			return nil, nil, e
+		}
+		code := secretEncoding.EncodeToString(b)[:10]
+		codes = append(codes, code[:5]+"-"+code[5:])
+		hashes = append(hashes, hashRecoveryCode(code))
+	}
+	return codes, hashes, nil
+}
+
+// hashRecoveryCode ignores case, spaces and dashes so that a code can be typed in as it is read.
+func hashRecoveryCode(code string) string {
+	code = strings.NewReplacer("-", "", " ", "").Replace(strings.ToUpper(code))
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,131 @@
+package logic
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/rinosukmandityo/user-profile/helper"
+	m "github.com/rinosukmandityo/user-profile/models"
+	repo "github.com/rinosukmandityo/user-profile/repositories"
+	svc "github.com/rinosukmandityo/user-profile/services"
+
+	"github.com/pkg/errors"
+)
+
+type twoFactorService struct {
+	twoFactorRepo repo.TwoFactorRepository
+	transactor    repo.Transactor
+	issuer        string
+}
+
+// NewTwoFactorService returns the TOTP service, issuer names the account in the authenticator app.
+func NewTwoFactorService(twoFactorRepo repo.TwoFactorRepository, transactor repo.Transactor, issuer string) svc.TwoFactorService {
+	return &twoFactorService{
+		twoFactorRepo, transactor, issuer,
+	}
+}
+
+func (u *twoFactorService) Enroll(ctx context.Context, user m.User) (m.TwoFactorEnrollment, error) {
+	current, e := u.twoFactorRepo.GetBy(ctx, user.ID)
+	if e == nil && current.IsEnabled {
+		return m.TwoFactorEnrollment{}, errors.Wrap(helper.ErrTwoFactorEnabled, "service.TwoFactor.Enroll")
+	}
+	if e != nil && errors.Cause(e) != helper.ErrTwoFactorNotFound {
+		return m.TwoFactorEnrollment{}, errors.Wrap(e, "service.TwoFactor.Enroll")
+	}
+	secret, e := newTOTPSecret()
+	if e != nil {
+		return m.TwoFactorEnrollment{}, errors.Wrap(e, "service.TwoFactor.Enroll")
+	}
+	data := m.TwoFactor{
+		ID:      user.ID,
+		Secret:  secret,
+		Created: time.Now().UTC(),
+	}
+	if e = u.twoFactorRepo.Save(ctx, &data); e != nil {
+		return m.TwoFactorEnrollment{}, errors.Wrap(e, "service.TwoFactor.Enroll")
+	}
+
+	return m.TwoFactorEnrollment{Secret: secret, URI: totpURI(u.issuer, user.Email, secret)}, nil
+}
+
+func (u *twoFactorService) Confirm(ctx context.Context, userID, code string) (codes []string, e error) {
+	e = u.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		data, e := u.twoFactorRepo.GetBy(ctx, userID)
+		if e != nil {
+			return e
+		}
+		if data.IsEnabled {
+			return helper.ErrTwoFactorEnabled
+		}
+		step, ok := matchTOTP(data.Secret, strings.TrimSpace(code), int64(data.LastStep), time.Now())
+		if !ok {
+			return helper.ErrInvalidCode
+		}
+		var hashes []string
+		if codes, hashes, e = newRecoveryCodes(); e != nil {
+			return e
+		}
+		data.IsEnabled = true
+		data.LastStep = int(step)
+		data.RecoveryCodes = strings.Join(hashes, ",")
+		return u.twoFactorRepo.Save(ctx, &data)
+	})
+	if e != nil {
+		return nil, errors.Wrap(e, "service.TwoFactor.Confirm")
+	}
+	return codes, nil
+}
+
+// Verify accepts a code of the authenticator app or a recovery code and uses it up.
+// The enrolment is only updated while it is still the one the code was checked
+// against, so a code sent twice at once is accepted once.
+func (u *twoFactorService) Verify(ctx context.Context, userID, code string) error {
+	code = strings.TrimSpace(code)
+	e := u.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		data, e := u.twoFactorRepo.GetBy(ctx, userID)
+		if e != nil {
+			return e
+		}
+		if !data.IsEnabled {
+			return helper.ErrTwoFactorNotFound
+		}
+		lastStep, recoveryCodes := data.LastStep, data.RecoveryCodes
+		if step, ok := matchTOTP(data.Secret, code, int64(data.LastStep), time.Now()); ok {
+			lastStep = int(step)
+		} else {
+			hashes := strings.Split(data.RecoveryCodes, ",")
+			hash := hashRecoveryCode(code)
+			i := 0
+			for i < len(hashes) && hashes[i] != hash {
+				i++
+			}
+			if i == len(hashes) {
+				return helper.ErrInvalidCode
+			}
+			recoveryCodes = strings.Join(append(hashes[:i], hashes[i+1:]...), ",")
+		}
+		if e := u.twoFactorRepo.UpdateCodes(ctx, data, lastStep, recoveryCodes); errors.Cause(e) == helper.ErrTwoFactorNotFound {
+			return helper.ErrInvalidCode
+		} else if e != nil {
+			return e
+		}
+		return nil
+	})
+	if e != nil {
+		return errors.Wrap(e, "service.TwoFactor.Verify")
+	}
+	return nil
+}
+
+func (u *twoFactorService) IsEnabled(ctx context.Context, userID string) (bool, error) {
+	data, e := u.twoFactorRepo.GetBy(ctx, userID)
+	if errors.Cause(e) == helper.ErrTwoFactorNotFound {
+		return false, nil
+	}
+	if e != nil {
+		return false, errors.Wrap(e, "service.TwoFactor.IsEnabled")
+	}
+	return data.IsEnabled, nil
+}
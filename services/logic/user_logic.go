@@ -1,10 +1,10 @@
 package logic
 
 import (
-	"fmt"
-	"time"
+	"context"
 
 	"github.com/rinosukmandityo/user-profile/helper"
+	"github.com/rinosukmandityo/user-profile/helper/ids"
 	m "github.com/rinosukmandityo/user-profile/models"
 	repo "github.com/rinosukmandityo/user-profile/repositories"
 	svc "github.com/rinosukmandityo/user-profile/services"
@@ -13,17 +13,19 @@ import (
 )
 
 type userService struct {
-	userRepo repo.UserRepository
+	userRepo   repo.UserRepository
+	transactor repo.Transactor
+	policy     PasswordPolicy
 }
 
-func NewUserService(userRepo repo.UserRepository) svc.UserService {
+func NewUserService(userRepo repo.UserRepository, transactor repo.Transactor, policy PasswordPolicy) svc.UserService {
 	return &userService{
-		userRepo,
+		userRepo, transactor, policy,
 	}
 }
 
-func (u *userService) GetById(id string) (m.User, error) {
-	res, e := u.userRepo.GetBy(map[string]interface{}{"ID": id})
+func (u *userService) GetById(ctx context.Context, id string) (m.User, error) {
+	res, e := u.userRepo.GetBy(ctx, map[string]interface{}{"ID": id})
 	if e != nil {
 		return res, e
 	}
@@ -31,36 +33,67 @@ func (u *userService) GetById(id string) (m.User, error) {
 	return res, nil
 
 }
-func (u *userService) Store(data *m.User) error {
+func (u *userService) Store(ctx context.Context, data *m.User) error {
 	if data.ID == "" {
-		data.ID = fmt.Sprintf("%d", time.Now().UTC().UnixNano())
-	}
-	if isFound, _, _ := u.GetByEmail(data.Email); isFound {
-		return errs.Wrap(helper.ErrEmailDuplicate, "service.User.Store")
+		id, e := ids.NewULID()
+		if e != nil {
+			return errs.Wrap(e, "service.User.Store")
+		}
+		data.ID = id
 	}
-	if data.Password != "" {
-		data.Password = repo.EncryptPassword(data.Password)
-	} else {
-		data.IsGoogleAuth = true
+	// only accounts signed up with Google have no password
+	if !data.IsGoogleAuth {
+		if e := u.policy.Validate(data.Password, *data); e != nil {
+			return errs.Wrap(e, "service.User.Store")
+		}
+		password, e := repo.EncryptPassword(data.Password)
+		if e != nil {
+			return errs.Wrap(e, "service.User.Store")
+		}
+		data.Password = password
 	}
-	return u.userRepo.Store(data)
+	// the email check and the insert must see the same data
+	return u.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		if u.isEmailTaken(ctx, data.Email, "") {
+			return errs.Wrap(helper.ErrEmailDuplicate, "service.User.Store")
+		}
+		return u.userRepo.Store(ctx, data)
+	})
 
 }
-func (u *userService) Update(data map[string]interface{}, id string) (m.User, error) {
+
+// Update only ever changes the fields of m.UserUpdate, a password which is
+// sent must meet the password policy.
+func (u *userService) Update(ctx context.Context, data *m.UserUpdate, id string) (m.User, error) {
 	user := m.User{}
-	var e error
 
-	if isFound, usr, _ := u.GetByEmail(data["Email"].(string)); isFound {
-		if usr.ID != id {
-			return user, errs.Wrap(helper.ErrEmailDuplicate, "service.User.Update")
+	dataMap := data.GetMapFormat()
+	if data.Password != nil {
+		if e := u.validatePassword(ctx, data, id); e != nil {
+			return user, errs.Wrap(e, "service.User.Update")
 		}
-	}
-	if _, hasData := data["Password"]; hasData {
-		if data["Password"].(string) != "" {
-			data["Password"] = repo.EncryptPassword(data["Password"].(string))
+		password, e := repo.EncryptPassword(*data.Password)
+		if e != nil {
+			return user, errs.Wrap(e, "service.User.Update")
 		}
+		dataMap["Password"] = password
+	}
+	if data.Email != nil && *data.Email == "" {
+		return user, errs.Wrap(helper.ErrUserInvalid, "service.User.Update")
+	}
+	if len(dataMap) == 0 {
+		// nothing to change is reported the same way as an update which changes nothing
+		return user, errs.Wrap(helper.ErrUserNotFound, "service.User.Update")
 	}
-	user, e = u.userRepo.Update(data, id)
+	e := u.transactor.WithinTransaction(ctx, func(ctx context.Context) (e error) {
+		if data.Email != nil {
+			if u.isEmailTaken(ctx, *data.Email, id) {
+				return helper.ErrEmailDuplicate
+			}
+		}
+		user, e = u.userRepo.Update(ctx, dataMap, id)
+		return
+	})
 	if e != nil {
 		return user, errs.Wrap(e, "service.User.Update")
 	}
@@ -68,11 +101,46 @@ func (u *userService) Update(data map[string]interface{}, id string) (m.User, er
 
 }
 
-func (u *userService) GetByEmail(email string) (bool, m.User, error) {
-	res, e := u.userRepo.GetBy(map[string]interface{}{"Email": email})
+// validatePassword checks the new password against the name and email the user has after the update.
+func (u *userService) validatePassword(ctx context.Context, data *m.UserUpdate, id string) error {
+	user, e := u.userRepo.GetBy(ctx, map[string]interface{}{"ID": id})
+	if e != nil {
+		return e
+	}
+	if data.Name != nil {
+		user.Name = *data.Name
+	}
+	if data.Email != nil {
+		user.Email = *data.Email
+	}
+	return u.policy.Validate(*data.Password, user)
+}
+
+func (u *userService) List(ctx context.Context, query m.UserListQuery) (m.UserPage, error) {
+	page, e := u.userRepo.List(ctx, query)
+	if e != nil {
+		return page, errs.Wrap(e, "service.User.List")
+	}
+	return page, nil
+}
+
+func (u *userService) GetByEmail(ctx context.Context, email string) (bool, m.User, error) {
+	res, e := u.userRepo.GetBy(ctx, map[string]interface{}{"Email": email})
 	if e != nil {
 		return false, res, e
 	}
 
 	return true, res, nil
 }
+
+// isEmailTaken reports whether a user other than id owns the email, a soft
+// deleted account keeps its email until it is purged.
+func (u *userService) isEmailTaken(ctx context.Context, email, id string) bool {
+	for _, isDeleted := range []bool{false, true} {
+		usr, e := u.userRepo.GetBy(ctx, map[string]interface{}{"Email": email, "IsDeleted": isDeleted})
+		if e == nil && usr.ID != id {
+			return true
+		}
+	}
+	return false
+}
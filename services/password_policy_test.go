@@ -0,0 +1,138 @@
+package services_test
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rinosukmandityo/user-profile/helper"
+	m "github.com/rinosukmandityo/user-profile/models"
+	"github.com/rinosukmandityo/user-profile/services/logic"
+
+	"github.com/pkg/errors"
+)
+
+func TestPasswordPolicy(t *testing.T) {
+	user := m.User{
+		Name:  "Jo Anderson",
+		Email: "janedoe@gmail.com",
+	}
+	symbolPolicy := logic.DefaultPasswordPolicy()
+	symbolPolicy.RequireSymbol = true
+
+	testTable := []struct {
+		name               string
+		policy             logic.PasswordPolicy
+		password           string
+		expectedViolations []string
+	}{
+		{
+			name:     "valid",
+			policy:   logic.DefaultPasswordPolicy(),
+			password: "Password.1",
+		},
+		{
+			name:     "short_name_part_is_allowed",
+			policy:   logic.DefaultPasswordPolicy(),
+			password: "Jo.Password.1",
+		},
+		{
+			name:               "empty",
+			policy:             logic.DefaultPasswordPolicy(),
+			password:           "",
+			expectedViolations: []string{"Password must be at least 8 characters", helper.ErrPasswordNoLower, helper.ErrPasswordNoUpper, helper.ErrPasswordNoDigit},
+		},
+		{
+			name:               "too_long",
+			policy:             logic.PasswordPolicy{MaxLength: 4},
+			password:           "Password.1",
+			expectedViolations: []string{"Password must be at most 4 bytes"},
+		},
+		{
+			// 38 characters but 73 bytes, bcrypt would drop the last byte
+			name:               "too_long_multibyte",
+			policy:             logic.DefaultPasswordPolicy(),
+			password:           "Pa1" + strings.Repeat("é", 35),
+			expectedViolations: []string{"Password must be at most 72 bytes"},
+		},
+		{
+			name:     "multibyte_at_limit",
+			policy:   logic.DefaultPasswordPolicy(),
+			password: "Pa1" + strings.Repeat("é", 34) + "a",
+		},
+		{
+			name:               "no_symbol",
+			policy:             symbolPolicy,
+			password:           "Password1",
+			expectedViolations: []string{helper.ErrPasswordNoSymbol},
+		},
+		{
+			name:               "contains_email",
+			policy:             logic.DefaultPasswordPolicy(),
+			password:           "JaneDoe.2020",
+			expectedViolations: []string{helper.ErrPasswordHasEmail},
+		},
+		{
+			name:               "contains_name",
+			policy:             logic.DefaultPasswordPolicy(),
+			password:           "Anderson.2020",
+			expectedViolations: []string{helper.ErrPasswordHasName},
+		},
+	}
+
+	for _, _tt := range testTable {
+		tt := _tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			e := tt.policy.Validate(tt.password, user)
+			if tt.expectedViolations == nil {
+				if e != nil {
+					t.Errorf("password should be valid, got %s", e.Error())
+				}
+				return
+			}
+
+			policyErr, ok := e.(*helper.PasswordPolicyError)
+			if !ok {
+				t.Fatalf("error should be a password policy error, got %v", e)
+			}
+			if !reflect.DeepEqual(tt.expectedViolations, policyErr.Violations) {
+				t.Errorf("violations are not correct, \nwant: \n%v, \ngot: \n%v", tt.expectedViolations, policyErr.Violations)
+			}
+		})
+	}
+}
+
+func TestPasswordPolicyOnUpdate(t *testing.T) {
+	testData := []m.User{{
+		Name:     "User 01",
+		Password: "Password.1",
+		ID:       "userid01",
+		Email:    "usermail01@gmail.com",
+	}}
+
+	defer func() {
+		if err := cleanupUserData(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	if e := seedUserData(testData); e != nil {
+		t.Fatal(e)
+	}
+
+	empty := ""
+	if _, e := userService.Update(context.Background(), &m.UserUpdate{Password: &empty}, testData[0].ID); e == nil {
+		t.Error("empty password should be rejected")
+	} else if _, ok := errors.Cause(e).(*helper.PasswordPolicyError); !ok {
+		t.Errorf("error should be a password policy error, got %s", e.Error())
+	}
+
+	// the new email is checked, not the stored one
+	password := "Newmail.2020"
+	email := "newmail@gmail.com"
+	if _, e := userService.Update(context.Background(), &m.UserUpdate{Password: &password, Email: &email}, testData[0].ID); e == nil {
+		t.Error("password containing the new email should be rejected")
+	}
+}
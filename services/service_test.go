@@ -1,37 +1,51 @@
 package services_test
 
 import (
+	"context"
 	"net/http/httptest"
+	"time"
 
+	"github.com/rinosukmandityo/user-profile/helper"
 	m "github.com/rinosukmandityo/user-profile/models"
 	repo "github.com/rinosukmandityo/user-profile/repositories"
 	rh "github.com/rinosukmandityo/user-profile/repositories/helper"
 	. "github.com/rinosukmandityo/user-profile/services"
 	"github.com/rinosukmandityo/user-profile/services/logic"
+
+	"github.com/pkg/errors"
 )
 
 var (
-	userRepo     repo.UserRepository
-	sessionRepo  repo.SessionRepository
-	tokenRepo    repo.TokenRepository
-	sessionSvc   SessionService
-	userService  UserService
-	tokenService TokenService
-	ts           *httptest.Server
+	userRepo      repo.UserRepository
+	sessionRepo   repo.SessionRepository
+	tokenRepo     repo.TokenRepository
+	attemptRepo   repo.LoginAttemptRepository
+	twoFactorRepo repo.TwoFactorRepository
+	transactor    repo.Transactor
+	sessionSvc    SessionService
+	userService   UserService
+	tokenService  TokenService
+	accountSvc    AccountService
+	lockoutSvc    LockoutService
+	twoFactorSvc  TwoFactorService
+	ts            *httptest.Server
 )
 
 func init() {
-	userRepo, sessionRepo, tokenRepo = rh.ChooseRepo()
+	userRepo, sessionRepo, tokenRepo, attemptRepo, twoFactorRepo, transactor = rh.ChooseRepo()
 
-	sessionSvc = logic.NewSessionService(sessionRepo)
-	userService = logic.NewUserService(userRepo)
-	tokenService = logic.NewTokenService(tokenRepo, userRepo)
+	sessionSvc = logic.NewSessionService(sessionRepo, userRepo, transactor, logic.DefaultSessionPolicy())
+	userService = logic.NewUserService(userRepo, transactor, logic.DefaultPasswordPolicy())
+	tokenService = logic.NewTokenService(tokenRepo, userRepo, sessionRepo, attemptRepo, transactor, logic.DefaultPasswordPolicy())
+	accountSvc = logic.NewAccountService(userRepo, sessionRepo, tokenRepo, twoFactorRepo, attemptRepo, transactor, time.Hour)
+	lockoutSvc = logic.NewLockoutService(attemptRepo, logic.DefaultLockoutPolicy())
+	twoFactorSvc = logic.NewTwoFactorService(twoFactorRepo, transactor, "User Profile")
 }
 
 func seedUserData(testdata []m.User) error {
 	var err error
 	for _, data := range testdata {
-		if err = userRepo.Store(&data); err != nil {
+		if err = userRepo.Store(context.Background(), &data); err != nil {
 			return err
 		}
 	}
@@ -39,13 +53,27 @@ func seedUserData(testdata []m.User) error {
 }
 
 func cleanupUserData() error {
-	return userRepo.DeleteAll()
+	return userRepo.DeleteAll(context.Background())
 }
 
 func cleanupSessionData() error {
-	return sessionRepo.DeleteAll()
+	return sessionRepo.DeleteAll(context.Background())
 }
 
 func cleanupTokenData() error {
-	return tokenRepo.DeleteAll()
+	return tokenRepo.DeleteAll(context.Background())
+}
+
+func cleanupAttemptData() error {
+	if e := attemptRepo.DeleteAll(context.Background()); errors.Cause(e) != helper.ErrAttemptNotFound {
+		return e
+	}
+	return nil
+}
+
+func cleanupTwoFactorData() error {
+	if e := twoFactorRepo.DeleteAll(context.Background()); errors.Cause(e) != helper.ErrTwoFactorNotFound {
+		return e
+	}
+	return nil
 }
@@ -1,13 +1,31 @@
 package services
 
 import (
+	"context"
+
 	m "github.com/rinosukmandityo/user-profile/models"
 )
 
 type SessionService interface {
-	Authenticate(email, password string) (bool, m.User, error)
-	GetBy(filter map[string]interface{}) (m.Session, error)
-	IsSessionActive(id string) (bool, m.Session, error)
-	CreateNewSession(user m.User) (m.Session, error)
-	Logout(data m.Session) error
+	Authenticate(ctx context.Context, email, password string) (bool, m.User, error)
+	GetBy(ctx context.Context, filter map[string]interface{}) (m.Session, error)
+	IsSessionActive(ctx context.Context, id string) (bool, m.Session, error)
+	// CreateNewSession starts a new session on every login, client tells the devices of a user apart
+	CreateNewSession(ctx context.Context, user m.User, client m.Client) (m.Session, error)
+	// CreatePendingSession starts a login which still needs the second factor
+	CreatePendingSession(ctx context.Context, user m.User, client m.Client) (m.Session, error)
+	// GetPendingSession returns helper.ErrSessionNotFound unless id is a pending session which has not expired
+	GetPendingSession(ctx context.Context, id string) (m.Session, error)
+	// CompletePendingSession ends the pending session and creates the full session of its user,
+	// marked as passed the second factor. Of concurrent completions of one pending session
+	// only one succeeds, the others get helper.ErrSessionNotFound
+	CompletePendingSession(ctx context.Context, pending m.Session) (m.Session, error)
+	// Rotate moves old to a new ID after the credentials of user changed, the old ID stops working.
+	// The new session keeps the lifetime, the device and the second factor of the old one.
+	Rotate(ctx context.Context, old m.Session, user m.User) (m.Session, error)
+	// ListSessions returns the active sessions of userID, the most recently seen first
+	ListSessions(ctx context.Context, userID string) ([]m.Session, error)
+	Logout(ctx context.Context, data m.Session) error
+	// LogoutAll ends every active session of userID
+	LogoutAll(ctx context.Context, userID string) error
 }
@@ -1,6 +1,7 @@
 package services_test
 
 import (
+	"context"
 	"github.com/pkg/errors"
 	"github.com/rinosukmandityo/user-profile/helper"
 	"reflect"
@@ -8,6 +9,8 @@ import (
 	"time"
 
 	m "github.com/rinosukmandityo/user-profile/models"
+	"github.com/rinosukmandityo/user-profile/repositories"
+	"github.com/rinosukmandityo/user-profile/services/logic"
 )
 
 func TestCreateNewSession(t *testing.T) {
@@ -27,36 +30,208 @@ func TestCreateNewSession(t *testing.T) {
 	}()
 
 	testTable := []struct {
-		name     string
-		userData m.User
+		name   string
+		client m.Client
 	}{
 		{
-			name:     "success",
-			userData: userData,
+			name:   "laptop",
+			client: m.Client{IP: "10.0.0.1", UserAgent: "Laptop Browser"},
 		},
 		{
-			name:     "already_exists",
-			userData: userData,
+			name:   "phone",
+			client: m.Client{IP: "10.0.0.2", UserAgent: "Phone Browser"},
 		},
 	}
 
+	ids := map[string]bool{}
 	for _, _tt := range testTable {
 		tt := _tt
 		t.Run(tt.name, func(t *testing.T) {
-			actualData, e := sessionSvc.CreateNewSession(userData)
+			actualData, e := sessionSvc.CreateNewSession(context.Background(), userData, tt.client)
 			if e != nil {
-				t.Errorf("unable to create a new session")
+				t.Fatalf("unable to create a new session: %s", e.Error())
 			}
 
-			if actualData.UserID != tt.userData.ID {
-				t.Errorf("data is not correct, want: %s, got: %s", tt.userData.ID, actualData.UserID)
+			if actualData.UserID != userData.ID {
+				t.Errorf("data is not correct, want: %s, got: %s", userData.ID, actualData.UserID)
+			}
+			if actualData.Email != userData.Email {
+				t.Errorf("data is not correct, want: %s, got: %s", userData.Email, actualData.Email)
+			}
+			if actualData.IP != tt.client.IP || actualData.UserAgent != tt.client.UserAgent {
+				t.Errorf("client is not correct, want: %+v, got: %s %s", tt.client, actualData.IP, actualData.UserAgent)
 			}
-			if actualData.Email != tt.userData.Email {
-				t.Errorf("data is not correct, want: %s, got: %s", tt.userData.Email, actualData.Email)
+			// every login gets its own session
+			if ids[actualData.ID] {
+				t.Errorf("session %s is reused", actualData.ID)
 			}
+			ids[actualData.ID] = true
+		})
+	}
+	for id := range ids {
+		if active, _, _ := sessionSvc.IsSessionActive(context.Background(), id); !active {
+			t.Errorf("session %s should be active", id)
+		}
+	}
+}
+
+func TestSessionPolicy(t *testing.T) {
+	defer func() {
+		if e := cleanupSessionData(); e != nil {
+			t.Fatal(e)
+		}
+	}()
+	ctx := context.Background()
+	user := m.User{ID: "userid01", Email: "usermail01@gmail.com"}
+	policy := logic.DefaultSessionPolicy()
+	// every request extends the session
+	policy.TouchInterval = 0
+	svc := logic.NewSessionService(sessionRepo, userRepo, transactor, policy)
+	near := func(want, got time.Time) bool {
+		d := want.Sub(got)
+		return d < 2*time.Second && d > -2*time.Second
+	}
+	update := func(id string, data map[string]interface{}) {
+		if _, e := sessionRepo.Update(ctx, data, id); e != nil {
+			t.Fatal(e)
+		}
+	}
+
+	tSession, e := svc.CreateNewSession(ctx, user, m.Client{})
+	if e != nil {
+		t.Fatal(e)
+	}
+	if tSession.IsRemembered || !near(tSession.Created.Add(policy.IdleTimeout), tSession.Expired) {
+		t.Errorf("session should end after the idle timeout, got %+v", tSession)
+	}
 
-			time.Sleep(time.Second)
+	t.Run("activity_extends_session", func(t *testing.T) {
+		update(tSession.ID, map[string]interface{}{"Expired": time.Now().UTC().Add(time.Minute)})
+		active, sess, _ := svc.IsSessionActive(ctx, tSession.ID)
+		if !active || !near(time.Now().Add(policy.IdleTimeout), sess.Expired) {
+			t.Errorf("session should be extended by the idle timeout, got %v %+v", active, sess)
+		}
+		// the lifetime is counted from Created, extending the session must not move it
+		stored, e := sessionRepo.GetBy(ctx, map[string]interface{}{"ID": tSession.ID})
+		if e != nil {
+			t.Fatal(e)
+		}
+		if !stored.Created.Equal(tSession.Created.Truncate(time.Second)) && !stored.Created.Equal(tSession.Created) {
+			t.Errorf("created should not change, want %v, got %v", tSession.Created, stored.Created)
+		}
+	})
+	t.Run("logout_during_request", func(t *testing.T) {
+		sess, e := svc.CreateNewSession(ctx, user, m.Client{})
+		if e != nil {
+			t.Fatal(e)
+		}
+		// the session was read by a request before it was logged out
+		if e := svc.Logout(ctx, sess); e != nil {
+			t.Fatal(e)
+		}
+		now := time.Now().UTC()
+		if e := sessionRepo.Touch(ctx, sess, now, now.Add(policy.IdleTimeout)); errors.Cause(e) != helper.ErrSessionNotFound {
+			t.Errorf("logged out session should not be extended, got %v", e)
+		}
+		if active, _, _ := svc.IsSessionActive(ctx, sess.ID); active {
+			t.Error("logged out session should stay logged out")
+		}
+	})
+	t.Run("idle_session_ends", func(t *testing.T) {
+		update(tSession.ID, map[string]interface{}{"Expired": time.Now().UTC().Add(-time.Minute)})
+		if active, _, _ := svc.IsSessionActive(ctx, tSession.ID); active {
+			t.Error("idle session should not be active")
+		}
+	})
+	t.Run("lifetime_is_not_extended", func(t *testing.T) {
+		old, e := svc.CreateNewSession(ctx, user, m.Client{})
+		if e != nil {
+			t.Fatal(e)
+		}
+		created := time.Now().UTC().Add(-policy.Lifetime + time.Minute)
+		update(old.ID, map[string]interface{}{"Created": created})
+		active, sess, _ := svc.IsSessionActive(ctx, old.ID)
+		if !active || !near(created.Add(policy.Lifetime), sess.Expired) {
+			t.Errorf("session should not outlive its lifetime, got %v %+v", active, sess)
+		}
+		update(old.ID, map[string]interface{}{"Created": created.Add(-2 * time.Minute)})
+		if active, _, _ := svc.IsSessionActive(ctx, old.ID); active {
+			t.Error("session past its lifetime should not be active")
+		}
+	})
+	t.Run("remembered_session", func(t *testing.T) {
+		remembered, e := svc.CreateNewSession(ctx, user, m.Client{Remember: true})
+		if e != nil {
+			t.Fatal(e)
+		}
+		if !remembered.IsRemembered || !near(remembered.Created.Add(policy.RememberLifetime), remembered.Expired) {
+			t.Errorf("remembered session should last the remember lifetime, got %+v", remembered)
+		}
+		update(remembered.ID, map[string]interface{}{
+			"Created":  time.Now().UTC().Add(-policy.Lifetime - time.Hour),
+			"LastSeen": time.Now().UTC().Add(-policy.Lifetime),
 		})
+		if active, _, _ := svc.IsSessionActive(ctx, remembered.ID); !active {
+			t.Error("remembered session should stay active past the idle timeout and lifetime")
+		}
+	})
+}
+
+func TestListSessions(t *testing.T) {
+	defer func() {
+		if e := cleanupSessionData(); e != nil {
+			t.Fatal(e)
+		}
+	}()
+	ctx := context.Background()
+	user := m.User{ID: "userid01", Email: "usermail01@gmail.com"}
+	other := m.User{ID: "userid02", Email: "usermail02@gmail.com"}
+
+	first, e := sessionSvc.CreateNewSession(ctx, user, m.Client{UserAgent: "Laptop Browser"})
+	if e != nil {
+		t.Fatal(e)
+	}
+	time.Sleep(time.Second)
+	second, e := sessionSvc.CreateNewSession(ctx, user, m.Client{UserAgent: "Phone Browser"})
+	if e != nil {
+		t.Fatal(e)
+	}
+	pending, e := sessionSvc.CreatePendingSession(ctx, user, m.Client{})
+	if e != nil {
+		t.Fatal(e)
+	}
+	otherSession, e := sessionSvc.CreateNewSession(ctx, other, m.Client{})
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	sessions, e := sessionSvc.ListSessions(ctx, user.ID)
+	if e != nil {
+		t.Fatal(e)
+	}
+	// pending sessions and sessions of other users are left out, the last seen comes first
+	if len(sessions) != 2 || sessions[0].ID != second.ID || sessions[1].ID != first.ID {
+		t.Fatalf("sessions are not correct, got %+v", sessions)
+	}
+
+	if e := sessionSvc.Logout(ctx, first); e != nil {
+		t.Fatal(e)
+	}
+	if sessions, _ := sessionSvc.ListSessions(ctx, user.ID); len(sessions) != 1 || sessions[0].ID != second.ID {
+		t.Errorf("logged out session should not be listed, got %+v", sessions)
+	}
+
+	if e := sessionSvc.LogoutAll(ctx, user.ID); e != nil {
+		t.Fatal(e)
+	}
+	if sessions, _ := sessionSvc.ListSessions(ctx, user.ID); len(sessions) != 0 {
+		t.Errorf("every session should be logged out, got %+v", sessions)
+	}
+	if _, e := sessionSvc.GetPendingSession(ctx, pending.ID); errors.Cause(e) != helper.ErrSessionNotFound {
+		t.Errorf("pending session should be logged out too, got %v", e)
+	}
+	if active, _, _ := sessionSvc.IsSessionActive(ctx, otherSession.ID); !active {
+		t.Error("session of another user should stay active")
 	}
 }
 
@@ -77,7 +252,7 @@ func TestAuthenticateUserSuccess(t *testing.T) {
 	}()
 
 	userData := testData[0]
-	if e := userService.Store(&userData); e != nil {
+	if e := userService.Store(context.Background(), &userData); e != nil {
 		t.Fatal(e)
 	}
 
@@ -96,7 +271,7 @@ func TestAuthenticateUserSuccess(t *testing.T) {
 	for _, _tt := range testTable {
 		tt := _tt
 		t.Run(tt.name, func(t *testing.T) {
-			_, _, e := sessionSvc.Authenticate(tt.email, tt.password)
+			_, _, e := sessionSvc.Authenticate(context.Background(), tt.email, tt.password)
 			if e != nil {
 				t.Errorf("authentication failed: %s", e.Error())
 			}
@@ -104,6 +279,39 @@ func TestAuthenticateUserSuccess(t *testing.T) {
 	}
 }
 
+func TestAuthenticateUpgradesLegacyHash(t *testing.T) {
+	testData := []m.User{{
+		Name:     "User 01",
+		Password: "a8b6bb3b34339f5a6d439a0ef7fc7878", // MD5 of Password.1
+		ID:       "userid01",
+		Email:    "usermail01@gmail.com",
+		Address:  "User Address 01",
+	}}
+
+	defer func() {
+		if e := cleanupUserData(); e != nil {
+			t.Fatal(e)
+		}
+	}()
+
+	if e := seedUserData(testData); e != nil {
+		t.Fatal(e)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, _, e := sessionSvc.Authenticate(context.Background(), testData[0].Email, "Password.1"); e != nil {
+			t.Fatalf("authentication %d failed: %s", i+1, e.Error())
+		}
+		actualUser, e := userRepo.GetBy(context.Background(), map[string]interface{}{"ID": testData[0].ID})
+		if e != nil {
+			t.Fatalf("unable to get actual user: %s", e.Error())
+		}
+		if actualUser.Password == testData[0].Password || repositories.NeedsRehash(actualUser.Password) {
+			t.Errorf("legacy hash should be upgraded, got %s", actualUser.Password)
+		}
+	}
+}
+
 func TestAuthenticateUserFailed(t *testing.T) {
 	testData := []m.User{{
 		Name:     "User 01",
@@ -151,7 +359,7 @@ func TestAuthenticateUserFailed(t *testing.T) {
 	for _, _tt := range testTable {
 		tt := _tt
 		t.Run(tt.name, func(t *testing.T) {
-			authenticated, actualData, e := sessionSvc.Authenticate(tt.email, tt.password)
+			authenticated, actualData, e := sessionSvc.Authenticate(context.Background(), tt.email, tt.password)
 			if e == nil {
 				t.Errorf("authentication should be failed")
 			}
@@ -168,3 +376,93 @@ func TestAuthenticateUserFailed(t *testing.T) {
 		})
 	}
 }
+
+func TestPendingSession(t *testing.T) {
+	defer func() {
+		if e := cleanupSessionData(); e != nil {
+			t.Fatal(e)
+		}
+	}()
+	ctx := context.Background()
+	user := m.User{ID: "userid01", Email: "usermail01@gmail.com"}
+
+	client := m.Client{IP: "10.0.0.1", UserAgent: "Laptop Browser"}
+	pending, e := sessionSvc.CreatePendingSession(ctx, user, client)
+	if e != nil {
+		t.Fatalf("failed to create pending session: %s", e.Error())
+	}
+	if active, _, _ := sessionSvc.IsSessionActive(ctx, pending.ID); active {
+		t.Error("pending session should not be active")
+	}
+	if _, e := sessionSvc.GetPendingSession(ctx, pending.ID); e != nil {
+		t.Fatalf("failed to get pending session: %s", e.Error())
+	}
+
+	tSession, e := sessionSvc.CompletePendingSession(ctx, pending)
+	if e != nil {
+		t.Fatalf("failed to complete pending session: %s", e.Error())
+	}
+	if tSession.ID == pending.ID || tSession.UserID != user.ID || tSession.IP != client.IP || tSession.UserAgent != client.UserAgent || !tSession.IsTwoFactor {
+		t.Errorf("full session is not correct, got %+v", tSession)
+	}
+	// a second request which read the pending session before it was completed
+	if _, e := sessionSvc.CompletePendingSession(ctx, pending); errors.Cause(e) != helper.ErrSessionNotFound {
+		t.Errorf("pending session should be completed once, got %v", e)
+	}
+	if sessions, _ := sessionSvc.ListSessions(ctx, user.ID); len(sessions) != 1 {
+		t.Errorf("want 1 full session, got %d", len(sessions))
+	}
+	if active, _, _ := sessionSvc.IsSessionActive(ctx, tSession.ID); !active {
+		t.Error("full session should be active")
+	}
+	if _, e := sessionSvc.GetPendingSession(ctx, pending.ID); errors.Cause(e) != helper.ErrSessionNotFound {
+		t.Errorf("completed pending session can't be used again, got %v", e)
+	}
+	if _, e := sessionSvc.GetPendingSession(ctx, tSession.ID); errors.Cause(e) != helper.ErrSessionNotFound {
+		t.Errorf("full session is not a pending session, got %v", e)
+	}
+}
+
+func TestRotate(t *testing.T) {
+	defer func() {
+		if e := cleanupSessionData(); e != nil {
+			t.Fatal(e)
+		}
+	}()
+	ctx := context.Background()
+	user := m.User{ID: "userid01", Email: "usermail01@gmail.com"}
+	client := m.Client{IP: "10.0.0.1", UserAgent: "Laptop Browser"}
+
+	old, e := sessionSvc.CreateNewSession(ctx, user, client)
+	if e != nil {
+		t.Fatal(e)
+	}
+	user.Email = "usermail02@gmail.com"
+	tSession, e := sessionSvc.Rotate(ctx, old, user)
+	if e != nil {
+		t.Fatalf("failed to rotate session: %s", e.Error())
+	}
+	if tSession.ID == old.ID || tSession.Email != user.Email {
+		t.Errorf("rotated session is not correct, got %+v", tSession)
+	}
+	// the device and the lifetime stay the same
+	if tSession.IP != client.IP || tSession.UserAgent != client.UserAgent || !tSession.Expired.Equal(old.Expired) {
+		t.Errorf("rotated session should keep the device and lifetime, want %+v, got %+v", old, tSession)
+	}
+	if active, _, _ := sessionSvc.IsSessionActive(ctx, old.ID); active {
+		t.Error("old session ID should stop working")
+	}
+	if active, _, _ := sessionSvc.IsSessionActive(ctx, tSession.ID); !active {
+		t.Error("rotated session should be active")
+	}
+
+	// when the old session can't be logged out the new one isn't kept either
+	other := m.User{ID: "userid02", Email: "usermail03@gmail.com"}
+	unknown := m.Session{ID: "unknown", UserID: other.ID, Created: time.Now().UTC(), Expired: time.Now().UTC().Add(time.Hour)}
+	if _, e := sessionSvc.Rotate(ctx, unknown, other); e == nil {
+		t.Fatal("rotating an unknown session should fail")
+	}
+	if sessions, _ := sessionSvc.ListSessions(ctx, other.ID); len(sessions) != 0 {
+		t.Errorf("failed rotation should not leave a new session, got %+v", sessions)
+	}
+}
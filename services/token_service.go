@@ -1,12 +1,24 @@
 package services
 
 import (
+	"context"
+
 	m "github.com/rinosukmandityo/user-profile/models"
 	"time"
 )
 
 type TokenService interface {
-	IsTokenValid(userid, tokenid string) (bool, error)
-	ResetPasswordByMail(email string, duration time.Duration) (m.User, string, error)
-	ChangePasswordToken(userID, passwd, tokenid string) error
+	IsTokenValid(ctx context.Context, userid, tokenid string) (bool, error)
+	ResetPasswordByMail(ctx context.Context, email string, duration time.Duration) (m.User, string, error)
+	ChangePasswordToken(ctx context.Context, userID, passwd, tokenid string) error
+	// VerificationByMail returns the unverified user of email with its email verification token,
+	// it returns helper.ErrEmailVerified once the email has been verified
+	VerificationByMail(ctx context.Context, email string, duration time.Duration) (m.User, string, error)
+	// VerifyEmail activates the user and claims the email verification token
+	VerifyEmail(ctx context.Context, userID, tokenid string) error
+	// MagicLinkByMail returns the user of email with a one-time login token,
+	// it returns helper.ErrEmailNotVerified until the email has been verified
+	MagicLinkByMail(ctx context.Context, email string, duration time.Duration) (m.User, string, error)
+	// ClaimMagicLink uses up the login token and returns its user
+	ClaimMagicLink(ctx context.Context, userID, tokenid string) (m.User, error)
 }
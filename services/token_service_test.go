@@ -1,6 +1,7 @@
 package services_test
 
 import (
+	"context"
 	"fmt"
 	"github.com/pkg/errors"
 	"github.com/rinosukmandityo/user-profile/helper"
@@ -46,6 +47,7 @@ func TestResetPasswordByMailSuccess(t *testing.T) {
 		Created:   time.Now().UTC().Add(time.Minute * -30),
 		Expired:   time.Now().UTC().Add(time.Minute * -1),
 		IsClaimed: false,
+		Purpose:   m.TokenResetPassword,
 	}
 	tokenClaimed := m.Token{
 		ID:        "token03",
@@ -53,15 +55,16 @@ func TestResetPasswordByMailSuccess(t *testing.T) {
 		Created:   time.Now().UTC(),
 		Expired:   time.Now().UTC().Add(time.Minute * 30),
 		IsClaimed: true,
+		Purpose:   m.TokenResetPassword,
 	}
 
 	if e := seedUserData(userData); e != nil {
 		t.Fatal(e)
 	}
-	if e := tokenRepo.Store(&tokenExpired); e != nil {
+	if e := tokenRepo.Store(context.Background(), &tokenExpired); e != nil {
 		t.Fatal(e)
 	}
-	if e := tokenRepo.Store(&tokenClaimed); e != nil {
+	if e := tokenRepo.Store(context.Background(), &tokenClaimed); e != nil {
 		t.Fatal(e)
 	}
 
@@ -95,7 +98,7 @@ func TestResetPasswordByMailSuccess(t *testing.T) {
 	for _, _tt := range testTable {
 		tt := _tt
 		t.Run(tt.name, func(t *testing.T) {
-			actualUserData, tokenID, e := tokenService.ResetPasswordByMail(tt.expectedUserData.Email, time.Minute*30)
+			actualUserData, tokenID, e := tokenService.ResetPasswordByMail(context.Background(), tt.expectedUserData.Email, time.Minute*30)
 			if e != nil {
 				t.Errorf("unable to create a new session: %s", e.Error())
 			}
@@ -150,7 +153,7 @@ func TestResetPasswordByMailFailed(t *testing.T) {
 	for _, _tt := range testTable {
 		tt := _tt
 		t.Run(tt.name, func(t *testing.T) {
-			actualUserData, tokenID, e := tokenService.ResetPasswordByMail(tt.expectedUserData.Email, time.Minute*30)
+			actualUserData, tokenID, e := tokenService.ResetPasswordByMail(context.Background(), tt.expectedUserData.Email, time.Minute*30)
 			if e == nil {
 				t.Errorf("it should be error to create new token")
 			}
@@ -194,13 +197,14 @@ func TestIsTokenValidSuccess(t *testing.T) {
 		Created:   time.Now().UTC(),
 		Expired:   time.Now().UTC().Add(time.Minute * 30),
 		IsClaimed: false,
+		Purpose:   m.TokenResetPassword,
 	}
 
 	if e := seedUserData(userData); e != nil {
 		t.Fatal(e)
 	}
 
-	if e := tokenRepo.Store(&tokenData); e != nil {
+	if e := tokenRepo.Store(context.Background(), &tokenData); e != nil {
 		t.Fatal(e)
 	}
 
@@ -219,7 +223,7 @@ func TestIsTokenValidSuccess(t *testing.T) {
 	for _, _tt := range testTable {
 		tt := _tt
 		t.Run(tt.name, func(t *testing.T) {
-			found, e := tokenService.IsTokenValid(tt.userID, tt.tokenID)
+			found, e := tokenService.IsTokenValid(context.Background(), tt.userID, tt.tokenID)
 			if e != nil {
 				t.Errorf("failed to check token: %s", e.Error())
 			}
@@ -269,6 +273,7 @@ func TestIsTokenValidFailed(t *testing.T) {
 		Created:   time.Now().UTC().Add(time.Minute * -30),
 		Expired:   time.Now().UTC().Add(time.Minute * -1),
 		IsClaimed: false,
+		Purpose:   m.TokenResetPassword,
 	}
 	tokenClaimed := m.Token{
 		ID:        "token02",
@@ -276,15 +281,16 @@ func TestIsTokenValidFailed(t *testing.T) {
 		Created:   time.Now().UTC(),
 		Expired:   time.Now().UTC().Add(time.Minute * 30),
 		IsClaimed: true,
+		Purpose:   m.TokenResetPassword,
 	}
 
 	if e := seedUserData(userData); e != nil {
 		t.Fatal(e)
 	}
-	if e := tokenRepo.Store(&tokenExpired); e != nil {
+	if e := tokenRepo.Store(context.Background(), &tokenExpired); e != nil {
 		t.Fatal(e)
 	}
-	if e := tokenRepo.Store(&tokenClaimed); e != nil {
+	if e := tokenRepo.Store(context.Background(), &tokenClaimed); e != nil {
 		t.Fatal(e)
 	}
 
@@ -329,7 +335,7 @@ func TestIsTokenValidFailed(t *testing.T) {
 	for _, _tt := range testTable {
 		tt := _tt
 		t.Run(tt.name, func(t *testing.T) {
-			found, e := tokenService.IsTokenValid(tt.userID, tt.tokenID)
+			found, e := tokenService.IsTokenValid(context.Background(), tt.userID, tt.tokenID)
 			if e == nil {
 				t.Errorf("token validation should be failed")
 			}
@@ -360,6 +366,9 @@ func TestChangePasswordTokenSuccess(t *testing.T) {
 		if e := cleanupTokenData(); e != nil {
 			t.Fatal(e)
 		}
+		if e := cleanupSessionData(); e != nil {
+			t.Fatal(e)
+		}
 	}()
 
 	tokenData := m.Token{
@@ -368,13 +377,22 @@ func TestChangePasswordTokenSuccess(t *testing.T) {
 		Created:   time.Now().UTC(),
 		Expired:   time.Now().UTC().Add(time.Minute * 30),
 		IsClaimed: false,
+		Purpose:   m.TokenResetPassword,
 	}
 
 	if e := seedUserData(userData); e != nil {
 		t.Fatal(e)
 	}
 
-	if e := tokenRepo.Store(&tokenData); e != nil {
+	if e := tokenRepo.Store(context.Background(), &tokenData); e != nil {
+		t.Fatal(e)
+	}
+	tSession, e := sessionSvc.CreateNewSession(context.Background(), userData[0], m.Client{})
+	if e != nil {
+		t.Fatal(e)
+	}
+	otherSession, e := sessionSvc.CreateNewSession(context.Background(), m.User{ID: "userid02", Email: "usermail02@gmail.com"}, m.Client{})
+	if e != nil {
 		t.Fatal(e)
 	}
 
@@ -386,7 +404,7 @@ func TestChangePasswordTokenSuccess(t *testing.T) {
 	}{
 		{
 			name:        "success",
-			newPassword: "new password",
+			newPassword: "New.Password.2",
 			userID:      userData[0].ID,
 			tokenID:     tokenData.ID,
 		},
@@ -395,25 +413,35 @@ func TestChangePasswordTokenSuccess(t *testing.T) {
 	for _, _tt := range testTable {
 		tt := _tt
 		t.Run(tt.name, func(t *testing.T) {
-			e := tokenService.ChangePasswordToken(tt.userID, tt.newPassword, tt.tokenID)
+			e := tokenService.ChangePasswordToken(context.Background(), tt.userID, tt.newPassword, tt.tokenID)
 			if e != nil {
 				t.Errorf("failed to change password with token: %s", e.Error())
 			}
-			actualUser, e := userRepo.GetBy(map[string]interface{}{"ID": tt.userID})
+			actualUser, e := userRepo.GetBy(context.Background(), map[string]interface{}{"ID": tt.userID})
 			if e != nil {
 				t.Errorf("unable to get actual user: %s", e.Error())
 			}
-			expectedPassword := repositories.EncryptPassword(tt.newPassword)
-			if expectedPassword != actualUser.Password {
-				t.Errorf("password is incorrect, want %s, got %s", expectedPassword, actualUser.Password)
+			if !repositories.IsPasswordMatch(tt.newPassword, actualUser.Password) {
+				t.Errorf("password is incorrect, got %s", actualUser.Password)
 			}
-			actualToken, e := tokenRepo.GetLatestToken(tt.userID)
+			actualToken, e := tokenRepo.GetLatestToken(context.Background(), tt.userID, m.TokenResetPassword)
 			if e != nil {
 				t.Errorf("unable to get actual token: %s", e.Error())
 			}
 			if !actualToken.IsClaimed {
 				t.Errorf("token should be claimed")
 			}
+			if active, _, _ := sessionSvc.IsSessionActive(context.Background(), tSession.ID); active {
+				t.Errorf("sessions of the user should be revoked")
+			}
+			if active, _, _ := sessionSvc.IsSessionActive(context.Background(), otherSession.ID); !active {
+				t.Errorf("sessions of other users should stay active")
+			}
+			// the used reset link can't change the password again
+			e = tokenService.ChangePasswordToken(context.Background(), tt.userID, "Other.Password.3", tt.tokenID)
+			if e == nil || errors.Cause(e).Error() != helper.ErrTokenClaimed {
+				t.Errorf("claimed token should not be reused, got %v", e)
+			}
 		})
 	}
 }
@@ -433,6 +461,13 @@ func TestChangePasswordTokenFailed(t *testing.T) {
 		Email:    "usermail02@gmail.com",
 		Address:  "User Address 02",
 		IsActive: false,
+	}, {
+		Name:     "User 03",
+		Password: "Password.1",
+		ID:       "userid03",
+		Email:    "usermail03@gmail.com",
+		Address:  "User Address 03",
+		IsActive: false,
 	}}
 
 	defer func() {
@@ -444,19 +479,31 @@ func TestChangePasswordTokenFailed(t *testing.T) {
 		}
 	}()
 
+	tokenExpired := m.Token{
+		ID:        "token02",
+		UserID:    userData[2].ID,
+		Created:   time.Now().UTC().Add(-time.Hour),
+		Expired:   time.Now().UTC().Add(-time.Minute * 30),
+		IsClaimed: false,
+		Purpose:   m.TokenResetPassword,
+	}
 	tokenSuccess := m.Token{
 		ID:        "token01",
 		UserID:    userData[0].ID,
 		Created:   time.Now().UTC(),
 		Expired:   time.Now().UTC().Add(time.Minute * 30),
 		IsClaimed: true,
+		Purpose:   m.TokenResetPassword,
 	}
 
 	if e := seedUserData(userData); e != nil {
 		t.Fatal(e)
 	}
 
-	if e := tokenRepo.Store(&tokenSuccess); e != nil {
+	if e := tokenRepo.Store(context.Background(), &tokenSuccess); e != nil {
+		t.Fatal(e)
+	}
+	if e := tokenRepo.Store(context.Background(), &tokenExpired); e != nil {
 		t.Fatal(e)
 	}
 
@@ -479,7 +526,21 @@ func TestChangePasswordTokenFailed(t *testing.T) {
 			userID:           userData[0].ID,
 			newPassword:      "",
 			tokenID:          "not found",
-			expectedErrorMsg: helper.ErrTokenNotMatch.Error(),
+			expectedErrorMsg: helper.ErrTokenNotFound.Error(),
+		},
+		{
+			name:             "token_claimed",
+			userID:           userData[0].ID,
+			newPassword:      "New.Password.2",
+			tokenID:          tokenSuccess.ID,
+			expectedErrorMsg: helper.ErrTokenClaimed,
+		},
+		{
+			name:             "token_expired",
+			userID:           userData[2].ID,
+			newPassword:      "New.Password.2",
+			tokenID:          tokenExpired.ID,
+			expectedErrorMsg: helper.ErrTokenExpired,
 		},
 		{
 			name:             "token_not_created_yet",
@@ -493,7 +554,7 @@ func TestChangePasswordTokenFailed(t *testing.T) {
 	for _, _tt := range testTable {
 		tt := _tt
 		t.Run(tt.name, func(t *testing.T) {
-			e := tokenService.ChangePasswordToken(tt.userID, tt.newPassword, tt.tokenID)
+			e := tokenService.ChangePasswordToken(context.Background(), tt.userID, tt.newPassword, tt.tokenID)
 			if e == nil {
 				t.Errorf("should be failed to change password with token")
 			}
@@ -504,3 +565,117 @@ func TestChangePasswordTokenFailed(t *testing.T) {
 		})
 	}
 }
+
+func TestVerifyEmail(t *testing.T) {
+	userData := []m.User{{
+		Name:     "User 01",
+		Password: "Password.1",
+		ID:       "userid01",
+		Email:    "usermail01@gmail.com",
+		IsActive: false,
+	}}
+
+	defer func() {
+		if e := cleanupUserData(); e != nil {
+			t.Fatal(e)
+		}
+		if e := cleanupTokenData(); e != nil {
+			t.Fatal(e)
+		}
+	}()
+
+	if e := seedUserData(userData); e != nil {
+		t.Fatal(e)
+	}
+	ctx := context.Background()
+	_, resetToken, e := tokenService.ResetPasswordByMail(ctx, userData[0].Email, time.Minute*15)
+	if e != nil {
+		t.Fatal(e)
+	}
+	_, verifyToken, e := tokenService.VerificationByMail(ctx, userData[0].Email, time.Hour)
+	if e != nil {
+		t.Fatal(e)
+	}
+	if _, again, _ := tokenService.VerificationByMail(ctx, userData[0].Email, time.Hour); again != verifyToken {
+		t.Errorf("unused verification token should be sent again, want %s, got %s", verifyToken, again)
+	}
+
+	// a token only ever grants what it was created for
+	if _, e := tokenService.IsTokenValid(ctx, userData[0].ID, verifyToken); errors.Cause(e) != helper.ErrTokenNotFound {
+		t.Errorf("verification token can't reset the password, got %v", e)
+	}
+	if e := tokenService.VerifyEmail(ctx, userData[0].ID, resetToken); errors.Cause(e) != helper.ErrTokenNotFound {
+		t.Errorf("reset password token can't verify the email, got %v", e)
+	}
+
+	if e := tokenService.VerifyEmail(ctx, userData[0].ID, verifyToken); e != nil {
+		t.Fatalf("failed to verify email: %s", e.Error())
+	}
+	if user, _ := userService.GetById(ctx, userData[0].ID); !user.IsActive {
+		t.Error("user should be active")
+	}
+	if e := tokenService.VerifyEmail(ctx, userData[0].ID, verifyToken); e == nil || errors.Cause(e).Error() != helper.ErrTokenClaimed {
+		t.Errorf("verification token can't be used twice, got %v", e)
+	}
+	if _, _, e := tokenService.VerificationByMail(ctx, userData[0].Email, time.Hour); errors.Cause(e) != helper.ErrEmailVerified {
+		t.Errorf("verified email should not get a new token, got %v", e)
+	}
+}
+
+func TestMagicLink(t *testing.T) {
+	userData := []m.User{{
+		Name:     "User 01",
+		Password: "Password.1",
+		ID:       "userid01",
+		Email:    "usermail01@gmail.com",
+		IsActive: true,
+	}, {
+		Name:     "User 02",
+		Password: "Password.2",
+		ID:       "userid02",
+		Email:    "usermail02@gmail.com",
+		IsActive: false,
+	}}
+
+	defer func() {
+		if e := cleanupUserData(); e != nil {
+			t.Fatal(e)
+		}
+		if e := cleanupTokenData(); e != nil {
+			t.Fatal(e)
+		}
+	}()
+
+	if e := seedUserData(userData); e != nil {
+		t.Fatal(e)
+	}
+	ctx := context.Background()
+	if _, _, e := tokenService.MagicLinkByMail(ctx, userData[1].Email, time.Minute*15); errors.Cause(e) != helper.ErrEmailNotVerified {
+		t.Errorf("unverified user should not get a login link, got %v", e)
+	}
+	_, resetToken, e := tokenService.ResetPasswordByMail(ctx, userData[0].Email, time.Minute*15)
+	if e != nil {
+		t.Fatal(e)
+	}
+	_, loginToken, e := tokenService.MagicLinkByMail(ctx, userData[0].Email, time.Minute*15)
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	if _, e := tokenService.ClaimMagicLink(ctx, userData[0].ID, resetToken); errors.Cause(e) != helper.ErrTokenNotFound {
+		t.Errorf("reset password token can't log in, got %v", e)
+	}
+	user, e := tokenService.ClaimMagicLink(ctx, userData[0].ID, loginToken)
+	if e != nil {
+		t.Fatalf("failed to claim login link: %s", e.Error())
+	}
+	if user.ID != userData[0].ID {
+		t.Errorf("user is not correct, want %s, got %s", userData[0].ID, user.ID)
+	}
+	if _, e := tokenService.ClaimMagicLink(ctx, userData[0].ID, loginToken); e == nil || errors.Cause(e).Error() != helper.ErrTokenClaimed {
+		t.Errorf("login link can't be used twice, got %v", e)
+	}
+	if _, again, _ := tokenService.MagicLinkByMail(ctx, userData[0].Email, time.Minute*15); again == loginToken {
+		t.Error("a used login link should be replaced")
+	}
+}
@@ -0,0 +1,83 @@
+package services_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rinosukmandityo/user-profile/helper"
+	m "github.com/rinosukmandityo/user-profile/models"
+)
+
+func TestTransactionRollback(t *testing.T) {
+	user := m.User{
+		Name:     "User 01",
+		Password: "Password.1",
+		ID:       "userid01",
+		Email:    "usermail01@gmail.com",
+	}
+	token := m.Token{
+		ID:      "token01",
+		UserID:  user.ID,
+		Created: time.Now().UTC(),
+		Expired: time.Now().UTC().Add(time.Minute * 30),
+		Purpose: m.TokenResetPassword,
+	}
+	errFailed := fmt.Errorf("second step failed")
+
+	e := transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
+		if e := userRepo.Store(ctx, &user); e != nil {
+			return e
+		}
+		if e := tokenRepo.Store(ctx, &token); e != nil {
+			return e
+		}
+		return errFailed
+	})
+	if e != errFailed {
+		t.Fatalf("error is not correct, want %v, got %v", errFailed, e)
+	}
+
+	if _, e = userRepo.GetBy(context.Background(), map[string]interface{}{"ID": user.ID}); errors.Cause(e) != helper.ErrUserNotFound {
+		t.Errorf("user should be rolled back, got %v", e)
+	}
+	if _, e = tokenRepo.GetLatestToken(context.Background(), user.ID, m.TokenResetPassword); errors.Cause(e) != helper.ErrTokenNotFound {
+		t.Errorf("token should be rolled back, got %v", e)
+	}
+}
+
+func TestTransactionCommit(t *testing.T) {
+	user := m.User{
+		Name:     "User 01",
+		Password: "Password.1",
+		ID:       "userid01",
+		Email:    "usermail01@gmail.com",
+	}
+
+	e := transactor.WithinTransaction(context.Background(), func(ctx context.Context) error {
+		if e := userRepo.Store(ctx, &user); e != nil {
+			return e
+		}
+		// reads inside the transaction see its own writes
+		_, e := userRepo.Update(ctx, map[string]interface{}{"Name": "User 01 Updated"}, user.ID)
+		return e
+	})
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer func() {
+		if err := cleanupUserData(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	res, e := userRepo.GetBy(context.Background(), map[string]interface{}{"ID": user.ID})
+	if e != nil {
+		t.Fatal(e)
+	}
+	if res.Name != "User 01 Updated" {
+		t.Errorf("name is not correct, want %s, got %s", "User 01 Updated", res.Name)
+	}
+}
@@ -0,0 +1,20 @@
+package services
+
+import (
+	"context"
+
+	m "github.com/rinosukmandityo/user-profile/models"
+)
+
+type TwoFactorService interface {
+	// Enroll starts a new enrolment with a new secret, it replaces an enrolment
+	// which was not confirmed and returns helper.ErrTwoFactorEnabled for a confirmed one
+	Enroll(ctx context.Context, user m.User) (m.TwoFactorEnrollment, error)
+	// Confirm enables two-factor authentication with the first code of the
+	// authenticator app and returns the recovery codes, they are not stored in plain
+	Confirm(ctx context.Context, userID, code string) ([]string, error)
+	// Verify accepts a code of the authenticator app or uses up a recovery code,
+	// it returns helper.ErrInvalidCode otherwise
+	Verify(ctx context.Context, userID, code string) error
+	IsEnabled(ctx context.Context, userID string) (bool, error)
+}
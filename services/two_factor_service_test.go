@@ -0,0 +1,159 @@
+package services_test
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rinosukmandityo/user-profile/helper"
+	m "github.com/rinosukmandityo/user-profile/models"
+	"github.com/rinosukmandityo/user-profile/services/logic"
+
+	"github.com/pkg/errors"
+)
+
+func TestGenerateTOTP(t *testing.T) {
+	// test vectors of RFC 6238 for SHA1, the secret is "12345678901234567890"
+	secret := "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+	testTable := []struct {
+		name         string
+		time         int64
+		expectedCode string
+	}{
+		{name: "59", time: 59, expectedCode: "287082"},
+		{name: "1111111109", time: 1111111109, expectedCode: "081804"},
+		{name: "1234567890", time: 1234567890, expectedCode: "005924"},
+		{name: "2000000000", time: 2000000000, expectedCode: "279037"},
+	}
+
+	for _, _tt := range testTable {
+		tt := _tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			code, e := logic.GenerateTOTP(secret, time.Unix(tt.time, 0))
+			if e != nil {
+				t.Fatal(e)
+			}
+			if code != tt.expectedCode {
+				t.Errorf("code is not correct, want %s, got %s", tt.expectedCode, code)
+			}
+		})
+	}
+}
+
+func TestTwoFactorEnrollment(t *testing.T) {
+	defer func() {
+		if e := cleanupTwoFactorData(); e != nil {
+			t.Fatal(e)
+		}
+	}()
+	ctx := context.Background()
+	user := m.User{ID: "userid01", Email: "usermail01@gmail.com"}
+
+	enrollment, e := twoFactorSvc.Enroll(ctx, user)
+	if e != nil {
+		t.Fatalf("failed to enroll: %s", e.Error())
+	}
+	if !strings.HasPrefix(enrollment.URI, "otpauth://totp/User%20Profile:usermail01@gmail.com?") ||
+		!strings.Contains(enrollment.URI, "secret="+enrollment.Secret) {
+		t.Errorf("otpauth URI is not correct, got %s", enrollment.URI)
+	}
+	if enabled, _ := twoFactorSvc.IsEnabled(ctx, user.ID); enabled {
+		t.Error("two-factor authentication should not be enabled before it is confirmed")
+	}
+
+	if _, e := twoFactorSvc.Confirm(ctx, user.ID, "abcdef"); errors.Cause(e) != helper.ErrInvalidCode {
+		t.Errorf("wrong code should be rejected, got %v", e)
+	}
+	code, _ := logic.GenerateTOTP(enrollment.Secret, time.Now())
+	codes, e := twoFactorSvc.Confirm(ctx, user.ID, code)
+	if e != nil {
+		t.Fatalf("failed to confirm: %s", e.Error())
+	}
+	if len(codes) != 10 {
+		t.Errorf("want 10 recovery codes, got %d", len(codes))
+	}
+	if enabled, _ := twoFactorSvc.IsEnabled(ctx, user.ID); !enabled {
+		t.Error("two-factor authentication should be enabled")
+	}
+	if _, e := twoFactorSvc.Enroll(ctx, user); errors.Cause(e) != helper.ErrTwoFactorEnabled {
+		t.Errorf("enabled two-factor authentication should not be replaced, got %v", e)
+	}
+}
+
+func TestTwoFactorVerify(t *testing.T) {
+	defer func() {
+		if e := cleanupTwoFactorData(); e != nil {
+			t.Fatal(e)
+		}
+	}()
+	ctx := context.Background()
+	user := m.User{ID: "userid01", Email: "usermail01@gmail.com"}
+
+	if e := twoFactorSvc.Verify(ctx, user.ID, "123456"); errors.Cause(e) != helper.ErrTwoFactorNotFound {
+		t.Errorf("user without two-factor authentication can't be verified, got %v", e)
+	}
+	enrollment, e := twoFactorSvc.Enroll(ctx, user)
+	if e != nil {
+		t.Fatal(e)
+	}
+	code, _ := logic.GenerateTOTP(enrollment.Secret, time.Now())
+	recoveryCodes, e := twoFactorSvc.Confirm(ctx, user.ID, code)
+	if e != nil {
+		t.Fatal(e)
+	}
+	// the clock of the phone may be one period ahead
+	nextCode, _ := logic.GenerateTOTP(enrollment.Secret, time.Now().Add(30*time.Second))
+
+	testTable := []struct {
+		name          string
+		code          string
+		expectedError error
+	}{
+		{name: "code_used_on_confirm", code: code, expectedError: helper.ErrInvalidCode},
+		{name: "next_code", code: nextCode, expectedError: nil},
+		{name: "replayed_code", code: nextCode, expectedError: helper.ErrInvalidCode},
+		{name: "recovery_code", code: strings.ToLower(recoveryCodes[0]), expectedError: nil},
+		{name: "used_recovery_code", code: recoveryCodes[0], expectedError: helper.ErrInvalidCode},
+		{name: "another_recovery_code", code: strings.Replace(recoveryCodes[1], "-", "", 1), expectedError: nil},
+		{name: "invalid_code", code: "ABCDE-FGHIJ", expectedError: helper.ErrInvalidCode},
+	}
+
+	for _, tt := range testTable {
+		if e := twoFactorSvc.Verify(ctx, user.ID, tt.code); errors.Cause(e) != tt.expectedError {
+			t.Errorf("%s: error is not correct, want %v, got %v", tt.name, tt.expectedError, e)
+		}
+	}
+
+	// a code sent several times at once is accepted once
+	var wg sync.WaitGroup
+	var accepted int32
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if e := twoFactorSvc.Verify(ctx, user.ID, recoveryCodes[2]); e == nil {
+				atomic.AddInt32(&accepted, 1)
+			}
+		}()
+	}
+	wg.Wait()
+	if accepted != 1 {
+		t.Errorf("recovery code sent at once should be accepted once, got %d", accepted)
+	}
+
+	// a verification which read the enrolment before another one used a code can't save
+	stale, e := twoFactorRepo.GetBy(ctx, user.ID)
+	if e != nil {
+		t.Fatal(e)
+	}
+	if e := twoFactorSvc.Verify(ctx, user.ID, recoveryCodes[3]); e != nil {
+		t.Fatal(e)
+	}
+	if e := twoFactorRepo.UpdateCodes(ctx, stale, stale.LastStep, ""); errors.Cause(e) != helper.ErrTwoFactorNotFound {
+		t.Errorf("stale enrolment should not be saved, got %v", e)
+	}
+}
@@ -1,12 +1,15 @@
 package services
 
 import (
+	"context"
+
 	m "github.com/rinosukmandityo/user-profile/models"
 )
 
 type UserService interface {
-	GetById(id string) (m.User, error)
-	GetByEmail(email string) (bool, m.User, error)
-	Store(data *m.User) error
-	Update(data map[string]interface{}, id string) (m.User, error)
+	GetById(ctx context.Context, id string) (m.User, error)
+	GetByEmail(ctx context.Context, email string) (bool, m.User, error)
+	Store(ctx context.Context, data *m.User) error
+	Update(ctx context.Context, data *m.UserUpdate, id string) (m.User, error)
+	List(ctx context.Context, query m.UserListQuery) (m.UserPage, error)
 }
@@ -1,6 +1,7 @@
 package services_test
 
 import (
+	"context"
 	"reflect"
 	"testing"
 
@@ -28,11 +29,11 @@ func TestInsertUserSuccess(t *testing.T) {
 
 	expectedData := testData[0]
 
-	if e := userService.Store(&expectedData); e != nil {
+	if e := userService.Store(context.Background(), &expectedData); e != nil {
 		t.Errorf("failed to save data: %s ", e.Error())
 	}
 
-	actualData, e := userService.GetById(expectedData.ID)
+	actualData, e := userService.GetById(context.Background(), expectedData.ID)
 	if e != nil {
 		t.Errorf("unable to get data: %s", e.Error())
 	}
@@ -86,7 +87,7 @@ func TestInsertUserFailed(t *testing.T) {
 		tt := _tt
 
 		t.Run(tt.name, func(t *testing.T) {
-			e := userService.Store(&tt.data)
+			e := userService.Store(context.Background(), &tt.data)
 			if e == nil {
 				t.Error("duplicate validation is not working")
 			}
@@ -120,7 +121,7 @@ func TestGetUserSuccess(t *testing.T) {
 		t.Fatal(e)
 	}
 
-	actualData, e := userService.GetById(expectedData.ID)
+	actualData, e := userService.GetById(context.Background(), expectedData.ID)
 	if e != nil {
 		t.Errorf("unable to get data: %s", e.Error())
 	}
@@ -148,11 +149,11 @@ func TestGetUserFailed(t *testing.T) {
 
 	expectedData := testData[0]
 
-	if e := userService.Store(&expectedData); e != nil {
+	if e := userService.Store(context.Background(), &expectedData); e != nil {
 		t.Errorf("failed to save data: %s ", e.Error())
 	}
 
-	actualData, e := userService.GetById(expectedData.ID)
+	actualData, e := userService.GetById(context.Background(), expectedData.ID)
 	if e != nil {
 		t.Errorf("unable to get data: %s", e.Error())
 	}
@@ -183,9 +184,8 @@ func TestUpdateUserSuccess(t *testing.T) {
 
 	expectedData := testData[0]
 	expectedData.Email += "UPDATED"
-	dataMap := expectedData.GetMapFormat()
 
-	actualData, e := userService.Update(dataMap, expectedData.ID)
+	actualData, e := userService.Update(context.Background(), &m.UserUpdate{Email: &expectedData.Email}, expectedData.ID)
 	if e != nil {
 		t.Errorf("failed to update data: %s ", e.Error())
 	}
@@ -221,6 +221,9 @@ func TestUpdateUserFailed(t *testing.T) {
 	invalidID.ID = "invalid"
 	invalidID.Email = "wrong email"
 
+	emptyEmail := testData[0]
+	emptyEmail.Email = ""
+
 	testTable := []struct {
 		name             string
 		data             m.User
@@ -239,6 +242,12 @@ func TestUpdateUserFailed(t *testing.T) {
 			expectedData:     m.User{},
 			expectedErrorMsg: helper.ErrEmailDuplicate.Error(),
 		},
+		{
+			name:             "empty_email",
+			data:             emptyEmail,
+			expectedData:     m.User{},
+			expectedErrorMsg: helper.ErrUserInvalid.Error(),
+		},
 		{
 			name:             "no_row_affected",
 			data:             testData[0],
@@ -251,8 +260,13 @@ func TestUpdateUserFailed(t *testing.T) {
 		tt := _tt
 
 		t.Run(tt.name, func(t *testing.T) {
-			dataMap := tt.data.GetMapFormat()
-			actualData, e := userService.Update(dataMap, tt.data.ID)
+			data := &m.UserUpdate{
+				Name:      &tt.data.Name,
+				Email:     &tt.data.Email,
+				Telephone: &tt.data.Telephone,
+				Address:   &tt.data.Address,
+			}
+			actualData, e := userService.Update(context.Background(), data, tt.data.ID)
 			if e == nil {
 				t.Errorf("update should be failed")
 			}
@@ -267,3 +281,153 @@ func TestUpdateUserFailed(t *testing.T) {
 		})
 	}
 }
+
+func TestListUser(t *testing.T) {
+	testData := []m.User{
+		{ID: "userid01", Name: "Alice", Email: "alice@gmail.com", IsActive: true},
+		{ID: "userid02", Name: "Bob", Email: "bob@example.com", IsActive: true},
+		{ID: "userid03", Name: "Albert", Email: "albert@Example.com", IsGoogleAuth: true},
+		{ID: "userid04", Name: "Carol", Email: "carol@gmail.com"},
+		{ID: "userid05", Name: "Alan", Email: "alan@gmail.com", IsActive: true},
+	}
+
+	defer func() {
+		if err := cleanupUserData(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	if e := seedUserData(testData); e != nil {
+		t.Fatal(e)
+	}
+	if _, e := userRepo.Update(context.Background(), map[string]interface{}{"IsDeleted": true}, "userid04"); e != nil {
+		t.Fatal(e)
+	}
+
+	isTrue := true
+	testTable := []struct {
+		name          string
+		query         m.UserListQuery
+		expectedIDs   []string
+		expectedTotal int
+	}{
+		{
+			name:          "all_sorted_by_id",
+			query:         m.UserListQuery{},
+			expectedIDs:   []string{"userid01", "userid02", "userid03", "userid05"},
+			expectedTotal: 4,
+		},
+		{
+			name:          "sorted_by_name_desc",
+			query:         m.UserListQuery{SortBy: "Name", Desc: true},
+			expectedIDs:   []string{"userid02", "userid01", "userid03", "userid05"},
+			expectedTotal: 4,
+		},
+		{
+			name:          "active",
+			query:         m.UserListQuery{IsActive: &isTrue},
+			expectedIDs:   []string{"userid01", "userid02", "userid05"},
+			expectedTotal: 3,
+		},
+		{
+			name:          "google_auth",
+			query:         m.UserListQuery{IsGoogleAuth: &isTrue},
+			expectedIDs:   []string{"userid03"},
+			expectedTotal: 1,
+		},
+		{
+			name:          "email_domain",
+			query:         m.UserListQuery{EmailDomain: "example.com"},
+			expectedIDs:   []string{"userid02", "userid03"},
+			expectedTotal: 2,
+		},
+		{
+			name:          "name_prefix",
+			query:         m.UserListQuery{NamePrefix: "al", SortBy: "Name"},
+			expectedIDs:   []string{"userid05", "userid03", "userid01"},
+			expectedTotal: 3,
+		},
+		{
+			name:          "like_wildcard_is_literal",
+			query:         m.UserListQuery{NamePrefix: "%"},
+			expectedIDs:   []string{},
+			expectedTotal: 0,
+		},
+	}
+
+	for _, _tt := range testTable {
+		tt := _tt
+		t.Run(tt.name, func(t *testing.T) {
+			page, e := userService.List(context.Background(), tt.query)
+			if e != nil {
+				t.Fatalf("unable to list data: %s", e.Error())
+			}
+			actualIDs := []string{}
+			for _, user := range page.Users {
+				actualIDs = append(actualIDs, user.ID)
+			}
+			if !reflect.DeepEqual(tt.expectedIDs, actualIDs) {
+				t.Errorf("users are not correct, want %v, got %v", tt.expectedIDs, actualIDs)
+			}
+			if page.Total != tt.expectedTotal {
+				t.Errorf("total is not correct, want %d, got %d", tt.expectedTotal, page.Total)
+			}
+			if page.NextCursor != "" {
+				t.Errorf("a single page should have no next cursor")
+			}
+		})
+	}
+}
+
+func TestListUserPagination(t *testing.T) {
+	testData := []m.User{
+		{ID: "userid01", Name: "User C", Email: "usermail01@gmail.com"},
+		{ID: "userid02", Name: "User A", Email: "usermail02@gmail.com"},
+		{ID: "userid03", Name: "User B", Email: "usermail03@gmail.com"},
+		{ID: "userid04", Name: "User A", Email: "usermail04@gmail.com"},
+		{ID: "userid05", Name: "User B", Email: "usermail05@gmail.com"},
+	}
+
+	defer func() {
+		if err := cleanupUserData(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	if e := seedUserData(testData); e != nil {
+		t.Fatal(e)
+	}
+
+	expectedIDs := []string{"userid02", "userid04", "userid03", "userid05", "userid01"}
+	actualIDs := []string{}
+	query := m.UserListQuery{SortBy: "Name", Limit: 2}
+	for pages := 0; ; pages++ {
+		if pages > len(testData) {
+			t.Fatalf("pagination doesn't end")
+		}
+		page, e := userService.List(context.Background(), query)
+		if e != nil {
+			t.Fatalf("unable to list data: %s", e.Error())
+		}
+		if page.Total != len(testData) {
+			t.Errorf("total is not correct, want %d, got %d", len(testData), page.Total)
+		}
+		for _, user := range page.Users {
+			actualIDs = append(actualIDs, user.ID)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		query.Cursor = page.NextCursor
+	}
+	if !reflect.DeepEqual(expectedIDs, actualIDs) {
+		t.Errorf("users are not correct, want %v, got %v", expectedIDs, actualIDs)
+	}
+
+	if _, e := userService.List(context.Background(), m.UserListQuery{Cursor: "not a cursor"}); errors.Cause(e) != helper.ErrInvalidCursor {
+		t.Errorf("want invalid cursor error, got %v", e)
+	}
+	if _, e := userService.List(context.Background(), m.UserListQuery{SortBy: "Password"}); errors.Cause(e) != helper.ErrInvalidSort {
+		t.Errorf("want invalid sort error, got %v", e)
+	}
+}